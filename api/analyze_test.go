@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/api/handlers"
+	"github.com/AyomiCoder/loggar/api/middleware"
+	"github.com/AyomiCoder/loggar/internal/cache"
+	"github.com/AyomiCoder/loggar/internal/dbtest"
+	"github.com/AyomiCoder/loggar/pkg/offline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeOfflineFallback(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer provider.Close()
+	t.Setenv("GOOGLE_AI_KEY", "test-key")
+	t.Setenv("GOOGLE_AI_BASE_URL", provider.URL)
+	t.Setenv("GOOGLE_AI_MAX_ATTEMPTS", "1")
+	t.Setenv("ANALYSIS_CACHE", "off")
+
+	router := NewServer()
+	w := httptest.NewRecorder()
+	body := `{"logs": "2026-01-15 10:00:00 ERROR connection refused to db:5432\n"}`
+	req, _ := http.NewRequest("POST", "/api/analyze", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testJWT(t))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, offline.Profile, w.Header().Get("X-Loggar-Analyzer"))
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.NotEmpty(t, result["summary"])
+}
+
+func TestCreateJobWithoutWorkers(t *testing.T) {
+	router := NewServer()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/jobs", strings.NewReader(`{"logs": "ERROR boom"}`))
+	req.Header.Set("Authorization", "Bearer "+testJWT(t))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error": "analysis jobs are disabled on this server"}`, w.Body.String())
+}
+
+func TestAnalyzeWindowedSources(t *testing.T) {
+	t.Setenv("GOOGLE_AI_KEY", "")
+	router := NewServer()
+	body := `{
+		"sources": [
+			{"name": "api.log", "logs": "2026-01-15T09:00:00Z ERROR old failure\n2026-01-15T10:00:03Z ERROR upstream returned 502\n    at handler.go:42\n"},
+			{"name": "db.log", "logs": "2026-01-15T10:00:01Z LOG database system is shutting down\n"}
+		],
+		"since": "2026-01-15T10:00:00Z"
+	}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/analyze", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testJWT(t))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json; version=2")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result struct {
+		Sources []struct {
+			Name   string `json:"name"`
+			Lines  int    `json:"lines"`
+			Errors int    `json:"errors"`
+		} `json:"sources"`
+		LineMap []map[string]interface{} `json:"line_map"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+
+	// The line before the window is not counted
+	require.Len(t, result.Sources, 2)
+	assert.Equal(t, "api.log", result.Sources[0].Name)
+	assert.Equal(t, 2, result.Sources[0].Lines)
+	assert.Equal(t, 1, result.Sources[0].Errors)
+
+	assert.Equal(t, []map[string]interface{}{
+		{"start": 1.0, "end": 1.0, "source": "db.log", "line": 1.0},
+		{"start": 2.0, "end": 3.0, "source": "api.log", "line": 2.0},
+	}, result.LineMap)
+}
+
+func TestAnalyzeOversizedGzipJSON(t *testing.T) {
+	t.Setenv("MAX_LOG_BYTES", "1024")
+	router := NewServer()
+
+	// Small on the wire, but over the limit once decompressed
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	json.NewEncoder(zw).Encode(map[string]string{"logs": strings.Repeat("ERROR boom\n", 500)})
+	require.NoError(t, zw.Close())
+	require.Less(t, body.Len(), 1024)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/analyze", &body)
+	req.Header.Set("Authorization", "Bearer "+testJWT(t))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"error": "logs exceed the maximum size of 1024 bytes"}`, w.Body.String())
+}
+
+func TestAnalyzeQuota(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer dbtest.Result
+		status int
+	}{
+		{"used up", dbtest.Result{Columns: []string{"quota", "used"}, Rows: [][]driver.Value{{int64(10), int64(10)}}}, http.StatusTooManyRequests},
+		{"database error", dbtest.Result{Err: errors.New("connection refused")}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := dbtest.New(t)
+			fake.On("FROM users u WHERE u.id = $1", func([]driver.Value) dbtest.Result {
+				return dbtest.Result{Columns: []string{"disabled", "revoked"}, Rows: [][]driver.Value{{false, false}}}
+			})
+			fake.On("monthly_analysis_quota", func([]driver.Value) dbtest.Result { return tt.answer })
+			handlers.SetDB(db)
+			middleware.SetDB(db)
+			t.Cleanup(func() {
+				handlers.SetDB(nil)
+				middleware.SetDB(nil)
+			})
+
+			router := NewServer()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/analyze", strings.NewReader(`{"logs": "ERROR boom"}`))
+			req.Header.Set("Authorization", "Bearer "+testJWT(t))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Empty(t, fake.Queries("INSERT INTO usage_logs"))
+		})
+	}
+}
+
+func TestAnalyzeCacheHit(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{\"summary\": \"boom\", \"severity\": \"high\"}"}]}}]}`))
+	}))
+	defer provider.Close()
+	t.Setenv("GOOGLE_AI_KEY", "test-key")
+	t.Setenv("GOOGLE_AI_BASE_URL", provider.URL)
+	handlers.SetCache(cache.NewMemory(10, time.Hour))
+	t.Cleanup(func() { handlers.SetCache(nil) })
+
+	db, fake := dbtest.New(t)
+	fake.On("FROM users u WHERE u.id = $1", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"disabled", "revoked"}, Rows: [][]driver.Value{{false, false}}}
+	})
+	fake.On("monthly_analysis_quota", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"quota", "used"}, Rows: [][]driver.Value{{nil, int64(0)}}}
+	})
+	fake.On("INSERT INTO analysis_jobs", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}}}
+	})
+	fake.On("AND cache_key = $2", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}}}
+	})
+	handlers.SetDB(db)
+	middleware.SetDB(db)
+	t.Cleanup(func() {
+		handlers.SetDB(nil)
+		middleware.SetDB(nil)
+	})
+
+	router := NewServer()
+	analyze := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/analyze", strings.NewReader(`{"logs": "ERROR cache hit test"}`))
+		req.Header.Set("Authorization", "Bearer "+testJWT(t))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w
+	}
+
+	assert.Equal(t, "miss", analyze().Header().Get("X-Loggar-Cache"))
+	w := analyze()
+	assert.Equal(t, "hit", w.Header().Get("X-Loggar-Cache"))
+	assert.Equal(t, "1", w.Header().Get("X-Loggar-Analysis-ID"))
+
+	// The hit reuses the recorded analysis and is not counted or notified
+	assert.Len(t, fake.Queries("INSERT INTO analysis_jobs"), 1)
+	assert.Len(t, fake.Queries("monthly_analysis_quota"), 1)
+	assert.Len(t, fake.Queries("INSERT INTO usage_logs"), 1)
+	assert.Len(t, fake.Queries("FROM webhooks WHERE user_id"), 1)
+}
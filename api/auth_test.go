@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func testJWT(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"email":   "test@loggar.dev",
+	})
+	signed, err := token.SignedString([]byte("default-secret-change-me"))
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestProtectedRoutes(t *testing.T) {
+	router := NewServer()
+
+	t.Run("missing authorization header", func(t *testing.T) {
+		for _, path := range []string{"/api/keys", "/api/analyses/1/messages", "/admin/users"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+		}
+	})
+
+	t.Run("unknown api key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/keys", nil)
+		req.Header.Set("Authorization", "Bearer lgk_doesnotexist")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"error": "invalid api key"}`, w.Body.String())
+	})
+
+	t.Run("admin routes require the admin role", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer "+testJWT(t))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
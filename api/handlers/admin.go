@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminUser is a user record as seen by administrators
+type AdminUser struct {
+	ID                   int        `json:"id"`
+	Email                string     `json:"email"`
+	Provider             string     `json:"provider"`
+	Role                 string     `json:"role"`
+	CreatedAt            time.Time  `json:"created_at"`
+	DisabledAt           *time.Time `json:"disabled_at"`
+	MonthlyAnalysisQuota *int       `json:"monthly_analysis_quota"`
+	AnalysesThisMonth    int        `json:"analyses_this_month"`
+}
+
+type UpdateQuotaRequest struct {
+	MonthlyAnalysisQuota *int `json:"monthly_analysis_quota"`
+}
+
+const adminUserColumns = `
+	u.id, u.email, COALESCE(u.provider, ''), u.role, u.created_at, u.disabled_at, u.monthly_analysis_quota,
+	(SELECT COUNT(*) FROM usage_logs l WHERE l.user_id = u.id AND l.analyzed_at >= date_trunc('month', NOW()))`
+
+func scanAdminUser(row interface{ Scan(...interface{}) error }) (AdminUser, error) {
+	var (
+		u          AdminUser
+		disabledAt sql.NullTime
+		quota      sql.NullInt64
+	)
+	err := row.Scan(&u.ID, &u.Email, &u.Provider, &u.Role, &u.CreatedAt, &disabledAt, &quota, &u.AnalysesThisMonth)
+	if disabledAt.Valid {
+		u.DisabledAt = &disabledAt.Time
+	}
+	if quota.Valid {
+		q := int(quota.Int64)
+		u.MonthlyAnalysisQuota = &q
+	}
+	return u, err
+}
+
+// AdminListUsersHandler lists users, optionally filtered by an email search
+func AdminListUsersHandler(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := db.Query(`
+		SELECT `+adminUserColumns+`
+		FROM users u
+		WHERE $1 = '' OR u.email ILIKE '%' || $1 || '%'
+		ORDER BY u.id
+		LIMIT $2 OFFSET $3`, c.Query("q"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	users := []AdminUser{}
+	for rows.Next() {
+		u, err := scanAdminUser(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		users = append(users, u)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "limit": limit, "offset": offset})
+}
+
+// AdminGetUserHandler returns a single user
+func AdminGetUserHandler(c *gin.Context) {
+	userID, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	u, err := scanAdminUser(db.QueryRow(`SELECT `+adminUserColumns+` FROM users u WHERE u.id = $1`, userID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// AdminDisableUserHandler disables an account. Existing sessions and API keys stop working immediately.
+func AdminDisableUserHandler(c *gin.Context) {
+	setUserDisabled(c, true)
+}
+
+// AdminEnableUserHandler re-enables a disabled account
+func AdminEnableUserHandler(c *gin.Context) {
+	setUserDisabled(c, false)
+}
+
+func setUserDisabled(c *gin.Context, disabled bool) {
+	userID, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	query := "UPDATE users SET disabled_at = NOW(), updated_at = NOW() WHERE id = $1"
+	action := "user.disable"
+	if !disabled {
+		query = "UPDATE users SET disabled_at = NULL, updated_at = NOW() WHERE id = $1"
+		action = "user.enable"
+	}
+
+	if !execForUser(c, query, userID) {
+		return
+	}
+
+	recordAdminAction(c, action, userID, nil)
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// AdminRevokeTokensHandler revokes every JWT and API key belonging to a user
+func AdminRevokeTokensHandler(c *gin.Context) {
+	userID, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	tokens, err := tx.Exec("UPDATE tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	keys, err := tx.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	revokedTokens, _ := tokens.RowsAffected()
+	revokedKeys, _ := keys.RowsAffected()
+	details := gin.H{"tokens": revokedTokens, "api_keys": revokedKeys}
+	recordAdminAction(c, "user.revoke_tokens", userID, details)
+
+	c.JSON(http.StatusOK, details)
+}
+
+// AdminUpdateQuotaHandler sets or clears (null) a user's monthly analysis quota
+func AdminUpdateQuotaHandler(c *gin.Context) {
+	userID, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.MonthlyAnalysisQuota != nil && *req.MonthlyAnalysisQuota < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "monthly_analysis_quota must not be negative"})
+		return
+	}
+
+	if !execForUser(c, "UPDATE users SET monthly_analysis_quota = $2, updated_at = NOW() WHERE id = $1", userID, req.MonthlyAnalysisQuota) {
+		return
+	}
+
+	recordAdminAction(c, "user.update_quota", userID, gin.H{"monthly_analysis_quota": req.MonthlyAnalysisQuota})
+	c.JSON(http.StatusOK, gin.H{"monthly_analysis_quota": req.MonthlyAnalysisQuota})
+}
+
+// AdminUsageHandler returns aggregate usage from usage_logs, by default for the last 30 days
+func AdminUsageHandler(c *gin.Context) {
+	until := time.Now()
+	since := until.AddDate(0, 0, -30)
+	var err error
+	if v := c.Query("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if until, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+
+	var totals struct {
+		Analyses    int   `json:"analyses"`
+		Bytes       int64 `json:"bytes"`
+		ActiveUsers int   `json:"active_users"`
+	}
+	err = db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(log_size_bytes), 0), COUNT(DISTINCT user_id)
+		FROM usage_logs WHERE analyzed_at >= $1 AND analyzed_at < $2`, since, until,
+	).Scan(&totals.Analyses, &totals.Bytes, &totals.ActiveUsers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	type dayUsage struct {
+		Day      string `json:"day"`
+		Analyses int    `json:"analyses"`
+		Bytes    int64  `json:"bytes"`
+	}
+	daily := []dayUsage{}
+	rows, err := db.Query(`
+		SELECT to_char(date_trunc('day', analyzed_at), 'YYYY-MM-DD'), COUNT(*), COALESCE(SUM(log_size_bytes), 0)
+		FROM usage_logs WHERE analyzed_at >= $1 AND analyzed_at < $2
+		GROUP BY 1 ORDER BY 1`, since, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d dayUsage
+		if err := rows.Scan(&d.Day, &d.Analyses, &d.Bytes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		daily = append(daily, d)
+	}
+
+	type userUsage struct {
+		UserID   int    `json:"user_id"`
+		Email    string `json:"email"`
+		Analyses int    `json:"analyses"`
+		Bytes    int64  `json:"bytes"`
+	}
+	top := []userUsage{}
+	topRows, err := db.Query(`
+		SELECT u.id, u.email, COUNT(*), COALESCE(SUM(l.log_size_bytes), 0)
+		FROM usage_logs l JOIN users u ON u.id = l.user_id
+		WHERE l.analyzed_at >= $1 AND l.analyzed_at < $2
+		GROUP BY u.id, u.email ORDER BY 3 DESC LIMIT 10`, since, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer topRows.Close()
+	for topRows.Next() {
+		var u userUsage
+		if err := topRows.Scan(&u.UserID, &u.Email, &u.Analyses, &u.Bytes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		top = append(top, u)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since":     since,
+		"until":     until,
+		"totals":    totals,
+		"daily":     daily,
+		"top_users": top,
+	})
+}
+
+// adminTargetUser parses the :id route parameter, writing a 400 on failure
+func adminTargetUser(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// execForUser runs an update against a single user, writing 404/500 responses as needed
+func execForUser(c *gin.Context, query string, args ...interface{}) bool {
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return false
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return false
+	}
+	return true
+}
+
+// recordAdminAction writes an admin action to admin_audit_log
+func recordAdminAction(c *gin.Context, action string, targetUserID int, details interface{}) {
+	adminID, _ := currentUserID(c)
+
+	var payload []byte
+	if details != nil {
+		payload, _ = json.Marshal(details)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO admin_audit_log (admin_id, action, target_user_id, details)
+		VALUES ($1, $2, $3, $4)`,
+		adminID, action, targetUserID, nullableJSON(payload))
+	if err != nil {
+		fmt.Printf("Failed to record admin action %s: %v\n", action, err)
+	}
+
+	event := audit.Event{
+		Type:    audit.TypeAdminAction,
+		Outcome: audit.OutcomeSuccess,
+		Target:  fmt.Sprintf("user:%d", targetUserID),
+		Details: map[string]interface{}{"action": action},
+	}
+	if details != nil {
+		event.Details["details"] = details
+	}
+	recordEvent(c, event)
+}
+
+func nullableJSON(payload []byte) interface{} {
+	if payload == nil {
+		return nil
+	}
+	return string(payload)
+}
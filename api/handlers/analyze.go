@@ -1,30 +1,284 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/AyomiCoder/loggar/api/jobs"
+	"github.com/AyomiCoder/loggar/api/webhooks"
+	"github.com/AyomiCoder/loggar/internal/audit"
 	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/logs"
+	"github.com/AyomiCoder/loggar/pkg/offline"
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
 type AnalyzeRequest struct {
-	Logs string `json:"logs" binding:"required"`
+	Logs    string `json:"logs" binding:"required"`
+	Profile string `json:"profile"`
+	// KnownIssues are matches of the client's own rules, e.g. from ~/.loggar/rules
+	KnownIssues []ai.KnownIssue `json:"known_issues"`
+	// Sources are several log files to correlate, sent instead of Logs
+	Sources []SourceRequest `json:"sources"`
+	// ClockSkew is the tolerance for aligning sources, e.g. "500ms" (default 2s)
+	ClockSkew string `json:"clock_skew"`
+	// Since and Until keep only records in a time range; Around keeps the
+	// WindowWidth (default 5m) centred on a timestamp. Values are timestamps
+	// or durations before now, e.g. "30m".
+	Since       string `json:"since"`
+	Until       string `json:"until"`
+	Around      string `json:"around"`
+	WindowWidth string `json:"window"`
+	// AutoWindow analyzes only the densest burst of errors, with context
+	AutoWindow bool `json:"auto_window"`
+
+	sources   []logs.Source
+	breakdown []ai.SourceBreakdown
+	skew      time.Duration
+	window    *ai.TimeWindow
+	lineMap   []ai.LineSpan
+}
+
+// SourceRequest is one named log file of a multi-source request
+type SourceRequest struct {
+	Name string `json:"name"`
+	Logs string `json:"logs"`
+}
+
+// options returns the analysis options selected by the request
+func (r *AnalyzeRequest) options() ai.Options {
+	return ai.Options{Profile: r.Profile, KnownIssues: r.KnownIssues, Sources: r.breakdown, ClockSkew: r.skew, Window: r.window, LineMap: r.lineMap}
 }
 
 // AnalyzeHandler handles log analysis requests
 func AnalyzeHandler(c *gin.Context) {
-	var req AnalyzeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "logs field is required"})
+	req, ok := bindAnalyzeRequest(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := currentUserID(c)
+
+	// Cached results are free: they are not counted against the quota and
+	// do not notify webhooks again
+	if result, ok := cachedAnalysis(c, req.Logs, req.options()); ok {
+		recordAnalysis(c, audit.OutcomeSuccess, len(req.Logs), nil)
+		c.Header("X-Loggar-Analysis-ID", saveAnalysis(c, userID, req.Logs, req.options(), result, true))
+		writeAnalysis(c, http.StatusOK, result)
+		return
+	}
+
+	if err := checkQuota(userID); err != nil {
+		rejectQuota(c, len(req.Logs), err)
 		return
 	}
 
 	// Analyze logs using AI
-	result, err := ai.AnalyzeLogs(req.Logs)
+	result, err := ai.AnalyzeLogs(req.Logs, req.options())
+	if fallback, ok := offline.Fallback(req.Logs, req.options(), err); ok {
+		fmt.Printf("AI provider unavailable, using offline analysis: %v\n", err)
+		c.Header("X-Loggar-Analyzer", offline.Profile)
+		result, err = fallback, nil
+	} else if err == nil {
+		storeAnalysis(c, req.Logs, req.options(), result)
+	}
 	if err != nil {
+		recordAnalysis(c, audit.OutcomeFailure, len(req.Logs), err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, result)
+	recordUsage(userID, len(req.Logs))
+	recordAnalysis(c, audit.OutcomeSuccess, len(req.Logs), nil)
+
+	c.Header("X-Loggar-Analysis-ID", saveAnalysis(c, userID, req.Logs, req.options(), result, false))
+	writeAnalysis(c, http.StatusOK, result)
+}
+
+// AnalyzeStreamHandler streams an analysis as Server-Sent Events: summary text
+// as it is generated, each section once complete, then the full result
+func AnalyzeStreamHandler(c *gin.Context) {
+	req, ok := bindAnalyzeRequest(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	cached, hit := cachedAnalysis(c, req.Logs, req.options())
+	if !hit {
+		if err := checkQuota(userID); err != nil {
+			rejectQuota(c, len(req.Logs), err)
+			return
+		}
+	}
+	version := schemaVersion(c)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(event ai.StreamEvent) {
+		switch event.Type {
+		case ai.EventSummary:
+			c.SSEvent(ai.EventSummary, gin.H{"text": event.Text})
+		case ai.EventSection:
+			section := *event.Section
+			if version < ai.SchemaV2 {
+				section.Evidence = nil
+				section.Claims = nil
+			}
+			c.SSEvent(ai.EventSection, section)
+		case ai.EventDone:
+			// The SSE id is the analysis ID, for follow-up questions
+			c.Render(-1, sse.Event{
+				Event: ai.EventDone,
+				Id:    saveAnalysis(c, userID, req.Logs, req.options(), event.Result, hit),
+				Data:  event.Result.Version(version),
+			})
+		}
+		c.Writer.Flush()
+	}
+
+	// replay sends a finished result as if it had been streamed
+	replay := func(result *ai.AnalysisResult) {
+		send(ai.StreamEvent{Type: ai.EventSummary, Text: result.Summary})
+		for i := range result.Sections {
+			send(ai.StreamEvent{Type: ai.EventSection, Section: &result.Sections[i]})
+		}
+		send(ai.StreamEvent{Type: ai.EventDone, Result: result})
+		if !hit {
+			recordUsage(userID, len(req.Logs))
+		}
+		recordAnalysis(c, audit.OutcomeSuccess, len(req.Logs), nil)
+	}
+
+	if hit {
+		replay(cached)
+		return
+	}
+
+	result, err := ai.AnalyzeLogsStream(req.Logs, req.options(), send)
+	if fallback, ok := offline.Fallback(req.Logs, req.options(), err); ok {
+		// The provider failed before any event was sent
+		fmt.Printf("AI provider unavailable, using offline analysis: %v\n", err)
+		replay(fallback)
+		return
+	}
+	if err != nil {
+		recordAnalysis(c, audit.OutcomeFailure, len(req.Logs), err)
+		c.SSEvent(ai.EventError, gin.H{"error": err.Error()})
+		c.Writer.Flush()
+		return
+	}
+
+	storeAnalysis(c, req.Logs, req.options(), result)
+	recordUsage(userID, len(req.Logs))
+	recordAnalysis(c, audit.OutcomeSuccess, len(req.Logs), nil)
+}
+
+// saveAnalysis stores a result as a finished job so that follow-up questions
+// can refer to it, notifies the user's webhooks unless the result came from
+// the cache, and returns its ID. A cached result the user already has keeps
+// its ID. It returns "" without a database, for anonymous requests, or when
+// storing fails.
+func saveAnalysis(c *gin.Context, userID int, logs string, opts ai.Options, result *ai.AnalysisResult, cached bool) string {
+	if db == nil || userID == 0 {
+		return ""
+	}
+	key := ai.CacheKey(logs, opts)
+	if cached {
+		if id, err := jobs.FindRecorded(c.Request.Context(), db, userID, key, result); err == nil {
+			return strconv.FormatInt(id, 10)
+		}
+	}
+	id, err := jobs.Record(c.Request.Context(), db, userID, logs, key, storeLogs(), result)
+	if err != nil {
+		fmt.Printf("Failed to store analysis: %v\n", err)
+		return ""
+	}
+	if !cached {
+		if err := webhooks.Notify(c.Request.Context(), db, userID, webhooks.CompletedEvent(id, result)); err != nil {
+			fmt.Printf("Failed to queue webhooks for analysis %d: %v\n", id, err)
+		}
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// storeLogs reports whether the logs of synchronous analyses are stored for
+// follow-up questions. Set ANALYSIS_STORE_LOGS=off to keep only their results.
+func storeLogs() bool {
+	switch strings.ToLower(os.Getenv("ANALYSIS_STORE_LOGS")) {
+	case "off", "false", "0":
+		return false
+	}
+	return true
+}
+
+// recordAnalysis records an analysis attempt in the audit log
+func recordAnalysis(c *gin.Context, outcome string, logSize int, err error) {
+	details := map[string]interface{}{"log_size_bytes": logSize}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	recordEvent(c, audit.Event{Type: audit.TypeAnalysis, Outcome: outcome, Details: details})
+}
+
+// errQuotaUnavailable is returned when the quota could not be checked
+var errQuotaUnavailable = errors.New("could not check the analysis quota")
+
+// checkQuota returns an error once a user has used up their monthly analysis
+// quota, or errQuotaUnavailable when it could not be checked
+func checkQuota(userID int) error {
+	if db == nil || userID == 0 {
+		return nil
+	}
+
+	var quota sql.NullInt64
+	var used int64
+	err := db.QueryRow(`
+		SELECT u.monthly_analysis_quota,
+			(SELECT COUNT(*) FROM usage_logs l
+			 WHERE l.user_id = u.id AND l.analyzed_at >= date_trunc('month', NOW()))
+		FROM users u WHERE u.id = $1`, userID).Scan(&quota, &used)
+	if err != nil {
+		fmt.Printf("Failed to check quota of user %d: %v\n", userID, err)
+		return errQuotaUnavailable
+	}
+	if !quota.Valid {
+		return nil
+	}
+	if used >= quota.Int64 {
+		return fmt.Errorf("monthly analysis quota of %d reached", quota.Int64)
+	}
+	return nil
+}
+
+// rejectQuota writes the response to a failed quota check: 429 once the quota
+// is used up, 503 when it could not be checked
+func rejectQuota(c *gin.Context, logSize int, err error) {
+	status, outcome := http.StatusTooManyRequests, audit.OutcomeDenied
+	if errors.Is(err, errQuotaUnavailable) {
+		status, outcome = http.StatusServiceUnavailable, audit.OutcomeFailure
+	}
+	recordAnalysis(c, outcome, logSize, err)
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// recordUsage stores an analysis in usage_logs
+func recordUsage(userID, logSize int) {
+	if db == nil || userID == 0 {
+		return
+	}
+	if _, err := db.Exec("INSERT INTO usage_logs (user_id, log_size_bytes) VALUES ($1, $2)", userID, logSize); err != nil {
+		fmt.Printf("Failed to record usage: %v\n", err)
+	}
 }
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// recordEvent fills in the request metadata and authenticated actor, then records the event
+func recordEvent(c *gin.Context, event audit.Event) {
+	if event.ActorID == 0 {
+		event.ActorID, _ = currentUserID(c)
+	}
+	if event.ActorEmail == "" {
+		event.ActorEmail = c.GetString("email")
+	}
+	event.IP = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	if method := c.GetString("auth_method"); method != "" {
+		if event.Details == nil {
+			event.Details = map[string]interface{}{}
+		}
+		event.Details["auth_method"] = method
+	}
+	audit.Record(c.Request.Context(), event)
+}
+
+// AuditHandler returns audit events, filtered by query parameters.
+// Pass format=jsonl (or Accept: application/x-ndjson) to export as JSON lines.
+func AuditHandler(c *gin.Context) {
+	filter := audit.Filter{
+		Type:    c.Query("type"),
+		Outcome: c.Query("outcome"),
+	}
+
+	var err error
+	if v := c.Query("actor_id"); v != "" {
+		if filter.ActorID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_id"})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+	if v := c.Query("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+
+	events, err := audit.Query(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	if c.Query("format") == "jsonl" || c.GetHeader("Accept") == "application/x-ndjson" {
+		recordEvent(c, audit.Event{
+			Type:    audit.TypeAuditExport,
+			Outcome: audit.OutcomeSuccess,
+			Details: map[string]interface{}{"events": len(events)},
+		})
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="audit-events.jsonl"`)
+		c.Status(http.StatusOK)
+		audit.WriteJSONLines(c.Writer, events)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
@@ -9,8 +9,10 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/AyomiCoder/loggar/internal/audit"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
@@ -98,6 +100,7 @@ func verifyState(c *gin.Context) error {
 
 func AuthGitHubCallbackHandler(c *gin.Context) {
 	if err := verifyState(c); err != nil {
+		recordLoginFailure(c, "github", err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid state parameter. Please try again."})
 		return
 	}
@@ -114,12 +117,14 @@ func AuthGitHubCallbackHandler(c *gin.Context) {
 	config := getGithubOauthConfig()
 	token, err := config.Exchange(context.Background(), code)
 	if err != nil {
+		recordLoginFailure(c, "github", "failed to exchange token")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to exchange token"})
 		return
 	}
 	client := config.Client(context.Background(), token)
 	resp, err := client.Get("https://api.github.com/user")
 	if err != nil {
+		recordLoginFailure(c, "github", "failed to get user info")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user info"})
 		return
 	}
@@ -131,6 +136,7 @@ func AuthGitHubCallbackHandler(c *gin.Context) {
 		Email string `json:"email"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&githubUser); err != nil {
+		recordLoginFailure(c, "github", "failed to parse user info")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse user info"})
 		return
 	}
@@ -145,6 +151,7 @@ func AuthGitHubCallbackHandler(c *gin.Context) {
 
 func AuthGoogleCallbackHandler(c *gin.Context) {
 	if err := verifyState(c); err != nil {
+		recordLoginFailure(c, "google", err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid state parameter"})
 		return
 	}
@@ -156,12 +163,14 @@ func AuthGoogleCallbackHandler(c *gin.Context) {
 	config := getGoogleOauthConfig()
 	token, err := config.Exchange(context.Background(), code)
 	if err != nil {
+		recordLoginFailure(c, "google", "failed to exchange token")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to exchange token"})
 		return
 	}
 	client := config.Client(context.Background(), token)
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
 	if err != nil {
+		recordLoginFailure(c, "google", "failed to get user info")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user info"})
 		return
 	}
@@ -172,6 +181,7 @@ func AuthGoogleCallbackHandler(c *gin.Context) {
 		Email string `json:"email"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
+		recordLoginFailure(c, "google", "failed to parse user info")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse user info"})
 		return
 	}
@@ -180,22 +190,49 @@ func AuthGoogleCallbackHandler(c *gin.Context) {
 }
 
 func completeFlow(c *gin.Context, email, provider, providerID, cliPort string) {
+	role := "user"
+	if isBootstrapAdmin(email) {
+		role = "admin"
+	}
+
 	var userID int
+	var disabled bool
 	err := db.QueryRow(`
-		INSERT INTO users (email, provider, provider_id, password_hash) 
-		VALUES ($1, $2, $3, NULL)
+		INSERT INTO users (email, provider, provider_id, password_hash, role) 
+		VALUES ($1, $2, $3, NULL, $4)
 		ON CONFLICT (email) DO UPDATE 
-		SET provider = $2, provider_id = $3
-		RETURNING id`,
-		email, provider, providerID).Scan(&userID)
+		SET provider = $2, provider_id = $3,
+			role = CASE WHEN $4 = 'admin' THEN 'admin' ELSE users.role END
+		RETURNING id, disabled_at IS NOT NULL`,
+		email, provider, providerID, role).Scan(&userID, &disabled)
 
 	if err != nil {
+		recordLoginFailure(c, provider, "database error")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
 
+	if disabled {
+		recordEvent(c, audit.Event{
+			Type:       audit.TypeLogin,
+			Outcome:    audit.OutcomeDenied,
+			ActorID:    userID,
+			ActorEmail: email,
+			Details:    map[string]interface{}{"provider": provider, "reason": "account disabled"},
+		})
+		c.JSON(http.StatusForbidden, gin.H{"error": "account disabled"})
+		return
+	}
+
 	jwtToken, err := generateJWT(userID, email)
 	if err != nil {
+		recordEvent(c, audit.Event{
+			Type:       audit.TypeTokenIssued,
+			Outcome:    audit.OutcomeFailure,
+			ActorID:    userID,
+			ActorEmail: email,
+			Details:    map[string]interface{}{"provider": provider},
+		})
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
@@ -204,10 +241,45 @@ func completeFlow(c *gin.Context, email, provider, providerID, cliPort string) {
 	if err != nil {
 		fmt.Printf("Failed to save token: %v\n", err)
 	}
+
+	recordEvent(c, audit.Event{
+		Type:       audit.TypeLogin,
+		Outcome:    audit.OutcomeSuccess,
+		ActorID:    userID,
+		ActorEmail: email,
+		Details:    map[string]interface{}{"provider": provider},
+	})
+	recordEvent(c, audit.Event{
+		Type:       audit.TypeTokenIssued,
+		Outcome:    audit.OutcomeSuccess,
+		ActorID:    userID,
+		ActorEmail: email,
+		Details:    map[string]interface{}{"provider": provider, "persisted": err == nil},
+	})
+
 	redirectURL := fmt.Sprintf("http://localhost:%s/callback?token=%s&email=%s", cliPort, jwtToken, email)
 	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 }
 
+// recordLoginFailure records a failed OAuth login attempt
+func recordLoginFailure(c *gin.Context, provider, reason string) {
+	recordEvent(c, audit.Event{
+		Type:    audit.TypeLogin,
+		Outcome: audit.OutcomeFailure,
+		Details: map[string]interface{}{"provider": provider, "reason": reason},
+	})
+}
+
+// isBootstrapAdmin reports whether email is listed in ADMIN_EMAILS
+func isBootstrapAdmin(email string) bool {
+	for _, admin := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
 func generateJWT(userID int, email string) (string, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/AyomiCoder/loggar/internal/cache"
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/gin-gonic/gin"
+)
+
+var analysisCache cache.Cache
+
+// SetCache sets the cache used for analysis results. A nil cache disables caching.
+func SetCache(c cache.Cache) {
+	analysisCache = c
+}
+
+// cachedAnalysis looks up a previous result for logs and options. It sets the
+// X-Loggar-Cache header and honours Cache-Control: no-cache / no-store.
+func cachedAnalysis(c *gin.Context, logs string, opts ai.Options) (*ai.AnalysisResult, bool) {
+	c.Header("X-Loggar-Cache", "miss")
+	if analysisCache == nil || cacheDirective(c, "no-cache") || cacheDirective(c, "no-store") {
+		return nil, false
+	}
+
+	data, ok := analysisCache.Get(ai.CacheKey(logs, opts))
+	if !ok {
+		return nil, false
+	}
+	var result ai.AnalysisResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false
+	}
+
+	c.Header("X-Loggar-Cache", "hit")
+	return &result, true
+}
+
+// storeAnalysis caches a fresh result unless the client sent Cache-Control: no-store
+func storeAnalysis(c *gin.Context, logs string, opts ai.Options, result *ai.AnalysisResult) {
+	if analysisCache == nil || cacheDirective(c, "no-store") {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	analysisCache.Set(ai.CacheKey(logs, opts), data)
+}
+
+func cacheDirective(c *gin.Context, directive string) bool {
+	for _, d := range strings.Split(c.GetHeader("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(d), directive) {
+			return true
+		}
+	}
+	return false
+}
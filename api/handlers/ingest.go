@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/AyomiCoder/loggar/api/jobs"
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/ingest"
+	"github.com/AyomiCoder/loggar/pkg/logs"
+	"github.com/AyomiCoder/loggar/pkg/rules"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStreamName caps the length of a stream name, in characters
+const maxStreamName = 128
+
+var ingestBuffer *ingest.Buffer
+
+// SetIngestBuffer sets the buffer that ingested logs are added to
+func SetIngestBuffer(b *ingest.Buffer) {
+	ingestBuffer = b
+}
+
+// IngestHandler accepts a batch from a log shipper: JSON lines, Fluent Bit
+// or Vector JSON, or an OTLP/HTTP logs export in protobuf or JSON. Records
+// are buffered per stream, named by ?stream=, the X-Loggar-Stream header or
+// the records' service, and error bursts are analyzed as jobs.
+func IngestHandler(c *gin.Context) {
+	if ingestBuffer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "log ingestion is not enabled"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return
+	}
+
+	body, err := readIngestBody(c)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.Is(err, logs.ErrTooLarge) || errors.As(err, &maxErr) {
+			status = http.StatusRequestEntityTooLarge
+			err = fmt.Errorf("batch exceeds the maximum size of %d bytes", maxLogBytes())
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	records, format, err := ingest.Decode(c.GetHeader("Content-Type"), body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stream := c.Query("stream")
+	if stream == "" {
+		stream = c.GetHeader("X-Loggar-Stream")
+	}
+	var names []string
+	lines := make(map[string][]string)
+	for _, r := range records {
+		name := streamName(stream, r.Stream)
+		if _, ok := lines[name]; !ok {
+			names = append(names, name)
+		}
+		lines[name] = append(lines[name], r.Lines()...)
+	}
+	// Streams past the account's limit are folded into the default stream
+	var added []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		key := ingestBuffer.Add(ingest.Key{UserID: userID, Stream: name}, lines[name])
+		if !seen[key.Stream] {
+			seen[key.Stream] = true
+			added = append(added, key.Stream)
+		}
+	}
+
+	// OTLP exporters expect an empty ExportLogsServiceResponse
+	switch format {
+	case ingest.FormatOTLP:
+		c.Data(http.StatusOK, "application/x-protobuf", nil)
+	case ingest.FormatOTLPJSON:
+		c.JSON(http.StatusOK, gin.H{})
+	default:
+		c.JSON(http.StatusOK, gin.H{"accepted": len(records), "streams": added})
+	}
+}
+
+// readIngestBody reads a request body of at most MAX_LOG_BYTES, decompressed
+func readIngestBody(c *gin.Context) ([]byte, error) {
+	limit := maxLogBytes()
+	body, err := logs.Decompress(c.GetHeader("Content-Encoding"), http.MaxBytesReader(c.Writer, c.Request.Body, limit), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, logs.ErrTooLarge
+	}
+	return data, nil
+}
+
+// streamName picks the stream of a record: the one named by the request,
+// else the record's service, else the default stream
+func streamName(requested, service string) string {
+	name := strings.TrimSpace(requested)
+	if name == "" {
+		name = strings.TrimSpace(service)
+	}
+	if name == "" {
+		return ingest.DefaultStream
+	}
+	if r := []rune(name); len(r) > maxStreamName {
+		name = string(r[:maxStreamName])
+	}
+	return name
+}
+
+// AnalyzeIngested queues an analysis of an error burst in an ingested
+// stream. It counts towards the account's monthly quota like any job.
+func AnalyzeIngested(inc ingest.Incident) {
+	if db == nil {
+		return
+	}
+	if !jobsEnabled {
+		fmt.Printf("Skipped analysis of stream %q: analysis jobs are disabled\n", inc.Stream)
+		return
+	}
+	ctx := context.Background()
+	logText := inc.Logs()
+
+	if err := checkQuota(inc.UserID); err != nil {
+		audit.Record(ctx, audit.Event{
+			Type:    audit.TypeAnalysis,
+			Outcome: audit.OutcomeDenied,
+			ActorID: inc.UserID,
+			Target:  "ingest:" + inc.Stream,
+			Details: map[string]interface{}{"log_size_bytes": len(logText), "error": err.Error()},
+		})
+		fmt.Printf("Skipped analysis of stream %q: %v\n", inc.Stream, err)
+		return
+	}
+
+	opts := ai.Options{Profile: ai.DefaultProfile, KnownIssues: rules.Match(detectionRules, logText)}
+	job, err := jobs.Enqueue(ctx, db, inc.UserID, logText, opts)
+	if err != nil {
+		fmt.Printf("Failed to queue analysis of stream %q: %v\n", inc.Stream, err)
+		return
+	}
+	fmt.Printf("Queued job %d for %d errors in stream %q\n", job.ID, inc.Errors, inc.Stream)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AyomiCoder/loggar/api/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+var jobsEnabled bool
+
+// SetJobsEnabled records whether workers run queued jobs. Without them jobs
+// are refused rather than queued forever.
+func SetJobsEnabled(enabled bool) {
+	jobsEnabled = enabled
+}
+
+// CreateJobHandler queues an analysis and returns immediately with the job ID
+func CreateJobHandler(c *gin.Context) {
+	if !jobsEnabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "analysis jobs are disabled on this server"})
+		return
+	}
+	req, ok := bindAnalyzeRequest(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	if err := checkQuota(userID); err != nil {
+		rejectQuota(c, len(req.Logs), err)
+		return
+	}
+
+	job, err := jobs.Enqueue(c.Request.Context(), db, userID, req.Logs, req.options())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.Header("Location", "/api/jobs/"+strconv.FormatInt(job.ID, 10))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJobHandler returns the status of a job, and its result once finished
+func GetJobHandler(c *gin.Context) {
+	jobID, userID, ok := jobParams(c)
+	if !ok {
+		return
+	}
+
+	job, err := jobs.Get(c.Request.Context(), db, jobID, userID)
+	if errors.Is(err, jobs.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	job.Result = job.Result.Version(schemaVersion(c))
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJobHandler cancels a queued or running job
+func CancelJobHandler(c *gin.Context) {
+	jobID, userID, ok := jobParams(c)
+	if !ok {
+		return
+	}
+
+	job, err := jobs.Cancel(c.Request.Context(), db, jobID, userID)
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, jobs.ErrFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "job": job})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	default:
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// jobParams reads the caller and the :id route parameter, writing an error response on failure
+func jobParams(c *gin.Context) (int64, int, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return 0, 0, false
+	}
+	jobID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return jobID, userID, true
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AyomiCoder/loggar/internal/apikey"
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// APIKey is the public view of a stored key. The plaintext Key is only set on creation.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// currentUserID returns the authenticated user's ID set by AuthMiddleware
+func currentUserID(c *gin.Context) (int, bool) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	id, ok := userID.(int)
+	return id, ok
+}
+
+// CreateAPIKeyHandler issues a new API key and returns it once
+func CreateAPIKeyHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name field is required"})
+		return
+	}
+
+	scopes, err := apikey.ParseScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// A key can only create keys with a subset of its own scopes
+	if c.GetString("auth_method") == "api_key" {
+		own := c.GetStringSlice("scopes")
+		if len(req.Scopes) == 0 {
+			scopes = own
+		}
+		for _, s := range scopes {
+			if !hasScope(own, s) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "api key cannot grant the " + s + " scope"})
+				return
+			}
+		}
+	}
+
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be positive"})
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	key, err := apikey.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate key"})
+		return
+	}
+
+	created := APIKey{
+		Name:      req.Name,
+		Key:       key,
+		Prefix:    apikey.DisplayPrefix(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	err = db.QueryRow(`
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		userID, req.Name, created.Prefix, apikey.Hash(key), apikey.JoinScopes(scopes), expiresAt,
+	).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	recordEvent(c, audit.Event{
+		Type:    audit.TypeAPIKeyCreated,
+		Outcome: audit.OutcomeSuccess,
+		Target:  fmt.Sprintf("api_key:%d", created.ID),
+		Details: map[string]interface{}{"name": created.Name, "prefix": created.Prefix, "scopes": created.Scopes},
+	})
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ListAPIKeysHandler lists the caller's active API keys without their secrets
+func ListAPIKeysHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, name, prefix, scopes, created_at, last_used_at, expires_at
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var (
+			k         APIKey
+			scopes    string
+			lastUsed  sql.NullTime
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &lastUsed, &expiresAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		k.Scopes = apikey.SplitScopes(scopes)
+		if lastUsed.Valid {
+			k.LastUsedAt = &lastUsed.Time
+		}
+		if expiresAt.Valid {
+			k.ExpiresAt = &expiresAt.Time
+		}
+		keys = append(keys, k)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// RevokeAPIKeyHandler revokes one of the caller's API keys
+func RevokeAPIKeyHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return
+	}
+
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key id"})
+		return
+	}
+
+	res, err := db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, keyID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+
+	recordEvent(c, audit.Event{
+		Type:    audit.TypeAPIKeyRevoked,
+		Outcome: audit.OutcomeSuccess,
+		Target:  fmt.Sprintf("api_key:%d", keyID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
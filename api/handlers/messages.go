@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AyomiCoder/loggar/api/jobs"
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/gin-gonic/gin"
+)
+
+// Limits on follow-up questions, which re-send the logs and the whole
+// conversation to the provider
+const (
+	maxMessageBytes = 4 << 10
+	maxQuestions    = 20
+)
+
+// MessageRequest is a follow-up question about an analysis
+type MessageRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// ListMessagesHandler returns the follow-up conversation about an analysis
+func ListMessagesHandler(c *gin.Context) {
+	jobID, userID, ok := jobParams(c)
+	if !ok {
+		return
+	}
+	if _, ok := finishedAnalysis(c, jobID, userID); !ok {
+		return
+	}
+
+	messages, err := jobs.Messages(c.Request.Context(), db, jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// CreateMessageHandler asks a follow-up question about an analysis. The
+// provider gets the original logs, the result and the conversation so far.
+// Each question counts towards the monthly quota like an analysis.
+func CreateMessageHandler(c *gin.Context) {
+	jobID, userID, ok := jobParams(c)
+	if !ok {
+		return
+	}
+	var req MessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message field is required"})
+		return
+	}
+	if len(req.Message) > maxMessageBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("message exceeds the maximum size of %d bytes", maxMessageBytes)})
+		return
+	}
+
+	job, ok := finishedAnalysis(c, jobID, userID)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	logs, err := jobs.Logs(ctx, db, jobID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if logs == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "the logs of this analysis were not kept"})
+		return
+	}
+	history, err := jobs.Messages(ctx, db, jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if len(history) >= 2*maxQuestions {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("at most %d questions can be asked about an analysis", maxQuestions)})
+		return
+	}
+	if err := checkQuota(userID); err != nil {
+		rejectQuota(c, len(logs), err)
+		return
+	}
+
+	answer, err := ai.Ask(logs, job.Result, history, req.Message)
+	if err != nil {
+		recordAnalysis(c, audit.OutcomeFailure, len(logs), err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, ai.ErrProviderUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	recordUsage(userID, len(logs))
+	recordAnalysis(c, audit.OutcomeSuccess, len(logs), nil)
+
+	turn := []ai.Message{{Role: ai.RoleUser, Content: req.Message}, {Role: ai.RoleAssistant, Content: answer}}
+	if err := jobs.AddMessages(ctx, db, jobID, turn...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": turn[1], "messages": append(history, turn...)})
+}
+
+// finishedAnalysis loads a succeeded job, writing an error response when it
+// does not exist or has no result yet
+func finishedAnalysis(c *gin.Context, jobID int64, userID int) (*jobs.Job, bool) {
+	job, err := jobs.Get(c.Request.Context(), db, jobID, userID)
+	if errors.Is(err, jobs.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "analysis not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, false
+	}
+	if job.Status != jobs.StatusSucceeded || job.Result == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "analysis has not finished", "status": job.Status})
+		return nil, false
+	}
+	return job, true
+}
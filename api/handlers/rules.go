@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"github.com/AyomiCoder/loggar/pkg/rules"
+)
+
+// maxClientKnownIssues caps the known issues a client may send with its logs
+const maxClientKnownIssues = 50
+
+var detectionRules []*rules.Rule
+
+// SetRules sets the server-wide detection rules matched against every analysis
+func SetRules(r []*rules.Rule) {
+	detectionRules = r
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/logs"
+	"github.com/AyomiCoder/loggar/pkg/rules"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxLogBytes caps decompressed log input when MAX_LOG_BYTES is not set
+const defaultMaxLogBytes = 10 << 20
+
+// maxLogBytes returns the configured limit on decompressed log input
+func maxLogBytes() int64 {
+	if v := os.Getenv("MAX_LOG_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxLogBytes
+}
+
+// bindAnalyzeRequest reads logs from a JSON body, a multipart upload of one or
+// more files, or a raw file body. Bodies may be gzip or zstd encoded and files
+// may be compressed or tar/zip archives. The analysis profile comes from the
+// JSON body, a "profile" form field or the ?profile= query parameter.
+// Several files are merged onto one timeline, aligned within the clock skew
+// from "clock_skew" in the body or form, or the ?clock_skew= parameter.
+// Only records within the requested time window are kept (see
+// requestWindow). On failure it writes the error response.
+func bindAnalyzeRequest(c *gin.Context) (*AnalyzeRequest, bool) {
+	req, err := readAnalyzeRequest(c)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.Is(err, logs.ErrTooLarge) || errors.As(err, &maxErr) {
+			status = http.StatusRequestEntityTooLarge
+			err = fmt.Errorf("logs exceed the maximum size of %d bytes", maxLogBytes())
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if req.ClockSkew == "" {
+		req.ClockSkew = c.Query("clock_skew")
+	}
+	req.skew = logs.DefaultClockSkew
+	if req.ClockSkew != "" {
+		skew, err := time.ParseDuration(req.ClockSkew)
+		if err != nil || skew < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid clock_skew %q", req.ClockSkew)})
+			return nil, false
+		}
+		req.skew = skew
+	}
+	sources := req.sources
+	if len(sources) == 0 {
+		sources = []logs.Source{{Text: req.Logs}}
+	}
+	blank := true
+	for _, src := range sources {
+		blank = blank && strings.TrimSpace(src.Text) == ""
+	}
+	if blank {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "logs field is required"})
+		return nil, false
+	}
+	window, err := requestWindow(c, req, sources)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	text, stats, spans := logs.Correlate(sources, req.skew, window)
+	if !window.IsZero() && text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errEmptyWindow.Error()})
+		return nil, false
+	}
+	req.Logs = text
+	req.breakdown = sourceBreakdown(stats)
+	req.lineMap = lineMap(spans)
+	if req.Profile == "" {
+		req.Profile = c.Query("profile")
+	}
+	if _, err := ai.GetPrompt(req.Profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(req.KnownIssues) > maxClientKnownIssues {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d known_issues are accepted", maxClientKnownIssues)})
+		return nil, false
+	}
+	req.KnownIssues = append(ai.ValidKnownIssues(req.KnownIssues, req.Logs), rules.Match(detectionRules, req.Logs)...)
+	return req, true
+}
+
+// sourceBreakdown converts correlation stats for the analysis; a single
+// source has no breakdown
+func sourceBreakdown(stats []logs.Stats) []ai.SourceBreakdown {
+	if len(stats) < 2 {
+		return nil
+	}
+	out := make([]ai.SourceBreakdown, len(stats))
+	for i, s := range stats {
+		out[i] = ai.SourceBreakdown{Name: s.Name, Lines: s.Lines, Errors: s.Errors, Warnings: s.Warnings}
+		if !s.First.IsZero() {
+			out[i].First = s.First.Format(time.RFC3339Nano)
+			out[i].Last = s.Last.Format(time.RFC3339Nano)
+		}
+	}
+	return out
+}
+
+// lineMap converts the spans of correlated logs for the analysis
+func lineMap(spans []logs.Span) []ai.LineSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]ai.LineSpan, len(spans))
+	for i, s := range spans {
+		out[i] = ai.LineSpan{Start: s.Start, End: s.End, Source: s.Source, Line: s.Line}
+	}
+	return out
+}
+
+func readAnalyzeRequest(c *gin.Context) (*AnalyzeRequest, error) {
+	limit := maxLogBytes()
+	body, err := logs.Decompress(c.GetHeader("Content-Encoding"), http.MaxBytesReader(c.Writer, c.Request.Body, limit), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	budget := logs.NewBudget(limit)
+	mediaType, params, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+
+	switch {
+	case mediaType == "" || mediaType == "application/json":
+		data, err := io.ReadAll(io.LimitReader(body, limit+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > limit {
+			return nil, logs.ErrTooLarge
+		}
+		var req AnalyzeRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, fmt.Errorf("logs field is required")
+		}
+		size := len(req.Logs)
+		for i, src := range req.Sources {
+			size += len(src.Logs)
+			name := src.Name
+			if name == "" {
+				name = fmt.Sprintf("source-%d", i+1)
+			}
+			req.sources = append(req.sources, logs.Source{Name: name, Text: src.Logs})
+		}
+		if int64(size) > limit {
+			return nil, logs.ErrTooLarge
+		}
+		if req.Logs != "" && len(req.sources) > 0 {
+			return nil, fmt.Errorf("send either logs or sources, not both")
+		}
+		return &req, nil
+
+	case mediaType == "multipart/form-data":
+		return readMultipart(body, params["boundary"], budget)
+
+	default:
+		name := c.GetHeader("X-Loggar-Filename")
+		if name == "" {
+			name = "upload"
+		}
+		sources, err := logs.Extract(name, body, budget)
+		if err != nil {
+			return nil, err
+		}
+		return &AnalyzeRequest{sources: sources}, nil
+	}
+}
+
+// readMultipart collects every file part, plus optional "logs" text and the
+// profile, clock skew and time window fields
+func readMultipart(body io.Reader, boundary string, budget *logs.Budget) (*AnalyzeRequest, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("multipart boundary missing")
+	}
+
+	var (
+		sources []logs.Source
+		fields  = map[string]string{
+			"profile": "", "clock_skew": "",
+			"since": "", "until": "", "around": "", "window": "", "auto_window": "",
+		}
+	)
+	mr := multipart.NewReader(body, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+
+		name := part.FileName()
+		if _, ok := fields[part.FormName()]; ok && name == "" {
+			value, err := io.ReadAll(io.LimitReader(part, 256))
+			part.Close()
+			if err != nil {
+				return nil, fmt.Errorf("invalid multipart body: %w", err)
+			}
+			fields[part.FormName()] = strings.TrimSpace(string(value))
+			continue
+		}
+		if name == "" && part.FormName() != "logs" {
+			part.Close()
+			continue
+		}
+		if name == "" {
+			name = "logs"
+		}
+
+		extracted, err := logs.Extract(name, part, budget)
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, extracted...)
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no log files uploaded")
+	}
+	req := &AnalyzeRequest{
+		sources:     sources,
+		Profile:     fields["profile"],
+		ClockSkew:   fields["clock_skew"],
+		Since:       fields["since"],
+		Until:       fields["until"],
+		Around:      fields["around"],
+		WindowWidth: fields["window"],
+	}
+	if fields["auto_window"] != "" {
+		auto, err := strconv.ParseBool(fields["auto_window"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid auto_window %q", fields["auto_window"])
+		}
+		req.AutoWindow = auto
+	}
+	return req, nil
+}
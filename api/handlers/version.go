@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/gin-gonic/gin"
+)
+
+// Vendor media types that select the analysis response schema
+const (
+	mediaTypeV1 = "application/vnd.loggar.v1+json"
+	mediaTypeV2 = "application/vnd.loggar.v2+json"
+)
+
+// schemaVersion negotiates the analysis schema from the Accept header. Clients
+// opt into v2 with application/vnd.loggar.v2+json, or a version=2 parameter on
+// any media type (e.g. text/event-stream; version=2). Everyone else gets v1.
+func schemaVersion(c *gin.Context) int {
+	c.Header("Vary", "Accept")
+
+	version := ai.SchemaV1
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch {
+		case mediaType == mediaTypeV2:
+			version = ai.SchemaV2
+		case mediaType == mediaTypeV1:
+			// explicit v1
+		default:
+			if n, err := strconv.Atoi(params["version"]); err == nil && n > version {
+				version = n
+			}
+		}
+	}
+	if version > ai.SchemaV2 {
+		version = ai.SchemaV2
+	}
+	return version
+}
+
+// writeAnalysis responds with result in the negotiated schema version
+func writeAnalysis(c *gin.Context, status int, result *ai.AnalysisResult) {
+	version := schemaVersion(c)
+	if version >= ai.SchemaV2 {
+		c.Header("Content-Type", mediaTypeV2+"; charset=utf-8")
+	}
+	c.IndentedJSON(status, result.Version(version))
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AyomiCoder/loggar/api/webhooks"
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Format      string   `json:"format"`
+	Events      []string `json:"events"`
+	MinSeverity string   `json:"min_severity"`
+}
+
+// CreateWebhookHandler registers a webhook and returns its signing secret once
+func CreateWebhookHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url field is required"})
+		return
+	}
+	hook := webhooks.Webhook{URL: req.URL, Format: req.Format, Events: req.Events, MinSeverity: req.MinSeverity}
+	if err := hook.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := webhooks.Create(c.Request.Context(), db, userID, &hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	recordEvent(c, audit.Event{
+		Type:    audit.TypeWebhookCreated,
+		Outcome: audit.OutcomeSuccess,
+		Target:  fmt.Sprintf("webhook:%d", hook.ID),
+		Details: map[string]interface{}{"url": hook.URL, "format": hook.Format, "events": hook.Events},
+	})
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+// ListWebhooksHandler lists the caller's webhooks without their secrets
+func ListWebhooksHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return
+	}
+
+	hooks, err := webhooks.List(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+// DeleteWebhookHandler removes one of the caller's webhooks
+func DeleteWebhookHandler(c *gin.Context) {
+	hookID, userID, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	err := webhooks.Delete(c.Request.Context(), db, hookID, userID)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	recordEvent(c, audit.Event{
+		Type:    audit.TypeWebhookDeleted,
+		Outcome: audit.OutcomeSuccess,
+		Target:  fmt.Sprintf("webhook:%d", hookID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListDeliveriesHandler returns the delivery log of a webhook, newest first
+func ListDeliveriesHandler(c *gin.Context) {
+	hookID, userID, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := webhooks.Deliveries(c.Request.Context(), db, hookID, userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// ReplayDeliveryHandler sends the payload of an earlier delivery again
+func ReplayDeliveryHandler(c *gin.Context) {
+	hookID, userID, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery id"})
+		return
+	}
+
+	delivery, err := webhooks.Replay(c.Request.Context(), db, hookID, deliveryID, userID)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// webhookParams reads the caller and the :id route parameter, writing an error response on failure
+func webhookParams(c *gin.Context) (int64, int, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown user"})
+		return 0, 0, false
+	}
+	hookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+		return 0, 0, false
+	}
+	return hookID, userID, true
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/logs"
+	"github.com/gin-gonic/gin"
+)
+
+// errEmptyWindow is returned when no log lines fall within the requested window
+var errEmptyWindow = errors.New("no log lines within the time window")
+
+// requestWindow returns the time window the sources are cut to: since/until,
+// around with a window width, and/or the densest burst of errors with
+// auto_window. Query parameters of the same names fill in fields missing from
+// the body. It records the window in req.window.
+func requestWindow(c *gin.Context, req *AnalyzeRequest, sources []logs.Source) (logs.Window, error) {
+	for _, field := range []struct {
+		value *string
+		name  string
+	}{{&req.Since, "since"}, {&req.Until, "until"}, {&req.Around, "around"}, {&req.WindowWidth, "window"}} {
+		if *field.value == "" {
+			*field.value = c.Query(field.name)
+		}
+	}
+	if !req.AutoWindow && c.Query("auto_window") != "" {
+		auto, err := strconv.ParseBool(c.Query("auto_window"))
+		if err != nil {
+			return logs.Window{}, fmt.Errorf("invalid auto_window %q", c.Query("auto_window"))
+		}
+		req.AutoWindow = auto
+	}
+
+	width := time.Duration(0)
+	if req.WindowWidth != "" {
+		var err error
+		if width, err = time.ParseDuration(req.WindowWidth); err != nil || width <= 0 {
+			return logs.Window{}, fmt.Errorf("invalid window %q", req.WindowWidth)
+		}
+	}
+
+	now := time.Now()
+	var window logs.Window
+	switch {
+	case req.Around != "":
+		if req.Since != "" || req.Until != "" {
+			return logs.Window{}, fmt.Errorf("use either around or since/until")
+		}
+		t, err := logs.ParseTime(req.Around, now)
+		if err != nil {
+			return logs.Window{}, err
+		}
+		if width == 0 {
+			width = logs.DefaultBurstWidth
+		}
+		window = logs.Around(t, width)
+	default:
+		var err error
+		if req.Since != "" {
+			if window.Since, err = logs.ParseTime(req.Since, now); err != nil {
+				return logs.Window{}, err
+			}
+		}
+		if req.Until != "" {
+			if window.Until, err = logs.ParseTime(req.Until, now); err != nil {
+				return logs.Window{}, err
+			}
+		}
+	}
+
+	if req.AutoWindow {
+		if width == 0 {
+			width = logs.DefaultBurstWidth
+		}
+		text, _, _ := logs.Correlate(sources, req.skew, window)
+		if burst, ok := logs.DetectBurst(text, width, logs.DefaultBurstContext); ok {
+			window = intersect(window, burst)
+			req.window = timeWindow(burst, true)
+			return window, nil
+		}
+	}
+	if !window.IsZero() {
+		req.window = timeWindow(window, false)
+	}
+	return window, nil
+}
+
+// intersect returns the part of a burst detected within w that lies in w
+func intersect(w, burst logs.Window) logs.Window {
+	if !w.Since.IsZero() && w.Since.After(burst.Since) {
+		burst.Since = w.Since
+	}
+	if !w.Until.IsZero() && w.Until.Before(burst.Until) {
+		burst.Until = w.Until
+	}
+	return burst
+}
+
+func timeWindow(w logs.Window, auto bool) *ai.TimeWindow {
+	tw := &ai.TimeWindow{Auto: auto}
+	if !w.Since.IsZero() {
+		tw.Start = w.Since.UTC().Format(time.RFC3339)
+	}
+	if !w.Until.IsZero() {
+		tw.End = w.Until.UTC().Format(time.RFC3339)
+	}
+	return tw
+}
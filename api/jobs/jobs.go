@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	// ErrNotFound is returned when a job does not exist or belongs to another user
+	ErrNotFound = errors.New("job not found")
+	// ErrFinished is returned when cancelling a job that already completed
+	ErrFinished = errors.New("job already finished")
+)
+
+// Job is an asynchronous analysis request
+type Job struct {
+	ID           int64              `json:"id"`
+	Status       string             `json:"status"`
+	LogSizeBytes int                `json:"log_size_bytes"`
+	Profile      string             `json:"profile"`
+	Result       *ai.AnalysisResult `json:"result,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Attempts     int                `json:"attempts"`
+	CreatedAt    time.Time          `json:"created_at"`
+	StartedAt    *time.Time         `json:"started_at"`
+	FinishedAt   *time.Time         `json:"finished_at"`
+}
+
+// Finished reports whether the job has reached a terminal status
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// Enqueue stores a new job for the worker pool
+func Enqueue(ctx context.Context, db *sql.DB, userID int, logs string, opts ai.Options) (*Job, error) {
+	job := &Job{Status: StatusQueued, LogSizeBytes: len(logs), Profile: opts.Profile}
+	if job.Profile == "" {
+		job.Profile = ai.DefaultProfile
+	}
+	known, err := json.Marshal(opts.KnownIssues)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue job: %w", err)
+	}
+	sources, err := json.Marshal(opts.Sources)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue job: %w", err)
+	}
+	window, err := json.Marshal(opts.Window)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue job: %w", err)
+	}
+	lineMap, err := json.Marshal(opts.LineMap)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue job: %w", err)
+	}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO analysis_jobs (user_id, logs, log_size_bytes, profile, known_issues, sources, clock_skew_ms, time_window, line_map)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`,
+		userID, logs, len(logs), job.Profile, string(known), string(sources), opts.ClockSkew.Milliseconds(), string(window), string(lineMap),
+	).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue job: %w", err)
+	}
+	return job, nil
+}
+
+// Get returns one of the user's jobs
+func Get(ctx context.Context, db *sql.DB, id int64, userID int) (*Job, error) {
+	var (
+		job        Job
+		result     []byte
+		errText    sql.NullString
+		startedAt  sql.NullTime
+		finishedAt sql.NullTime
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT id, status, log_size_bytes, profile, result, error, attempts, created_at, started_at, finished_at
+		FROM analysis_jobs WHERE id = $1 AND user_id = $2`, id, userID,
+	).Scan(&job.ID, &job.Status, &job.LogSizeBytes, &job.Profile, &result, &errText, &job.Attempts,
+		&job.CreatedAt, &startedAt, &finishedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get job: %w", err)
+	}
+
+	if len(result) > 0 {
+		job.Result = &ai.AnalysisResult{}
+		if err := json.Unmarshal(result, job.Result); err != nil {
+			return nil, fmt.Errorf("decode job result: %w", err)
+		}
+	}
+	job.Error = errText.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// Cancel cancels a queued job immediately, or asks the worker running it to stop
+func Cancel(ctx context.Context, db *sql.DB, id int64, userID int) (*Job, error) {
+	_, err := db.ExecContext(ctx, `
+		UPDATE analysis_jobs
+		SET cancel_requested = TRUE,
+			status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+			finished_at = CASE WHEN status = 'queued' THEN NOW() ELSE finished_at END
+		WHERE id = $1 AND user_id = $2 AND status IN ('queued', 'running')`, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel job: %w", err)
+	}
+
+	job, err := Get(ctx, db, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == StatusSucceeded || job.Status == StatusFailed {
+		return job, ErrFinished
+	}
+	return job, nil
+}
@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+)
+
+// Record stores an analysis that already finished, such as one made by
+// /api/analyze, as a succeeded job so that it can be referred to by ID. A
+// cached result is stored with its cache key for FindRecorded. Unless
+// keepLogs is set only the size of the logs is stored.
+func Record(ctx context.Context, db *sql.DB, userID int, logs, cacheKey string, keepLogs bool, result *ai.AnalysisResult) (int64, error) {
+	profile := result.Profile
+	if profile == "" {
+		profile = ai.DefaultProfile
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return 0, fmt.Errorf("record analysis: %w", err)
+	}
+
+	size := len(logs)
+	if !keepLogs {
+		logs = ""
+	}
+	var key interface{}
+	if cacheKey != "" {
+		key = cacheKey
+	}
+
+	var id int64
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO analysis_jobs (user_id, status, logs, log_size_bytes, profile, result, cache_key, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING id`,
+		userID, StatusSucceeded, logs, size, profile, string(data), key,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("record analysis: %w", err)
+	}
+	return id, nil
+}
+
+// FindRecorded returns the ID of the user's latest recorded analysis with the
+// given cache key and result, or ErrNotFound
+func FindRecorded(ctx context.Context, db *sql.DB, userID int, cacheKey string, result *ai.AnalysisResult) (int64, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return 0, fmt.Errorf("find analysis: %w", err)
+	}
+	var id int64
+	err = db.QueryRowContext(ctx, `
+		SELECT id FROM analysis_jobs
+		WHERE user_id = $1 AND cache_key = $2 AND result = $3::jsonb
+		ORDER BY id DESC LIMIT 1`, userID, cacheKey, string(data),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find analysis: %w", err)
+	}
+	return id, nil
+}
+
+// Logs returns the logs analyzed by one of the user's jobs
+func Logs(ctx context.Context, db *sql.DB, id int64, userID int) (string, error) {
+	var logs string
+	err := db.QueryRowContext(ctx, `SELECT logs FROM analysis_jobs WHERE id = $1 AND user_id = $2`, id, userID).Scan(&logs)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("get job logs: %w", err)
+	}
+	return logs, nil
+}
+
+// Messages returns the follow-up conversation about a job, oldest first
+func Messages(ctx context.Context, db *sql.DB, id int64) ([]ai.Message, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT role, content FROM analysis_messages WHERE job_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("get messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []ai.Message{}
+	for rows.Next() {
+		var m ai.Message
+		if err := rows.Scan(&m.Role, &m.Content); err != nil {
+			return nil, fmt.Errorf("get messages: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// AddMessages appends messages to the conversation about a job
+func AddMessages(ctx context.Context, db *sql.DB, id int64, messages ...ai.Message) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("add messages: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, m := range messages {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO analysis_messages (job_id, role, content) VALUES ($1, $2, $3)`,
+			id, m.Role, m.Content); err != nil {
+			return fmt.Errorf("add messages: %w", err)
+		}
+	}
+	return tx.Commit()
+}
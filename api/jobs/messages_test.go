@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/AyomiCoder/loggar/internal/dbtest"
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	db, fake := dbtest.New(t)
+	fake.On("INSERT INTO analysis_jobs", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(3)}}}
+	})
+	result := &ai.AnalysisResult{Summary: "boom"}
+
+	id, err := Record(context.Background(), db, 1, "ERROR boom", "key", true, result)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+	// Without keepLogs only the size of the logs is stored
+	_, err = Record(context.Background(), db, 1, "ERROR boom", "", false, result)
+	require.NoError(t, err)
+
+	inserted := fake.Queries("INSERT INTO analysis_jobs")
+	require.Len(t, inserted, 2)
+	assert.Equal(t, []driver.Value{"ERROR boom", int64(10), "key"}, []driver.Value{inserted[0].Args[2], inserted[0].Args[3], inserted[0].Args[6]})
+	assert.Equal(t, []driver.Value{"", int64(10), nil}, []driver.Value{inserted[1].Args[2], inserted[1].Args[3], inserted[1].Args[6]})
+
+	_, err = FindRecorded(context.Background(), db, 1, "key", result)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
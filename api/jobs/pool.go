@@ -0,0 +1,324 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/AyomiCoder/loggar/api/webhooks"
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/offline"
+)
+
+// Pool runs queued analysis jobs on a fixed number of workers. A worker
+// records a heartbeat on its job every cancelPolling; running jobs without
+// one for staleAfter, e.g. of a crashed process, are returned to the queue.
+// A job that has been claimed maxAttempts times fails instead.
+type Pool struct {
+	db            *sql.DB
+	workers       int
+	pollInterval  time.Duration
+	cancelPolling time.Duration
+	staleAfter    time.Duration
+	maxAttempts   int
+	analyze       func(logs string, opts ai.Options) (*ai.AnalysisResult, error)
+}
+
+// NewPool creates a worker pool that analyzes jobs with ai.AnalyzeLogs
+func NewPool(db *sql.DB, workers int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Pool{
+		db:            db,
+		workers:       workers,
+		pollInterval:  time.Second,
+		cancelPolling: time.Second,
+		staleAfter:    time.Minute,
+		maxAttempts:   3,
+		analyze:       ai.AnalyzeLogs,
+	}
+}
+
+// Start launches the workers. They stop when ctx is cancelled, returning
+// the jobs they were running to the queue.
+func (p *Pool) Start(ctx context.Context) {
+	p.requeueStale(ctx)
+	go p.recover(ctx)
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx)
+	}
+	log.Printf("Started %d analysis job workers", p.workers)
+}
+
+// recover periodically requeues jobs whose worker stopped sending heartbeats
+func (p *Pool) recover(ctx context.Context) {
+	ticker := time.NewTicker(p.staleAfter / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.requeueStale(ctx)
+		}
+	}
+}
+
+// requeueStale returns running jobs without a heartbeat for staleAfter to the
+// queue, or fails them once they have used up their attempts
+func (p *Pool) requeueStale(ctx context.Context) {
+	cutoff := time.Now().Add(-p.staleAfter)
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, user_id, log_size_bytes FROM analysis_jobs
+		WHERE status = 'running' AND COALESCE(heartbeat_at, started_at) < $1 AND attempts >= $2`, cutoff, p.maxAttempts)
+	if err != nil {
+		log.Printf("Failed to find stale jobs: %v", err)
+		return
+	}
+	type stale struct {
+		id              int64
+		userID, logSize int
+	}
+	var exhausted []stale
+	for rows.Next() {
+		var s stale
+		if err := rows.Scan(&s.id, &s.userID, &s.logSize); err != nil {
+			log.Printf("Failed to find stale jobs: %v", err)
+			break
+		}
+		exhausted = append(exhausted, s)
+	}
+	rows.Close()
+	for _, s := range exhausted {
+		p.finish(ctx, s.id, s.userID, s.logSize, nil, p.errExhausted())
+	}
+
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE analysis_jobs SET status = 'queued', started_at = NULL, heartbeat_at = NULL
+		WHERE status = 'running' AND COALESCE(heartbeat_at, started_at) < $1 AND attempts < $2`, cutoff, p.maxAttempts)
+	if err != nil {
+		log.Printf("Failed to requeue stale jobs: %v", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Requeued %d stale analysis jobs", n)
+	}
+}
+
+// errExhausted is the error of a job that was given up on
+func (p *Pool) errExhausted() error {
+	return fmt.Errorf("analysis did not finish after %d attempts", p.maxAttempts)
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		claimed, err := p.claim(ctx)
+		if err != nil {
+			log.Printf("Failed to claim analysis job: %v", err)
+		}
+		if claimed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.pollInterval):
+		}
+	}
+}
+
+// claim picks the oldest queued job, if any, and runs it
+func (p *Pool) claim(ctx context.Context) (bool, error) {
+	var (
+		id       int64
+		userID   int
+		attempts int
+		logs     string
+		profile  string
+		known    []byte
+		sources  []byte
+		skewMS   int64
+		window   []byte
+		lineMap  []byte
+	)
+	err := p.db.QueryRowContext(ctx, `
+		UPDATE analysis_jobs
+		SET status = 'running', started_at = NOW(), heartbeat_at = NOW(), attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM analysis_jobs
+			WHERE status = 'queued'
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, attempts, logs, profile, known_issues, sources, clock_skew_ms, time_window, line_map`,
+	).Scan(&id, &userID, &attempts, &logs, &profile, &known, &sources, &skewMS, &window, &lineMap)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if attempts > p.maxAttempts {
+		p.finish(ctx, id, userID, len(logs), nil, p.errExhausted())
+		return true, nil
+	}
+
+	opts := ai.Options{Profile: profile, ClockSkew: time.Duration(skewMS) * time.Millisecond}
+	if len(known) > 0 {
+		if err := json.Unmarshal(known, &opts.KnownIssues); err != nil {
+			log.Printf("Failed to decode known issues of job %d: %v", id, err)
+		}
+	}
+	if len(sources) > 0 {
+		if err := json.Unmarshal(sources, &opts.Sources); err != nil {
+			log.Printf("Failed to decode sources of job %d: %v", id, err)
+		}
+	}
+	if len(window) > 0 {
+		if err := json.Unmarshal(window, &opts.Window); err != nil {
+			log.Printf("Failed to decode time window of job %d: %v", id, err)
+		}
+	}
+	if len(lineMap) > 0 {
+		if err := json.Unmarshal(lineMap, &opts.LineMap); err != nil {
+			log.Printf("Failed to decode line map of job %d: %v", id, err)
+		}
+	}
+
+	p.run(ctx, id, userID, logs, opts)
+	return true, nil
+}
+
+// run analyzes a claimed job, abandoning it if cancellation is requested
+// meanwhile and requeueing it if the pool stops
+func (p *Pool) run(ctx context.Context, id int64, userID int, logs string, opts ai.Options) {
+	type outcome struct {
+		result *ai.AnalysisResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := p.analyze(logs, opts)
+		if fallback, ok := offline.Fallback(logs, opts, err); ok {
+			log.Printf("AI provider unavailable for job %d, using offline analysis: %v", id, err)
+			result, err = fallback, nil
+		}
+		done <- outcome{result, err}
+	}()
+
+	ticker := time.NewTicker(p.cancelPolling)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case out := <-done:
+			p.finish(ctx, id, userID, len(logs), out.result, out.err)
+			return
+		case <-ticker.C:
+			if p.heartbeat(ctx, id) {
+				p.db.ExecContext(ctx, `
+					UPDATE analysis_jobs SET status = 'cancelled', finished_at = NOW()
+					WHERE id = $1 AND status = 'running'`, id)
+				return
+			}
+		case <-ctx.Done():
+			p.requeue(id)
+			return
+		}
+	}
+}
+
+// heartbeat records that a job is still running and reports whether its
+// cancellation was requested
+func (p *Pool) heartbeat(ctx context.Context, id int64) bool {
+	var requested bool
+	p.db.QueryRowContext(ctx, `
+		UPDATE analysis_jobs SET heartbeat_at = NOW()
+		WHERE id = $1 AND status = 'running'
+		RETURNING cancel_requested`, id).Scan(&requested)
+	return requested
+}
+
+// requeue returns a job abandoned by a stopping worker to the queue
+func (p *Pool) requeue(id int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, `
+		UPDATE analysis_jobs SET status = 'queued', started_at = NULL, heartbeat_at = NULL
+		WHERE id = $1 AND status = 'running'`, id)
+	if err != nil {
+		log.Printf("Failed to requeue job %d: %v", id, err)
+		return
+	}
+	log.Printf("Requeued job %d on shutdown", id)
+}
+
+// finish stores the outcome of a job and records usage for successful analyses
+func (p *Pool) finish(ctx context.Context, id int64, userID, logSize int, result *ai.AnalysisResult, analyzeErr error) {
+	event := audit.Event{
+		Type:    audit.TypeAnalysis,
+		ActorID: userID,
+		Target:  "job:" + strconv.FormatInt(id, 10),
+		Details: map[string]interface{}{"log_size_bytes": logSize, "async": true},
+	}
+
+	if analyzeErr != nil {
+		res, err := p.db.ExecContext(ctx, `
+			UPDATE analysis_jobs SET status = 'failed', error = $2, finished_at = NOW()
+			WHERE id = $1 AND status = 'running'`, id, analyzeErr.Error())
+		if err != nil {
+			log.Printf("Failed to store job %d failure: %v", id, err)
+		} else if n, _ := res.RowsAffected(); n == 0 {
+			// Finished or requeued meanwhile
+			return
+		}
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = analyzeErr.Error()
+		audit.Record(ctx, event)
+		p.notify(ctx, id, userID, webhooks.FailedEvent(id, analyzeErr))
+		return
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("Failed to encode job %d result: %v", id, err)
+		return
+	}
+
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE analysis_jobs SET status = 'succeeded', result = $2, finished_at = NOW()
+		WHERE id = $1 AND status = 'running' AND NOT cancel_requested`, id, string(payload))
+	if err != nil {
+		log.Printf("Failed to store job %d result: %v", id, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Cancelled while the analysis was finishing
+		p.db.ExecContext(ctx, `
+			UPDATE analysis_jobs SET status = 'cancelled', finished_at = NOW()
+			WHERE id = $1 AND status = 'running'`, id)
+		return
+	}
+
+	if _, err := p.db.ExecContext(ctx, "INSERT INTO usage_logs (user_id, log_size_bytes) VALUES ($1, $2)", userID, logSize); err != nil {
+		log.Printf("Failed to record usage for job %d: %v", id, err)
+	}
+	event.Outcome = audit.OutcomeSuccess
+	audit.Record(ctx, event)
+	p.notify(ctx, id, userID, webhooks.CompletedEvent(id, result))
+}
+
+// notify queues the user's webhooks for the outcome of a job
+func (p *Pool) notify(ctx context.Context, id int64, userID int, event webhooks.Event) {
+	if err := webhooks.Notify(ctx, p.db, userID, event); err != nil {
+		log.Printf("Failed to queue webhooks for job %d: %v", id, err)
+	}
+}
@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/internal/dbtest"
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPool returns a pool with fast timers whose claim query hands out job 7
+// once, on its first attempt
+func testPool(t *testing.T, analyze func(string, ai.Options) (*ai.AnalysisResult, error)) (*Pool, *dbtest.DB) {
+	return testPoolAttempt(t, 1, analyze)
+}
+
+// testPoolAttempt is testPool with job 7 claimed for the given attempt
+func testPoolAttempt(t *testing.T, attempt int64, analyze func(string, ai.Options) (*ai.AnalysisResult, error)) (*Pool, *dbtest.DB) {
+	db, fake := dbtest.New(t)
+	var claimed int32
+	fake.On("SET status = 'running'", func([]driver.Value) dbtest.Result {
+		if !atomic.CompareAndSwapInt32(&claimed, 0, 1) {
+			return dbtest.Result{}
+		}
+		return dbtest.Result{
+			Columns: []string{"id", "user_id", "attempts", "logs", "profile", "known_issues", "sources", "clock_skew_ms", "time_window", "line_map"},
+			Rows:    [][]driver.Value{{int64(7), int64(3), attempt, "ERROR boom", ai.DefaultProfile, nil, nil, int64(0), nil, nil}},
+		}
+	})
+	fake.On("WHERE id = $1 AND status = 'running'", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{RowsAffected: 1}
+	})
+
+	p := NewPool(db, 1)
+	p.pollInterval = 5 * time.Millisecond
+	p.cancelPolling = 5 * time.Millisecond
+	p.analyze = analyze
+	return p, fake
+}
+
+func TestPoolRunsJob(t *testing.T) {
+	p, fake := testPool(t, func(string, ai.Options) (*ai.AnalysisResult, error) {
+		return &ai.AnalysisResult{Summary: "boom"}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(fake.Queries("INSERT INTO usage_logs")) == 1
+	}, time.Second, 5*time.Millisecond)
+	stored := fake.Queries("SET status = 'succeeded'")
+	require.Len(t, stored, 1)
+	assert.Equal(t, int64(7), stored[0].Args[0])
+}
+
+func TestPoolCancelRequested(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p, fake := testPool(t, func(string, ai.Options) (*ai.AnalysisResult, error) {
+		<-release
+		return &ai.AnalysisResult{}, nil
+	})
+	fake.On("SET heartbeat_at = NOW()", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"cancel_requested"}, Rows: [][]driver.Value{{true}}}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(fake.Queries("SET status = 'cancelled'")) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, fake.Queries("SET status = 'succeeded'"))
+}
+
+func TestPoolRequeuesOnShutdown(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p, fake := testPool(t, func(string, ai.Options) (*ai.AnalysisResult, error) {
+		<-release
+		return &ai.AnalysisResult{}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+
+	// Wait for the worker to be running the job
+	require.Eventually(t, func() bool {
+		return len(fake.Queries("SET heartbeat_at = NOW()")) > 0
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+
+	require.Eventually(t, func() bool {
+		for _, q := range fake.Queries("SET status = 'queued'") {
+			if len(q.Args) == 1 && q.Args[0] == int64(7) {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestPoolRecoversStaleJobs(t *testing.T) {
+	p, fake := testPool(t, func(string, ai.Options) (*ai.AnalysisResult, error) {
+		return &ai.AnalysisResult{}, nil
+	})
+	p.staleAfter = 20 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	p.Start(ctx)
+
+	// Stale jobs are looked for at start and then periodically
+	require.Eventually(t, func() bool {
+		return len(fake.Queries("COALESCE(heartbeat_at, started_at) < $1")) >= 3
+	}, time.Second, 5*time.Millisecond)
+	stale := fake.Queries("COALESCE(heartbeat_at, started_at) < $1")
+	cutoff := stale[len(stale)-1].Args[0].(time.Time)
+	assert.True(t, cutoff.After(start.Add(-p.staleAfter)), "cutoff %v", cutoff)
+}
+
+func TestPoolGivesUpAfterMaxAttempts(t *testing.T) {
+	var analyzed int32
+	p, fake := testPoolAttempt(t, 4, func(string, ai.Options) (*ai.AnalysisResult, error) {
+		atomic.AddInt32(&analyzed, 1)
+		return &ai.AnalysisResult{}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(fake.Queries("SET status = 'failed'")) == 1
+	}, time.Second, 5*time.Millisecond)
+	failed := fake.Queries("SET status = 'failed'")
+	assert.Equal(t, []driver.Value{int64(7), "analysis did not finish after 3 attempts"}, failed[0].Args)
+	assert.Zero(t, atomic.LoadInt32(&analyzed))
+}
+
+func TestPoolFailsExhaustedStaleJobs(t *testing.T) {
+	p, fake := testPool(t, func(string, ai.Options) (*ai.AnalysisResult, error) {
+		return &ai.AnalysisResult{}, nil
+	})
+	fake.On("attempts >= $2", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"id", "user_id", "log_size_bytes"}, Rows: [][]driver.Value{{int64(9), int64(3), int64(10)}}}
+	})
+
+	p.requeueStale(context.Background())
+
+	failed := fake.Queries("SET status = 'failed'")
+	require.Len(t, failed, 1)
+	assert.Equal(t, int64(9), failed[0].Args[0])
+	// Only jobs with attempts left go back to the queue
+	requeued := fake.Queries("SET status = 'queued'")
+	require.Len(t, requeued, 1)
+	assert.Contains(t, requeued[0].SQL, "attempts < $2")
+	assert.Equal(t, int64(3), requeued[0].Args[1])
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Retention deletes finished jobs, with their logs and follow-up questions,
+// once they are older than maxAge
+type Retention struct {
+	db       *sql.DB
+	maxAge   time.Duration
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewRetention creates a retention job that runs every hour
+func NewRetention(db *sql.DB, maxAge time.Duration) *Retention {
+	return &Retention{db: db, maxAge: maxAge, interval: time.Hour, now: time.Now}
+}
+
+// Start launches the retention job. It stops when ctx is cancelled.
+func (r *Retention) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.purge(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("Deleting analyses after %s", r.maxAge)
+}
+
+// purge deletes the jobs that finished before the retention period
+func (r *Retention) purge(ctx context.Context) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM analysis_jobs
+		WHERE status IN ($1, $2, $3) AND finished_at < $4`,
+		StatusSucceeded, StatusFailed, StatusCancelled, r.now().Add(-r.maxAge))
+	if err != nil {
+		log.Printf("Failed to delete old analyses: %v", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Deleted %d analyses older than %s", n, r.maxAge)
+	}
+}
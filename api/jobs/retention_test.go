@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionPurge(t *testing.T) {
+	db, fake := dbtest.New(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := NewRetention(db, 30*24*time.Hour)
+	r.now = func() time.Time { return now }
+
+	r.purge(context.Background())
+
+	deleted := fake.Queries("DELETE FROM analysis_jobs")
+	require.Len(t, deleted, 1)
+	// Queued and running jobs are never deleted
+	assert.Equal(t, []interface{}{StatusSucceeded, StatusFailed, StatusCancelled}, []interface{}{deleted[0].Args[0], deleted[0].Args[1], deleted[0].Args[2]})
+	assert.Equal(t, time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC), deleted[0].Args[3])
+}
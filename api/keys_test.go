@@ -0,0 +1,58 @@
+package api
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/api/handlers"
+	"github.com/AyomiCoder/loggar/api/middleware"
+	"github.com/AyomiCoder/loggar/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAPIKeyWithKey(t *testing.T) {
+	db, fake := dbtest.New(t)
+	// The caller authenticates with a key that only has the keys scope
+	fake.On("FROM api_keys k", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{
+			Columns: []string{"id", "user_id", "email", "scopes", "expires_at"},
+			Rows:    [][]driver.Value{{int64(4), int64(1), "test@loggar.dev", "keys", nil}},
+		}
+	})
+	fake.On("INSERT INTO api_keys", func([]driver.Value) dbtest.Result {
+		return dbtest.Result{Columns: []string{"id", "created_at"}, Rows: [][]driver.Value{{int64(5), time.Now()}}}
+	})
+	handlers.SetDB(db)
+	middleware.SetDB(db)
+	t.Cleanup(func() {
+		handlers.SetDB(nil)
+		middleware.SetDB(nil)
+	})
+	router := NewServer()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/keys", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer lgk_keysonly")
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create(`{"name": "escalate", "scopes": ["admin"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"error": "api key cannot grant the admin scope"}`, w.Body.String())
+	assert.Empty(t, fake.Queries("INSERT INTO api_keys"))
+
+	// Without scopes the new key gets the caller's own
+	w = create(`{"name": "copy"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	inserted := fake.Queries("INSERT INTO api_keys")
+	require.Len(t, inserted, 1)
+	assert.Equal(t, "keys", inserted[0].Args[4])
+}
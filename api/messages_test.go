@@ -0,0 +1,100 @@
+package api
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/api/handlers"
+	"github.com/AyomiCoder/loggar/api/middleware"
+	"github.com/AyomiCoder/loggar/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateMessageLimits(t *testing.T) {
+	var calls int32
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "Because of the pool [L1]"}]}}]}`))
+	}))
+	defer provider.Close()
+	t.Setenv("GOOGLE_AI_KEY", "test-key")
+	t.Setenv("GOOGLE_AI_BASE_URL", provider.URL)
+
+	// setup answers the session and job queries, with the given conversation
+	// length and quota usage
+	setup := func(t *testing.T, messages int, used int64) *dbtest.DB {
+		db, fake := dbtest.New(t)
+		fake.On("FROM users u WHERE u.id = $1", func([]driver.Value) dbtest.Result {
+			return dbtest.Result{Columns: []string{"disabled", "revoked"}, Rows: [][]driver.Value{{false, false}}}
+		})
+		fake.On("monthly_analysis_quota", func([]driver.Value) dbtest.Result {
+			return dbtest.Result{Columns: []string{"quota", "used"}, Rows: [][]driver.Value{{int64(10), used}}}
+		})
+		fake.On("SELECT id, status, log_size_bytes", func([]driver.Value) dbtest.Result {
+			return dbtest.Result{
+				Columns: []string{"id", "status", "log_size_bytes", "profile", "result", "error", "attempts", "created_at", "started_at", "finished_at"},
+				Rows:    [][]driver.Value{{int64(9), "succeeded", int64(10), "default", []byte(`{"summary": "boom"}`), nil, int64(1), time.Now(), nil, nil}},
+			}
+		})
+		fake.On("SELECT logs FROM analysis_jobs", func([]driver.Value) dbtest.Result {
+			return dbtest.Result{Columns: []string{"logs"}, Rows: [][]driver.Value{{"ERROR boom"}}}
+		})
+		fake.On("FROM analysis_messages", func([]driver.Value) dbtest.Result {
+			rows := make([][]driver.Value, messages)
+			for i := range rows {
+				rows[i] = []driver.Value{"user", "why?"}
+			}
+			return dbtest.Result{Columns: []string{"role", "content"}, Rows: rows}
+		})
+		handlers.SetDB(db)
+		middleware.SetDB(db)
+		t.Cleanup(func() {
+			handlers.SetDB(nil)
+			middleware.SetDB(nil)
+		})
+		return fake
+	}
+	ask := func(message string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/analyses/9/messages", strings.NewReader(`{"message": "`+message+`"}`))
+		req.Header.Set("Authorization", "Bearer "+testJWT(t))
+		req.Header.Set("Content-Type", "application/json")
+		NewServer().ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("answered", func(t *testing.T) {
+		fake := setup(t, 2, 0)
+		w := ask("why the pool?")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, fake.Queries("INSERT INTO usage_logs"), 1)
+		assert.Len(t, fake.Queries("INSERT INTO analysis_messages"), 2)
+	})
+
+	tests := []struct {
+		name     string
+		message  string
+		messages int
+		used     int64
+		status   int
+	}{
+		{"message too long", strings.Repeat("a", 5000), 0, 0, http.StatusRequestEntityTooLarge},
+		{"too many questions", "and now?", 40, 0, http.StatusTooManyRequests},
+		{"quota used up", "why the pool?", 0, 10, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := setup(t, tt.messages, tt.used)
+			before := atomic.LoadInt32(&calls)
+			w := ask(tt.message)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Equal(t, before, atomic.LoadInt32(&calls), "provider called")
+			assert.Empty(t, fake.Queries("INSERT INTO usage_logs"))
+		})
+	}
+}
@@ -1,34 +1,48 @@
 package middleware
 
 import (
+	"database/sql"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/AyomiCoder/loggar/internal/apikey"
+	"github.com/AyomiCoder/loggar/internal/audit"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware validates JWT tokens
+var db *sql.DB
+
+// SetDB sets the database used to look up API keys
+func SetDB(database *sql.DB) {
+	db = database
+}
+
+// AuthMiddleware validates JWT tokens and API keys
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
-			c.Abort()
+			reject(c, http.StatusUnauthorized, "authorization header required")
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
-			c.Abort()
+			reject(c, http.StatusUnauthorized, "invalid authorization header format")
 			return
 		}
 
 		tokenString := parts[1]
 
+		if apikey.IsKey(tokenString) {
+			authenticateAPIKey(c, tokenString)
+			return
+		}
+
 		// Parse and validate token
 		secret := os.Getenv("JWT_SECRET")
 		if secret == "" {
@@ -43,17 +57,148 @@ func AuthMiddleware() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			reject(c, http.StatusUnauthorized, "invalid token")
 			return
 		}
 
 		// Extract claims and set in context
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("user_id", claims["user_id"])
+			if userID, ok := claims["user_id"].(float64); ok {
+				c.Set("user_id", int(userID))
+			}
 			c.Set("email", claims["email"])
 		}
+		c.Set("auth_method", "jwt")
+
+		if status, msg := checkSession(c.GetInt("user_id"), tokenString); msg != "" {
+			reject(c, status, msg)
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// authenticateAPIKey resolves an lgk_ key to its owner and records its use
+func authenticateAPIKey(c *gin.Context, key string) {
+	if db == nil {
+		reject(c, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+
+	var (
+		keyID     int
+		userID    int
+		email     string
+		scopes    string
+		expiresAt sql.NullTime
+	)
+	err := db.QueryRow(`
+		SELECT k.id, k.user_id, u.email, k.scopes, k.expires_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.disabled_at IS NULL`,
+		apikey.Hash(key)).Scan(&keyID, &userID, &email, &scopes, &expiresAt)
+	if err != nil {
+		reject(c, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+
+	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
+		reject(c, http.StatusUnauthorized, "api key expired")
+		return
+	}
+
+	db.Exec("UPDATE api_keys SET last_used_at = NOW() WHERE id = $1", keyID)
+
+	c.Set("user_id", userID)
+	c.Set("email", email)
+	c.Set("auth_method", "api_key")
+	c.Set("api_key_id", keyID)
+	c.Set("scopes", apikey.SplitScopes(scopes))
+
+	c.Next()
+}
+
+// reject aborts the request and records the rejection in the audit log
+func reject(c *gin.Context, status int, reason string) {
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(int)
+	audit.Record(c.Request.Context(), audit.Event{
+		Type:       audit.TypeAuthRejected,
+		Outcome:    audit.OutcomeDenied,
+		ActorID:    id,
+		ActorEmail: c.GetString("email"),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Target:     c.Request.Method + " " + c.FullPath(),
+		Details:    map[string]interface{}{"reason": reason, "status": status},
+	})
+
+	c.JSON(status, gin.H{"error": reason})
+	c.Abort()
+}
+
+// checkSession returns a status and a non-empty reason when a JWT belongs to
+// a disabled account or was revoked by an admin. Sessions that cannot be
+// checked are rejected too.
+func checkSession(userID int, tokenString string) (int, string) {
+	if db == nil {
+		return 0, ""
+	}
+
+	var disabled, revoked bool
+	err := db.QueryRow(`
+		SELECT u.disabled_at IS NOT NULL,
+			EXISTS(SELECT 1 FROM tokens t WHERE t.token = $2 AND t.revoked_at IS NOT NULL)
+		FROM users u WHERE u.id = $1`,
+		userID, tokenString).Scan(&disabled, &revoked)
+	if err == sql.ErrNoRows {
+		return http.StatusUnauthorized, "unknown user"
+	}
+	if err != nil {
+		return http.StatusServiceUnavailable, "could not verify session"
+	}
+	if disabled {
+		return http.StatusUnauthorized, "account disabled"
+	}
+	if revoked {
+		return http.StatusUnauthorized, "token revoked"
+	}
+	return 0, ""
+}
+
+// RequireAdmin rejects callers whose account does not have the admin role
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var role string
+		if db != nil {
+			db.QueryRow("SELECT role FROM users WHERE id = $1", c.GetInt("user_id")).Scan(&role)
+		}
+		if role != "admin" {
+			reject(c, http.StatusForbidden, "admin role required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope rejects API keys that were not granted the given scope.
+// JWT sessions carry the full permissions of the user.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != "api_key" {
+			c.Next()
+			return
+		}
+
+		for _, s := range c.GetStringSlice("scopes") {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+
+		reject(c, http.StatusForbidden, "api key is missing the "+scope+" scope")
+	}
+}
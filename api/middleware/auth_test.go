@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AyomiCoder/loggar/internal/dbtest"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1}).
+		SignedString([]byte("default-secret-change-me"))
+	require.NoError(t, err)
+
+	session := func(disabled, revoked bool) func([]driver.Value) dbtest.Result {
+		return func([]driver.Value) dbtest.Result {
+			return dbtest.Result{Columns: []string{"disabled", "revoked"}, Rows: [][]driver.Value{{disabled, revoked}}}
+		}
+	}
+	tests := []struct {
+		name   string
+		answer func([]driver.Value) dbtest.Result
+		status int
+		body   string
+	}{
+		{"active", session(false, false), http.StatusOK, `{"ok": true}`},
+		{"disabled account", session(true, false), http.StatusUnauthorized, `{"error": "account disabled"}`},
+		{"revoked token", session(false, true), http.StatusUnauthorized, `{"error": "token revoked"}`},
+		{"unknown user", nil, http.StatusUnauthorized, `{"error": "unknown user"}`},
+		{"database error", func([]driver.Value) dbtest.Result {
+			return dbtest.Result{Err: errors.New("connection refused")}
+		}, http.StatusServiceUnavailable, `{"error": "could not verify session"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := dbtest.New(t)
+			if tt.answer != nil {
+				fake.On("FROM users u WHERE u.id = $1", tt.answer)
+			}
+			SetDB(db)
+			t.Cleanup(func() { SetDB(nil) })
+
+			router := gin.New()
+			router.GET("/", AuthMiddleware(), func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.JSONEq(t, tt.body, w.Body.String())
+		})
+	}
+}
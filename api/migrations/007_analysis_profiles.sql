@@ -0,0 +1,3 @@
+-- Analysis profile (prompt template) selected for each async job.
+
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS profile TEXT NOT NULL DEFAULT 'default';
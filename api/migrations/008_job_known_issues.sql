@@ -0,0 +1,3 @@
+-- Known issues matched by detection rules when a job was submitted.
+
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS known_issues JSONB;
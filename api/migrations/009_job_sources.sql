@@ -0,0 +1,4 @@
+-- Source breakdown and clock skew of jobs that correlate several log files.
+
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS sources JSONB;
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS clock_skew_ms BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Time window the logs of a job were cut to before analysis.
+
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS time_window JSONB;
@@ -0,0 +1,12 @@
+-- Follow-up questions about an analysis and the answers to them. Synchronous
+-- analyses are stored as finished jobs so that every analysis has an ID.
+
+CREATE TABLE IF NOT EXISTS analysis_messages (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES analysis_jobs(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_messages_job_id ON analysis_messages(job_id, id);
@@ -0,0 +1,4 @@
+-- Last sign of life from the worker running a job. Running jobs without one
+-- for a while are returned to the queue.
+
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP;
@@ -0,0 +1,4 @@
+-- Where the lines of a job's logs came from, when they were cut to a time
+-- window or merged from several files.
+
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS line_map JSONB;
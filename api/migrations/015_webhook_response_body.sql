@@ -0,0 +1,4 @@
+-- Receivers' responses are no longer kept: they could reveal services that
+-- are only reachable from the server.
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body;
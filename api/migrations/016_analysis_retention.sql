@@ -0,0 +1,7 @@
+-- The cache key of a recorded analysis, so that cache hits reuse its row,
+-- and an index for deleting finished analyses after the retention period.
+
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS cache_key TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_analysis_jobs_cache_key ON analysis_jobs(user_id, cache_key) WHERE cache_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_analysis_jobs_finished_at ON analysis_jobs(finished_at);
@@ -1,11 +1,23 @@
 package api
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/AyomiCoder/loggar/api/handlers"
+	"github.com/AyomiCoder/loggar/api/jobs"
 	"github.com/AyomiCoder/loggar/api/middleware"
+	"github.com/AyomiCoder/loggar/api/webhooks"
+	"github.com/AyomiCoder/loggar/internal/apikey"
+	"github.com/AyomiCoder/loggar/internal/audit"
+	"github.com/AyomiCoder/loggar/internal/cache"
+	"github.com/AyomiCoder/loggar/pkg/ingest"
+	"github.com/AyomiCoder/loggar/pkg/watch"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
@@ -30,11 +42,55 @@ func InitDB(databaseURL string) error {
 
 	// Set the database for handlers
 	handlers.SetDB(db)
+	middleware.SetDB(db)
+	audit.SetDB(db)
+
+	analysisCache, err := newCache(db)
+	if err != nil {
+		return err
+	}
+	handlers.SetCache(analysisCache)
 
 	log.Println("Database connected successfully")
 	return nil
 }
 
+// newCache builds the analysis cache from ANALYSIS_CACHE (memory, postgres or off),
+// ANALYSIS_CACHE_TTL and ANALYSIS_CACHE_SIZE
+func newCache(db *sql.DB) (cache.Cache, error) {
+	ttl := 24 * time.Hour
+	if v := os.Getenv("ANALYSIS_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ANALYSIS_CACHE_TTL %q: %w", v, err)
+		}
+		ttl = d
+	}
+
+	size := 500
+	if v := os.Getenv("ANALYSIS_CACHE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ANALYSIS_CACHE_SIZE %q: %w", v, err)
+		}
+		size = n
+	}
+
+	switch backend := os.Getenv("ANALYSIS_CACHE"); backend {
+	case "", "memory":
+		log.Printf("Analysis cache: memory (%d entries, ttl %s)", size, ttl)
+		return cache.NewMemory(size, ttl), nil
+	case "postgres":
+		log.Printf("Analysis cache: postgres (ttl %s)", ttl)
+		return cache.NewPostgres(db, ttl), nil
+	case "off":
+		log.Println("Analysis cache: disabled")
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown ANALYSIS_CACHE backend %q", backend)
+	}
+}
+
 // NewServer creates and configures the Gin server
 func NewServer() *gin.Engine {
 	router := gin.Default()
@@ -56,16 +112,156 @@ func NewServer() *gin.Engine {
 		auth.GET("/google/callback", handlers.AuthGoogleCallbackHandler)
 	}
 
-	// Protected routes (require JWT)
+	// Protected routes (require JWT or API key)
 	apiRoutes := router.Group("/api")
 	apiRoutes.Use(middleware.AuthMiddleware())
 	{
-		apiRoutes.POST("/analyze", handlers.AnalyzeHandler)
+		apiRoutes.POST("/analyze", middleware.RequireScope(apikey.ScopeAnalyze), handlers.AnalyzeHandler)
+		apiRoutes.POST("/analyze/stream", middleware.RequireScope(apikey.ScopeAnalyze), handlers.AnalyzeStreamHandler)
+
+		// OTLP exporters append /v1/logs to the endpoint they are given
+		ingestRoutes := apiRoutes.Group("/ingest", middleware.RequireScope(apikey.ScopeIngest))
+		ingestRoutes.POST("", handlers.IngestHandler)
+		ingestRoutes.POST("/v1/logs", handlers.IngestHandler)
+
+		jobRoutes := apiRoutes.Group("/jobs", middleware.RequireScope(apikey.ScopeAnalyze))
+		jobRoutes.POST("", handlers.CreateJobHandler)
+		jobRoutes.GET("/:id", handlers.GetJobHandler)
+		jobRoutes.POST("/:id/cancel", handlers.CancelJobHandler)
+
+		analyses := apiRoutes.Group("/analyses", middleware.RequireScope(apikey.ScopeAnalyze))
+		analyses.GET("/:id/messages", handlers.ListMessagesHandler)
+		analyses.POST("/:id/messages", handlers.CreateMessageHandler)
+
+		keys := apiRoutes.Group("/keys", middleware.RequireScope(apikey.ScopeKeys))
+		keys.POST("", handlers.CreateAPIKeyHandler)
+		keys.GET("", handlers.ListAPIKeysHandler)
+		keys.DELETE("/:id", handlers.RevokeAPIKeyHandler)
+
+		hooks := apiRoutes.Group("/webhooks", middleware.RequireScope(apikey.ScopeWebhooks))
+		hooks.POST("", handlers.CreateWebhookHandler)
+		hooks.GET("", handlers.ListWebhooksHandler)
+		hooks.DELETE("/:id", handlers.DeleteWebhookHandler)
+		hooks.GET("/:id/deliveries", handlers.ListDeliveriesHandler)
+		hooks.POST("/:id/deliveries/:delivery_id/replay", handlers.ReplayDeliveryHandler)
+
+		apiRoutes.GET("/audit", middleware.RequireScope(apikey.ScopeAdmin), middleware.RequireAdmin(), handlers.AuditHandler)
+	}
+
+	// Admin routes (require the admin role)
+	admin := router.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireScope(apikey.ScopeAdmin), middleware.RequireAdmin())
+	{
+		admin.GET("/users", handlers.AdminListUsersHandler)
+		admin.GET("/users/:id", handlers.AdminGetUserHandler)
+		admin.POST("/users/:id/disable", handlers.AdminDisableUserHandler)
+		admin.POST("/users/:id/enable", handlers.AdminEnableUserHandler)
+		admin.POST("/users/:id/revoke-tokens", handlers.AdminRevokeTokensHandler)
+		admin.PUT("/users/:id/quota", handlers.AdminUpdateQuotaHandler)
+		admin.GET("/usage", handlers.AdminUsageHandler)
 	}
 
 	return router
 }
 
+// StartWorkers launches the analysis job worker pool. Requires InitDB.
+func StartWorkers(ctx context.Context, workers int) {
+	jobs.NewPool(db, workers).Start(ctx)
+	handlers.SetJobsEnabled(true)
+}
+
+// StartWebhooks launches the webhook dispatcher. Requires InitDB.
+func StartWebhooks(ctx context.Context) {
+	webhooks.NewDispatcher(db).Start(ctx)
+}
+
+// defaultRetention is how long analyses are kept when ANALYSIS_RETENTION is not set
+const defaultRetention = 30 * 24 * time.Hour
+
+// StartRetention deletes finished analyses, with their logs and follow-up
+// questions, once they are older than ANALYSIS_RETENTION (default 30 days).
+// ANALYSIS_RETENTION=off keeps them. Requires InitDB.
+func StartRetention(ctx context.Context) error {
+	if os.Getenv("ANALYSIS_RETENTION") == "off" {
+		return nil
+	}
+	retention, err := envDuration("ANALYSIS_RETENTION")
+	if err != nil {
+		return err
+	}
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	jobs.NewRetention(db, retention).Start(ctx)
+	return nil
+}
+
+// StartIngest buffers ingested logs and queues an analysis for each error
+// burst, detected with INGEST_THRESHOLD errors within INGEST_WINDOW and ended
+// by INGEST_QUIET without errors. INGEST_CONTEXT lines before a burst are
+// kept, a burst is cut at INGEST_MAX_LINES, and the same failure is analyzed
+// once per stream within INGEST_DEDUP. An account has at most
+// INGEST_MAX_STREAMS streams. Requires InitDB.
+func StartIngest(ctx context.Context) error {
+	var cfg watch.Config
+	var err error
+	if cfg.Threshold, err = envInt("INGEST_THRESHOLD"); err != nil {
+		return err
+	}
+	if cfg.Window, err = envDuration("INGEST_WINDOW"); err != nil {
+		return err
+	}
+	if cfg.Quiet, err = envDuration("INGEST_QUIET"); err != nil {
+		return err
+	}
+	if cfg.Context, err = envInt("INGEST_CONTEXT"); err != nil {
+		return err
+	}
+	if cfg.MaxLines, err = envInt("INGEST_MAX_LINES"); err != nil {
+		return err
+	}
+	if cfg.DedupFor, err = envDuration("INGEST_DEDUP"); err != nil {
+		return err
+	}
+
+	maxStreams, err := envInt("INGEST_MAX_STREAMS")
+	if err != nil {
+		return err
+	}
+
+	buffer := ingest.NewBuffer(cfg, maxStreams, handlers.AnalyzeIngested)
+	buffer.Start(ctx)
+	handlers.SetIngestBuffer(buffer)
+	log.Println("Started log ingestion")
+	return nil
+}
+
+// envInt reads an integer setting, 0 when unset
+func envInt(name string) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+	return n, nil
+}
+
+// envDuration reads a duration setting, 0 when unset
+func envDuration(name string) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+	return d, nil
+}
+
 // Run starts the server on the specified port
 func Run(port string) error {
 	router := NewServer()
@@ -0,0 +1,267 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Loggar-Event"
+	HeaderDelivery  = "X-Loggar-Delivery"
+	HeaderTimestamp = "X-Loggar-Timestamp"
+	HeaderSignature = "X-Loggar-Signature"
+)
+
+// errBlockedAddress is returned when a receiver resolves to an address on the
+// server's own network
+var errBlockedAddress = errors.New("receiver address is not public")
+
+// Sign returns the signature of a delivery: "sha256=" and the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret.
+// Including the timestamp lets receivers reject replayed requests.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a received delivery, rejecting
+// timestamps more than tolerance away from now
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid timestamp")
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return errors.New("timestamp outside the tolerance")
+	}
+	if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(Sign(secret, timestamp, body))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// Dispatcher sends pending deliveries, retrying failures with backoff
+type Dispatcher struct {
+	db           *sql.DB
+	client       *http.Client
+	pollInterval time.Duration
+	maxAttempts  int
+	lease        time.Duration
+}
+
+// NewDispatcher creates a dispatcher that gives up on a delivery after 6 attempts
+func NewDispatcher(db *sql.DB) *Dispatcher {
+	return &Dispatcher{
+		db:           db,
+		client:       newClient(publicIP),
+		pollInterval: time.Second,
+		maxAttempts:  6,
+		lease:        5 * time.Minute,
+	}
+}
+
+// publicIP reports whether deliveries may be sent to ip: loopback, private,
+// link-local, multicast and unspecified addresses are refused
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// newClient returns the client deliveries are sent with. It only connects to
+// addresses allow accepts, checked on the resolved IP so that DNS rebinding
+// cannot get around it, and does not follow redirects.
+func newClient(allow func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Start launches the dispatcher. It stops when ctx is cancelled.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go d.work(ctx)
+	log.Println("Started webhook dispatcher")
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		claimed, err := d.claim(ctx)
+		if err != nil {
+			log.Printf("Failed to claim webhook delivery: %v", err)
+		}
+		if claimed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.pollInterval):
+		}
+	}
+}
+
+// hook is what the dispatcher needs to know about a webhook
+type hook struct {
+	url    string
+	format string
+	secret string
+}
+
+// outcome is the result of one delivery attempt
+type outcome struct {
+	status int
+	err    error
+	retry  bool
+}
+
+// claim picks the pending delivery that is due first, if any, and attempts
+// it. The delivery is leased so that a crash mid-attempt only delays it.
+func (d *Dispatcher) claim(ctx context.Context) (bool, error) {
+	var (
+		id       int64
+		event    string
+		payload  []byte
+		attempts int
+		h        hook
+	)
+	err := d.db.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = $1
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id = (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.format, w.secret`,
+		time.Now().Add(d.lease),
+	).Scan(&id, &event, &payload, &attempts, &h.url, &h.format, &h.secret)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	out := d.send(ctx, h, id, payload)
+	d.record(ctx, id, attempts, out)
+	return true, nil
+}
+
+// send makes one attempt to deliver an event payload. Network errors, 429
+// and 5xx responses are retried; other responses, including redirects, and
+// receivers on non-public addresses are final.
+func (d *Dispatcher) send(ctx context.Context, h hook, deliveryID int64, payload []byte) outcome {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return outcome{err: fmt.Errorf("invalid payload: %w", err)}
+	}
+	body, err := Body(h.format, event)
+	if err != nil {
+		return outcome{err: fmt.Errorf("invalid payload: %w", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return outcome{err: err}
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "loggar-webhooks/1.0")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(h.secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if errors.Is(err, errBlockedAddress) {
+		return outcome{err: errBlockedAddress}
+	}
+	if err != nil {
+		return outcome{err: fmt.Errorf("network error: %w", err), retry: true}
+	}
+	resp.Body.Close()
+
+	out := outcome{status: resp.StatusCode}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		out.err = fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+		out.retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	}
+	return out
+}
+
+// record stores the outcome of an attempt and schedules the next one when
+// the delivery should be retried
+func (d *Dispatcher) record(ctx context.Context, id int64, attempts int, out outcome) {
+	status, next := StatusSucceeded, time.Now()
+	var errText interface{}
+	if out.err != nil {
+		errText = out.err.Error()
+		status = StatusFailed
+		if out.retry && attempts < d.maxAttempts {
+			status, next = StatusPending, time.Now().Add(retryDelay(attempts))
+		}
+	}
+	var responseStatus interface{}
+	if out.status != 0 {
+		responseStatus = out.status
+	}
+
+	_, err := d.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, response_status = $4, error = $5,
+			delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() ELSE delivered_at END
+		WHERE id = $1`, id, status, next, responseStatus, errText)
+	if err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", id, err)
+	}
+}
+
+// retryDelays is the backoff between attempts: 30s, 2m, 10m, 30m, then hourly
+var retryDelays = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute, 30 * time.Minute, time.Hour}
+
+// retryDelay returns the wait after the given failed attempt
+func retryDelay(attempt int) time.Duration {
+	if attempt > len(retryDelays) {
+		attempt = len(retryDelays)
+	}
+	return retryDelays[attempt-1]
+}
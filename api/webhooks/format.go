@@ -0,0 +1,158 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Body renders an event in a webhook's format
+func Body(format string, event Event) ([]byte, error) {
+	switch format {
+	case FormatSlack:
+		return json.Marshal(slackMessage(event))
+	case FormatTeams:
+		return json.Marshal(teamsMessage(event))
+	default:
+		return json.Marshal(event)
+	}
+}
+
+// severityIcons match the terminal output
+var severityIcons = map[string]string{"critical": "🔴", "high": "🟠", "medium": "🟡", "low": "🟢"}
+
+// headline is the one-line title of a chat message
+func headline(event Event) string {
+	if event.Type == EventAnalysisFailed {
+		return "❌ Analysis failed"
+	}
+	title := "Log analysis"
+	if event.Result != nil && event.Result.PrimaryIssue != "" {
+		title = event.Result.PrimaryIssue
+	}
+	if event.Severity == "" {
+		return "🔵 " + title
+	}
+	icon, ok := severityIcons[event.Severity]
+	if !ok {
+		icon = "🔵"
+	}
+	return fmt.Sprintf("%s %s: %s", icon, strings.ToUpper(event.Severity), title)
+}
+
+// details lists the likely causes and recommended actions of an analysis
+func details(event Event) (causes, actions []string) {
+	if event.Result == nil {
+		return nil, nil
+	}
+	for _, c := range event.Result.Causes {
+		causes = append(causes, fmt.Sprintf("%d%% %s", int(c.Confidence*100+0.5), c.Cause))
+	}
+	for _, a := range event.Result.Actions {
+		actions = append(actions, a.Description)
+	}
+	return causes, actions
+}
+
+// footer names the analysis the message is about
+func footer(event Event) string {
+	if event.AnalysisID == 0 {
+		return "loggar"
+	}
+	return fmt.Sprintf("loggar · analysis #%d", event.AnalysisID)
+}
+
+// truncate shortens text to at most max runes
+func truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max-1]) + "…"
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as markup
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackMessage builds a Block Kit message for a Slack incoming webhook
+func slackMessage(event Event) map[string]interface{} {
+	title := headline(event)
+	blocks := []map[string]interface{}{
+		{"type": "header", "text": map[string]string{"type": "plain_text", "text": truncate(title, 150)}},
+	}
+	section := func(text string) {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": truncate(text, 3000)},
+		})
+	}
+
+	if event.Summary != "" {
+		section(slackEscape.Replace(event.Summary))
+	}
+	if event.Error != "" {
+		section("```" + slackEscape.Replace(event.Error) + "```")
+	}
+	causes, actions := details(event)
+	if len(causes) > 0 {
+		section("*Likely causes*\n• " + slackEscape.Replace(strings.Join(causes, "\n• ")))
+	}
+	if len(actions) > 0 {
+		section("*Recommended actions*\n• " + slackEscape.Replace(strings.Join(actions, "\n• ")))
+	}
+	blocks = append(blocks, map[string]interface{}{
+		"type":     "context",
+		"elements": []map[string]string{{"type": "mrkdwn", "text": footer(event)}},
+	})
+
+	return map[string]interface{}{"text": title, "blocks": blocks}
+}
+
+// teamsMessage builds an Adaptive Card message, which Microsoft Teams
+// incoming webhooks and Workflows accept
+func teamsMessage(event Event) map[string]interface{} {
+	text := func(text string, extra map[string]interface{}) map[string]interface{} {
+		block := map[string]interface{}{"type": "TextBlock", "text": text, "wrap": true}
+		for k, v := range extra {
+			block[k] = v
+		}
+		return block
+	}
+
+	titleColor := "Default"
+	if event.Type == EventAnalysisFailed || event.Severity == "critical" || event.Severity == "high" {
+		titleColor = "Attention"
+	} else if event.Severity == "medium" {
+		titleColor = "Warning"
+	}
+	body := []map[string]interface{}{
+		text(headline(event), map[string]interface{}{"size": "Medium", "weight": "Bolder", "color": titleColor}),
+	}
+	if event.Summary != "" {
+		body = append(body, text(event.Summary, nil))
+	}
+	if event.Error != "" {
+		body = append(body, text(event.Error, map[string]interface{}{"fontType": "Monospace"}))
+	}
+	causes, actions := details(event)
+	if len(causes) > 0 {
+		body = append(body, text("**Likely causes**\n\n- "+strings.Join(causes, "\n- "), nil))
+	}
+	if len(actions) > 0 {
+		body = append(body, text("**Recommended actions**\n\n- "+strings.Join(actions, "\n- "), nil))
+	}
+	body = append(body, text(footer(event), map[string]interface{}{"isSubtle": true, "size": "Small"}))
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
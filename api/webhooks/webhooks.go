@@ -0,0 +1,325 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+)
+
+// Event types a webhook can subscribe to
+const (
+	EventAnalysisCompleted = "analysis.completed"
+	EventAnalysisFailed    = "analysis.failed"
+)
+
+// AllEvents lists every event type
+var AllEvents = []string{EventAnalysisCompleted, EventAnalysisFailed}
+
+// Payload formats: the signed loggar event, or a message for Slack or
+// Microsoft Teams incoming webhooks
+const (
+	FormatJSON  = "json"
+	FormatSlack = "slack"
+	FormatTeams = "teams"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// SecretPrefix marks a webhook signing secret
+const SecretPrefix = "whsec_"
+
+// ErrNotFound is returned when a webhook or delivery does not exist or belongs to another user
+var ErrNotFound = errors.New("webhook not found")
+
+// Webhook is an endpoint notified of the owner's analyses. The Secret is only
+// set on creation.
+type Webhook struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Format      string    `json:"format"`
+	Events      []string  `json:"events"`
+	MinSeverity string    `json:"min_severity,omitempty"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Event is the payload of a delivery in the json format
+type Event struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	CreatedAt  time.Time          `json:"created_at"`
+	AnalysisID int64              `json:"analysis_id,omitempty"`
+	Severity   string             `json:"severity,omitempty"`
+	Summary    string             `json:"summary,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Result     *ai.AnalysisResult `json:"result,omitempty"`
+}
+
+// CompletedEvent describes a finished analysis
+func CompletedEvent(analysisID int64, result *ai.AnalysisResult) Event {
+	return Event{Type: EventAnalysisCompleted, AnalysisID: analysisID, Severity: result.Severity, Summary: result.Summary, Result: result}
+}
+
+// FailedEvent describes an analysis job that failed
+func FailedEvent(analysisID int64, err error) Event {
+	return Event{Type: EventAnalysisFailed, AnalysisID: analysisID, Error: err.Error()}
+}
+
+// Delivery is one attempt to notify a webhook of an event, with its retries
+type Delivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	ReplayOf       *int64     `json:"replay_of,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// Validate checks and normalises a webhook before it is stored: an http(s)
+// URL that does not name a local or private address, a known format (json by default), known events (all by default) and
+// a known minimum severity
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	// Host names are checked again when delivering, once resolved
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); (ip != nil && !publicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("url must not point to a local or private address")
+	}
+
+	w.Format = strings.ToLower(w.Format)
+	switch w.Format {
+	case "":
+		w.Format = FormatJSON
+	case FormatJSON, FormatSlack, FormatTeams:
+	default:
+		return fmt.Errorf("unknown format %q (available: %s, %s, %s)", w.Format, FormatJSON, FormatSlack, FormatTeams)
+	}
+
+	if len(w.Events) == 0 {
+		w.Events = AllEvents
+	}
+	for _, e := range w.Events {
+		if !contains(AllEvents, e) {
+			return fmt.Errorf("unknown event %q (available: %s)", e, strings.Join(AllEvents, ", "))
+		}
+	}
+
+	w.MinSeverity = strings.ToLower(w.MinSeverity)
+	if w.MinSeverity != "" && !contains(ai.Severities, w.MinSeverity) {
+		return fmt.Errorf("unknown severity %q (available: %s)", w.MinSeverity, strings.Join(ai.Severities, ", "))
+	}
+	return nil
+}
+
+// Matches reports whether the webhook subscribes to an event. The minimum
+// severity only filters completed analyses; results without a severity
+// never reach it.
+func (w *Webhook) Matches(event Event) bool {
+	if !contains(w.Events, event.Type) {
+		return false
+	}
+	if w.MinSeverity == "" || event.Type != EventAnalysisCompleted {
+		return true
+	}
+	return ai.SeverityRank(strings.ToLower(event.Severity)) <= ai.SeverityRank(w.MinSeverity)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateSecret returns a new random signing secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create stores a validated webhook for the user and generates its secret
+func Create(ctx context.Context, db *sql.DB, userID int, w *Webhook) error {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+	w.Secret = secret
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (user_id, url, format, events, min_severity, secret)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		userID, w.URL, w.Format, strings.Join(w.Events, ","), w.MinSeverity, w.Secret,
+	).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+	return nil
+}
+
+// List returns the user's webhooks without their secrets
+func List(ctx context.Context, db *sql.DB, userID int) ([]Webhook, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, url, format, events, min_severity, created_at
+		FROM webhooks WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		var w Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.URL, &w.Format, &events, &w.MinSeverity, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list webhooks: %w", err)
+		}
+		w.Events = strings.Split(events, ",")
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+// Delete removes one of the user's webhooks and its delivery log
+func Delete(ctx context.Context, db *sql.DB, id int64, userID int) error {
+	res, err := db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Notify queues a delivery of the event to each of the user's webhooks that
+// subscribe to it. The Dispatcher sends them.
+func Notify(ctx context.Context, db *sql.DB, userID int, event Event) error {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	hooks, err := List(ctx, db, userID)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	for _, w := range hooks {
+		if !w.Matches(event) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("notify webhooks: %w", err)
+			}
+		}
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (webhook_id, event, payload) VALUES ($1, $2, $3)`,
+			w.ID, event.Type, string(payload)); err != nil {
+			return fmt.Errorf("notify webhooks: %w", err)
+		}
+	}
+	return nil
+}
+
+// newEventID returns a random event identifier, which receivers can use to
+// discard duplicates
+func newEventID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "evt_" + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Deliveries returns the most recent deliveries of one of the user's webhooks
+func Deliveries(ctx context.Context, db *sql.DB, webhookID int64, userID int, limit int) ([]Delivery, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT d.id, d.webhook_id, d.event, d.status, d.attempts, d.response_status,
+			d.error, d.replay_of, d.created_at, d.next_attempt_at, d.delivered_at
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = $1 AND w.user_id = $2
+		ORDER BY d.id DESC LIMIT $3`, webhookID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var (
+			d              Delivery
+			responseStatus sql.NullInt64
+			errText        sql.NullString
+			replayOf       sql.NullInt64
+			nextAttemptAt  sql.NullTime
+			deliveredAt    sql.NullTime
+		)
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Status, &d.Attempts, &responseStatus,
+			&errText, &replayOf, &d.CreatedAt, &nextAttemptAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("list deliveries: %w", err)
+		}
+		d.ResponseStatus = int(responseStatus.Int64)
+		d.Error = errText.String
+		if replayOf.Valid {
+			d.ReplayOf = &replayOf.Int64
+		}
+		if nextAttemptAt.Valid && d.Status == StatusPending {
+			d.NextAttemptAt = &nextAttemptAt.Time
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Replay queues a new delivery of the same payload as an earlier one, for
+// instance after fixing a receiver that was down. The original is kept in
+// the log.
+func Replay(ctx context.Context, db *sql.DB, webhookID, deliveryID int64, userID int) (*Delivery, error) {
+	d := Delivery{WebhookID: webhookID, Status: StatusPending, ReplayOf: &deliveryID}
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, replay_of)
+		SELECT d.webhook_id, d.event, d.payload, d.id
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1 AND d.webhook_id = $2 AND w.user_id = $3
+		RETURNING id, event, created_at, next_attempt_at`, deliveryID, webhookID, userID,
+	).Scan(&d.ID, &d.Event, &d.CreatedAt, &d.NextAttemptAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replay delivery: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,222 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent() Event {
+	return Event{
+		ID:         "evt_test",
+		Type:       EventAnalysisCompleted,
+		AnalysisID: 42,
+		Severity:   "high",
+		Summary:    "Connection pool exhausted <db>",
+		Result: &ai.AnalysisResult{
+			PrimaryIssue: "Database connection pool exhausted",
+			Causes:       []ai.Cause{{Cause: "Pool too small", Confidence: 0.8}},
+			Actions:      []ai.Action{{Description: "Raise max_connections"}},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	w := Webhook{URL: "https://example.com/hook", Format: "Slack", MinSeverity: "HIGH"}
+	require.NoError(t, w.Validate())
+	assert.Equal(t, FormatSlack, w.Format)
+	assert.Equal(t, "high", w.MinSeverity)
+	assert.Equal(t, AllEvents, w.Events)
+
+	w = Webhook{URL: "https://example.com/hook"}
+	require.NoError(t, w.Validate())
+	assert.Equal(t, FormatJSON, w.Format)
+
+	for _, bad := range []Webhook{
+		{URL: "ftp://example.com/hook"},
+		{URL: "example.com/hook"},
+		{URL: "https://example.com", Format: "discord"},
+		{URL: "https://example.com", Events: []string{"analysis.started"}},
+		{URL: "https://example.com", MinSeverity: "urgent"},
+		{URL: "http://127.0.0.1:8080/hook"},
+		{URL: "http://169.254.169.254/latest/meta-data"},
+		{URL: "http://10.0.0.5/hook"},
+		{URL: "http://[::1]/hook"},
+		{URL: "http://0.0.0.0/hook"},
+		{URL: "http://localhost/hook"},
+	} {
+		assert.Error(t, bad.Validate(), "%+v", bad)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	w := Webhook{Events: AllEvents, MinSeverity: "high"}
+	assert.True(t, w.Matches(Event{Type: EventAnalysisCompleted, Severity: "critical"}))
+	assert.True(t, w.Matches(Event{Type: EventAnalysisCompleted, Severity: "High"}))
+	assert.False(t, w.Matches(Event{Type: EventAnalysisCompleted, Severity: "medium"}))
+	assert.False(t, w.Matches(Event{Type: EventAnalysisCompleted}))
+	assert.True(t, w.Matches(Event{Type: EventAnalysisFailed}))
+
+	w = Webhook{Events: []string{EventAnalysisFailed}}
+	assert.False(t, w.Matches(Event{Type: EventAnalysisCompleted, Severity: "critical"}))
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"type":"analysis.completed"}`)
+	now := time.Now().Unix()
+	header := http.Header{}
+	header.Set(HeaderTimestamp, strconv.FormatInt(now, 10))
+	header.Set(HeaderSignature, Sign("whsec_secret", now, body))
+
+	assert.NoError(t, Verify("whsec_secret", header, body, 5*time.Minute))
+	assert.Error(t, Verify("whsec_other", header, body, 5*time.Minute))
+	assert.Error(t, Verify("whsec_secret", header, []byte(`{}`), 5*time.Minute))
+
+	old := now - 3600
+	header.Set(HeaderTimestamp, strconv.FormatInt(old, 10))
+	header.Set(HeaderSignature, Sign("whsec_secret", old, body))
+	assert.Error(t, Verify("whsec_secret", header, body, 5*time.Minute))
+}
+
+func TestSend(t *testing.T) {
+	status := http.StatusOK
+	var received http.Header
+	var receivedBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	// The test server listens on loopback, which deliveries normally refuse
+	d := NewDispatcher(nil)
+	d.client = newClient(func(net.IP) bool { return true })
+	payload, err := json.Marshal(testEvent())
+	require.NoError(t, err)
+	h := hook{url: srv.URL, format: FormatJSON, secret: "whsec_secret"}
+
+	out := d.send(context.Background(), h, 7, payload)
+	require.NoError(t, out.err)
+	assert.Equal(t, http.StatusOK, out.status)
+	assert.Equal(t, EventAnalysisCompleted, received.Get(HeaderEvent))
+	assert.Equal(t, "7", received.Get(HeaderDelivery))
+	assert.NoError(t, Verify("whsec_secret", received, receivedBody, time.Minute))
+	assert.JSONEq(t, string(payload), string(receivedBody))
+
+	status = http.StatusServiceUnavailable
+	out = d.send(context.Background(), h, 7, payload)
+	assert.Error(t, out.err)
+	assert.True(t, out.retry)
+
+	status = http.StatusTooManyRequests
+	assert.True(t, d.send(context.Background(), h, 7, payload).retry)
+
+	status = http.StatusBadRequest
+	out = d.send(context.Background(), h, 7, payload)
+	assert.Error(t, out.err)
+	assert.False(t, out.retry)
+
+	srv.Close()
+	out = d.send(context.Background(), h, 7, payload)
+	assert.Error(t, out.err)
+	assert.True(t, out.retry)
+}
+
+func TestSendRefusesInternalReceivers(t *testing.T) {
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer redirect.Close()
+	payload, err := json.Marshal(testEvent())
+	require.NoError(t, err)
+	h := hook{url: redirect.URL, format: FormatJSON, secret: "whsec_secret"}
+
+	// The resolved loopback address is refused, and not retried
+	out := NewDispatcher(nil).send(context.Background(), h, 7, payload)
+	assert.ErrorIs(t, out.err, errBlockedAddress)
+	assert.False(t, out.retry)
+	assert.Zero(t, out.status)
+
+	// Redirects are not followed
+	d := NewDispatcher(nil)
+	d.client = newClient(func(net.IP) bool { return true })
+	out = d.send(context.Background(), h, 7, payload)
+	assert.Equal(t, http.StatusFound, out.status)
+	assert.Error(t, out.err)
+	assert.False(t, out.retry)
+}
+
+func TestSlackBody(t *testing.T) {
+	body, err := Body(FormatSlack, testEvent())
+	require.NoError(t, err)
+
+	var msg struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+			Elements []struct {
+				Text string `json:"text"`
+			} `json:"elements"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(body, &msg))
+	assert.Equal(t, "🟠 HIGH: Database connection pool exhausted", msg.Text)
+	require.Len(t, msg.Blocks, 5)
+	assert.Equal(t, "header", msg.Blocks[0].Type)
+	assert.Equal(t, "Connection pool exhausted &lt;db&gt;", msg.Blocks[1].Text.Text)
+	assert.Equal(t, "*Likely causes*\n• 80% Pool too small", msg.Blocks[2].Text.Text)
+	assert.Equal(t, "*Recommended actions*\n• Raise max_connections", msg.Blocks[3].Text.Text)
+	assert.Equal(t, "loggar · analysis #42", msg.Blocks[4].Elements[0].Text)
+}
+
+func TestTeamsBody(t *testing.T) {
+	body, err := Body(FormatTeams, Event{Type: EventAnalysisFailed, AnalysisID: 3, Error: "AI provider unavailable"})
+	require.NoError(t, err)
+
+	var msg struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type string `json:"type"`
+				Body []struct {
+					Text  string `json:"text"`
+					Color string `json:"color"`
+				} `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	require.NoError(t, json.Unmarshal(body, &msg))
+	assert.Equal(t, "message", msg.Type)
+	require.Len(t, msg.Attachments, 1)
+	card := msg.Attachments[0]
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", card.ContentType)
+	assert.Equal(t, "AdaptiveCard", card.Content.Type)
+	require.Len(t, card.Content.Body, 3)
+	assert.Equal(t, "❌ Analysis failed", card.Content.Body[0].Text)
+	assert.Equal(t, "Attention", card.Content.Body[0].Color)
+	assert.Equal(t, "AI provider unavailable", card.Content.Body[1].Text)
+	assert.Equal(t, "loggar · analysis #3", card.Content.Body[2].Text)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryDelay(1))
+	assert.Equal(t, 2*time.Minute, retryDelay(2))
+	assert.Equal(t, time.Hour, retryDelay(5))
+	assert.Equal(t, time.Hour, retryDelay(9))
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/AyomiCoder/loggar/api"
+	"github.com/AyomiCoder/loggar/api/handlers"
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/rules"
 	"github.com/joho/godotenv"
 )
 
@@ -25,11 +30,54 @@ func main() {
 		log.Fatal("DATABASE_URL environment variable is required")
 	}
 
+	// Load custom prompt templates
+	if dir := os.Getenv("PROMPTS_DIR"); dir != "" {
+		if err := ai.LoadPromptDir(dir); err != nil {
+			log.Fatalf("Failed to load prompts from %s: %v", dir, err)
+		}
+	}
+
+	// Load detection rules matched before every analysis
+	if dir := os.Getenv("RULES_DIR"); dir != "" {
+		loaded, err := rules.LoadDir(dir)
+		if err != nil {
+			log.Fatalf("Failed to load rules from %s: %v", dir, err)
+		}
+		handlers.SetRules(loaded)
+		log.Printf("Loaded %d detection rules from %s", len(loaded), dir)
+	}
+
 	// Initialize database
 	if err := api.InitDB(databaseURL); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Start analysis job workers
+	workers := 2
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("Invalid JOB_WORKERS value %q: %v", v, err)
+		}
+		workers = n
+	}
+	if workers > 0 {
+		api.StartWorkers(context.Background(), workers)
+	}
+
+	// Delete old analyses
+	if err := api.StartRetention(context.Background()); err != nil {
+		log.Fatalf("Failed to start analysis retention: %v", err)
+	}
+
+	// Start webhook deliveries
+	api.StartWebhooks(context.Background())
+
+	// Start buffering ingested logs
+	if err := api.StartIngest(context.Background()); err != nil {
+		log.Fatalf("Failed to start log ingestion: %v", err)
+	}
+
 	// Start server
 	log.Printf("Starting server on port %s...", port)
 	if err := api.Run(port); err != nil {
@@ -0,0 +1,98 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks a bearer token as a Loggar API key rather than a JWT
+const Prefix = "lgk_"
+
+// Scopes an API key can be granted
+const (
+	ScopeAnalyze  = "analyze"
+	ScopeIngest   = "ingest"
+	ScopeKeys     = "keys"
+	ScopeWebhooks = "webhooks"
+	ScopeAdmin    = "admin"
+)
+
+// AllScopes lists every scope accepted when creating a key
+var AllScopes = []string{ScopeAnalyze, ScopeIngest, ScopeKeys, ScopeWebhooks, ScopeAdmin}
+
+// DefaultScopes are granted when a key is created without explicit scopes
+var DefaultScopes = []string{ScopeAnalyze}
+
+// Generate returns a new random API key in plaintext. It is only ever shown once.
+func Generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsKey reports whether a bearer token looks like an API key
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// Hash returns the hex encoded SHA-256 of the key, which is what gets stored
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix returns the leading characters of a key, safe to show in listings
+func DisplayPrefix(key string) string {
+	if len(key) <= len(Prefix)+6 {
+		return key
+	}
+	return key[:len(Prefix)+6]
+}
+
+// ParseScopes validates requested scopes, falling back to DefaultScopes when empty
+func ParseScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return DefaultScopes, nil
+	}
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, s := range requested {
+		s = strings.TrimSpace(strings.ToLower(s))
+		if !isKnownScope(s) {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
+
+// JoinScopes encodes scopes for storage
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, ",")
+}
+
+// SplitScopes decodes scopes from storage
+func SplitScopes(stored string) []string {
+	if stored == "" {
+		return []string{}
+	}
+	return strings.Split(stored, ",")
+}
+
+func isKnownScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
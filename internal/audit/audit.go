@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// Event types recorded by the server
+const (
+	TypeLogin          = "auth.login"
+	TypeTokenIssued    = "auth.token_issued"
+	TypeAuthRejected   = "auth.rejected"
+	TypeAPIKeyCreated  = "apikey.created"
+	TypeAPIKeyRevoked  = "apikey.revoked"
+	TypeWebhookCreated = "webhook.created"
+	TypeWebhookDeleted = "webhook.deleted"
+	TypeAnalysis       = "analysis"
+	TypeAdminAction    = "admin.action"
+	TypeAuditExport    = "audit.export"
+)
+
+// Outcomes of an event
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Event is a single security-relevant event
+type Event struct {
+	ID         int64                  `json:"id"`
+	Type       string                 `json:"type"`
+	Outcome    string                 `json:"outcome"`
+	ActorID    int                    `json:"actor_id,omitempty"`
+	ActorEmail string                 `json:"actor_email,omitempty"`
+	IP         string                 `json:"ip,omitempty"`
+	UserAgent  string                 `json:"user_agent,omitempty"`
+	Target     string                 `json:"target,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// Filter narrows down the events returned by Query. Zero values are ignored.
+type Filter struct {
+	Type    string
+	Outcome string
+	ActorID int
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+var db *sql.DB
+
+// SetDB sets the database events are written to
+func SetDB(database *sql.DB) {
+	db = database
+}
+
+// Record appends an event to the audit log. Failures are logged and returned,
+// but callers are not expected to fail the request because of them.
+func Record(ctx context.Context, event Event) error {
+	if db == nil {
+		return nil
+	}
+
+	var details interface{}
+	if len(event.Details) > 0 {
+		payload, err := json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("marshal audit details: %w", err)
+		}
+		details = string(payload)
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO audit_events (event_type, outcome, actor_id, actor_email, ip, user_agent, target, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		event.Type, event.Outcome, nullInt(event.ActorID), nullString(event.ActorEmail),
+		nullString(event.IP), nullString(event.UserAgent), nullString(event.Target), details)
+	if err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Type, err)
+		return fmt.Errorf("record audit event: %w", err)
+	}
+	return nil
+}
+
+// Query returns events matching the filter, newest first
+func Query(ctx context.Context, f Filter) ([]Event, error) {
+	if db == nil {
+		return nil, fmt.Errorf("audit log not configured")
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 10000 {
+		limit = 100
+	}
+
+	var since, until interface{}
+	if !f.Since.IsZero() {
+		since = f.Since
+	}
+	if !f.Until.IsZero() {
+		until = f.Until
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, event_type, outcome, COALESCE(actor_id, 0), COALESCE(actor_email, ''),
+			COALESCE(ip, ''), COALESCE(user_agent, ''), COALESCE(target, ''), details, created_at
+		FROM audit_events
+		WHERE ($1 = '' OR event_type = $1)
+			AND ($2 = '' OR outcome = $2)
+			AND ($3 = 0 OR actor_id = $3)
+			AND ($4::timestamp IS NULL OR created_at >= $4)
+			AND ($5::timestamp IS NULL OR created_at < $5)
+		ORDER BY id DESC
+		LIMIT $6`,
+		f.Type, f.Outcome, f.ActorID, since, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var (
+			e       Event
+			details []byte
+		)
+		if err := rows.Scan(&e.ID, &e.Type, &e.Outcome, &e.ActorID, &e.ActorEmail,
+			&e.IP, &e.UserAgent, &e.Target, &details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit event: %w", err)
+		}
+		if len(details) > 0 {
+			json.Unmarshal(details, &e.Details)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// WriteJSONLines writes one JSON object per line, for export into log pipelines
+func WriteJSONLines(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nullInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+func nullString(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
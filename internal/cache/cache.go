@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"container/list"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Cache stores encoded analysis results by key
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// Memory is an in-process LRU cache with a fixed number of entries and a TTL
+type Memory struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemory creates an LRU cache holding at most size entries for ttl each
+func NewMemory(size int, ttl time.Duration) *Memory {
+	if size < 1 {
+		size = 1
+	}
+	return &Memory{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get returns a cached value if present and not expired
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if m.now().After(entry.expiresAt) {
+		m.order.Remove(el)
+		delete(m.entries, key)
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// Set stores a value, evicting the least recently used entry when full
+func (m *Memory) Set(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := m.now().Add(m.ttl)
+	if el, ok := m.entries[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		m.order.MoveToFront(el)
+		return
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Postgres stores entries in the analysis_cache table, shared by every server instance
+type Postgres struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewPostgres creates a cache backed by the analysis_cache table
+func NewPostgres(db *sql.DB, ttl time.Duration) *Postgres {
+	return &Postgres{db: db, ttl: ttl}
+}
+
+// Get returns a cached value if present and not expired
+func (p *Postgres) Get(key string) ([]byte, bool) {
+	var value []byte
+	err := p.db.QueryRow(`
+		SELECT result FROM analysis_cache
+		WHERE key = $1 AND expires_at > NOW()`, key).Scan(&value)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores a value and prunes expired entries
+func (p *Postgres) Set(key string, value []byte) {
+	p.db.Exec(`
+		INSERT INTO analysis_cache (key, result, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET result = $2, created_at = NOW(), expires_at = $3`,
+		key, string(value), time.Now().Add(p.ttl))
+	p.db.Exec("DELETE FROM analysis_cache WHERE expires_at < NOW()")
+}
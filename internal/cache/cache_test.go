@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	t.Run("evicts least recently used", func(t *testing.T) {
+		m := NewMemory(2, time.Hour)
+		m.Set("a", []byte("1"))
+		m.Set("b", []byte("2"))
+		m.Get("a")
+		m.Set("c", []byte("3"))
+
+		_, ok := m.Get("b")
+		assert.False(t, ok)
+		v, ok := m.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, "1", string(v))
+		_, ok = m.Get("c")
+		assert.True(t, ok)
+	})
+
+	t.Run("expires entries after ttl", func(t *testing.T) {
+		now := time.Now()
+		m := NewMemory(10, time.Minute)
+		m.now = func() time.Time { return now }
+		m.Set("a", []byte("1"))
+
+		now = now.Add(2 * time.Minute)
+		_, ok := m.Get("a")
+		assert.False(t, ok)
+	})
+}
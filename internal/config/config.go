@@ -9,6 +9,20 @@ import (
 type Config struct {
 	Token     string `json:"token"`
 	UserEmail string `json:"user_email"`
+
+	// NoAnimation prints analyses at once instead of typing them out
+	NoAnimation bool `json:"no_animation,omitempty"`
+	// Theme is the terminal color theme: dark, light, high-contrast or monochrome
+	Theme string `json:"theme,omitempty"`
+	// Highlights are extra patterns to color in analysis text
+	Highlights []Highlight `json:"highlights,omitempty"`
+}
+
+// Highlight colors the matches of a regular expression, e.g.
+// {"pattern": "payment-svc", "color": "hi-magenta bold"}
+type Highlight struct {
+	Pattern string `json:"pattern"`
+	Color   string `json:"color"`
 }
 
 // GetConfigPath returns the path to the config file
@@ -17,7 +31,8 @@ func GetConfigPath() string {
 	return filepath.Join(home, ".loggar", "config.json")
 }
 
-// SaveToken saves the JWT token and user email to config file
+// SaveToken saves the JWT token and user email to config file, keeping the
+// other settings
 func SaveToken(token, email string) error {
 	configPath := GetConfigPath()
 
@@ -26,10 +41,12 @@ func SaveToken(token, email string) error {
 		return err
 	}
 
-	config := Config{
-		Token:     token,
-		UserEmail: email,
+	config := Config{}
+	if existing, err := LoadToken(); err == nil {
+		config = *existing
 	}
+	config.Token = token
+	config.UserEmail = email
 
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
@@ -0,0 +1,151 @@
+// Package dbtest is a fake database/sql driver for tests of code that queries
+// Postgres. Statements are answered by stubs matched on a substring of their
+// SQL, and every statement is recorded.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Result is the answer to a statement: rows for queries, a count of affected
+// rows for other statements, or an error
+type Result struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	Err          error
+}
+
+// Query is a statement run against the fake database
+type Query struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// DB holds the stubs and the log of statements of a fake database
+type DB struct {
+	mu      sync.Mutex
+	stubs   []stub
+	queries []Query
+}
+
+type stub struct {
+	match  string
+	answer func(args []driver.Value) Result
+}
+
+// New opens a fake database, closed when the test ends
+func New(t testing.TB) (*sql.DB, *DB) {
+	fake := &DB{}
+	db := sql.OpenDB(connector{fake})
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// On answers statements containing match. Later stubs take precedence, and
+// statements no stub matches return no rows.
+func (f *DB) On(match string, answer func(args []driver.Value) Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stubs = append(f.stubs, stub{match, answer})
+}
+
+// Queries returns the statements run so far that contain match
+func (f *DB) Queries(match string) []Query {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []Query
+	for _, q := range f.queries {
+		if strings.Contains(q.SQL, match) {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+func (f *DB) run(query string, named []driver.NamedValue) Result {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		args[i] = nv.Value
+	}
+
+	f.mu.Lock()
+	f.queries = append(f.queries, Query{SQL: query, Args: args})
+	var answer func([]driver.Value) Result
+	for i := len(f.stubs) - 1; i >= 0; i-- {
+		if strings.Contains(query, f.stubs[i].match) {
+			answer = f.stubs[i].answer
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if answer == nil {
+		return Result{}
+	}
+	return answer(args)
+}
+
+type connector struct{ db *DB }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("dbtest: open with dbtest.New")
+}
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("dbtest: prepared statements are not supported")
+}
+func (c conn) Close() error              { return nil }
+func (c conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res := c.db.run(query, args)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return &rows{columns: res.Columns, values: res.Rows}, nil
+}
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res := c.db.run(query, args)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
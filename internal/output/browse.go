@@ -0,0 +1,260 @@
+package output
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// BrowseOptions configure the interactive view
+type BrowseOptions struct {
+	// Logs is the analyzed input, shown in the log pane
+	Logs string
+	// Theme colors the view; nil is the dark theme
+	Theme *Theme
+	// Rerun analyzes a subset of the logs; nil disables re-running
+	Rerun func(ctx context.Context, logs string) (*AnalysisResult, error)
+}
+
+// Browse shows an analysis in a full-screen interactive view (--tui) until
+// the user quits: collapsible sections, the log lines cited by the selected
+// finding, search, copying commands and re-running the analysis on a subset
+// of the lines. Keys are read from the terminal even when the logs were piped
+// on stdin.
+func Browse(result *AnalysisResult, opts BrowseOptions) error {
+	if !isTerminal(os.Stdout) {
+		return errors.New("the interactive view needs a terminal")
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDONLY, 0)
+	if err != nil {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return errors.New("the interactive view needs a terminal to read keys from")
+		}
+		tty = os.Stdin
+	} else {
+		defer tty.Close()
+	}
+
+	state, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer term.Restore(int(tty.Fd()), state)
+
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(w, "\x1b[?1049h\x1b[?25l") // alternate screen, hidden cursor
+	defer func() {
+		fmt.Fprint(w, "\x1b[?25h\x1b[?1049l")
+		w.Flush()
+	}()
+
+	b := newBrowser(result, opts.Logs, opts.Theme, colorAllowed())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Stops reading before the terminal is restored and closed
+	keys, stop := readKeys(tty)
+	defer stop()
+
+	type rerun struct {
+		result *AnalysisResult
+		logs   string
+		err    error
+	}
+	reruns := make(chan rerun, 1)
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	b.width, b.height = screenSize()
+	for redraw := true; ; redraw = true {
+		if redraw {
+			draw(w, b.view())
+		}
+
+		select {
+		case data, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range parseKeys(data) {
+				act := b.key(k)
+				switch act.kind {
+				case actionQuit:
+					return nil
+				case actionCopy:
+					if err := copyToClipboard(w, act.text); err != nil {
+						b.status = "Copy failed: " + err.Error()
+					} else {
+						b.status = "Copied " + countNoun(strings.Count(act.text, "\n")+1, "line")
+					}
+				case actionRerun:
+					if opts.Rerun == nil {
+						b.busy = false
+						b.status = "Re-running is not available"
+						continue
+					}
+					go func(logs string) {
+						result, err := opts.Rerun(ctx, logs)
+						reruns <- rerun{result, logs, err}
+					}(act.text)
+				}
+			}
+		case r := <-reruns:
+			b.rerunDone(r.result, r.logs, r.err)
+		case <-resize.C:
+			width, height := screenSize()
+			if redraw = width != b.width || height != b.height; redraw {
+				b.width, b.height = width, height
+				fmt.Fprint(w, "\x1b[2J")
+			}
+		}
+	}
+}
+
+// screenSize returns the size of the terminal, or 80x24 when unknown
+func screenSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// draw replaces the screen with a frame
+func draw(w *bufio.Writer, lines []string) {
+	for i, line := range lines {
+		fmt.Fprintf(w, "\x1b[%d;1H%s\x1b[0m", i+1, line)
+	}
+	w.Flush()
+}
+
+// key is a decoded keypress
+type key struct {
+	code keyCode
+	r    rune
+}
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPgUp
+	keyPgDn
+	keyHome
+	keyEnd
+	keyEnter
+	keyTab
+	keyBackspace
+	keyEsc
+	keyCtrlC
+	keyUnknown
+)
+
+func (k key) is(r rune) bool {
+	return k.code == keyRune && k.r == r
+}
+
+// escapeKeys maps the escape sequences of special keys, as sent by xterm
+// compatible terminals, to key codes
+var escapeKeys = map[string]keyCode{
+	"[A": keyUp, "[B": keyDown, "[C": keyRight, "[D": keyLeft,
+	"OA": keyUp, "OB": keyDown, "OC": keyRight, "OD": keyLeft,
+	"[5~": keyPgUp, "[6~": keyPgDn,
+	"[H": keyHome, "[F": keyEnd, "OH": keyHome, "OF": keyEnd,
+	"[1~": keyHome, "[4~": keyEnd, "[7~": keyHome, "[8~": keyEnd,
+}
+
+// parseKeys decodes the bytes of one read from a terminal in raw mode. A lone
+// escape byte is the Esc key.
+func parseKeys(data []byte) []key {
+	var keys []key
+	for len(data) > 0 {
+		switch c := data[0]; {
+		case c == 0x1b:
+			if len(data) == 1 || (data[1] != '[' && data[1] != 'O') {
+				keys = append(keys, key{code: keyEsc})
+				data = data[1:]
+				continue
+			}
+			// A CSI sequence ends with a byte in 0x40-0x7e, an SS3 one after one byte
+			end := 2
+			if data[1] == 'O' {
+				end = min(3, len(data))
+			} else {
+				for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+					end++
+				}
+				end = min(end+1, len(data))
+			}
+			code, ok := escapeKeys[string(data[1:end])]
+			if !ok {
+				code = keyUnknown
+			}
+			keys = append(keys, key{code: code})
+			data = data[end:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{code: keyEnter})
+			data = data[1:]
+		case c == '\t':
+			keys = append(keys, key{code: keyTab})
+			data = data[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+			data = data[1:]
+		case c == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+			data = data[1:]
+		case c < 0x20:
+			keys = append(keys, key{code: keyUnknown})
+			data = data[1:]
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, key{code: keyRune, r: r})
+			data = data[size:]
+		}
+	}
+	return keys
+}
+
+// clipboardCommands are tried in order to copy text: macOS, Wayland, X11 and
+// Windows (including WSL)
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// copyToClipboard puts text on the system clipboard with the first tool that
+// works, falling back to the OSC 52 escape sequence, which most terminal
+// emulators understand, also over SSH
+func copyToClipboard(w io.Writer, text string) error {
+	for _, args := range clipboardCommands {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if cmd.Run() == nil {
+			return nil
+		}
+	}
+	_, err := fmt.Fprintf(w, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
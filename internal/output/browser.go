@@ -0,0 +1,674 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// browser is the state of the interactive view (--tui): findings grouped by
+// section on the left, the selected finding and the log lines it cites on the
+// right. It only handles keys and draws frames; Browse runs it on a terminal.
+type browser struct {
+	result *AnalysisResult
+	logs   []string
+	groups []group
+	rows   []row
+
+	width, height int
+	focus         pane
+	cursor, top   int // selected row and first visible row of the findings pane
+	line, logTop  int // selected and first visible log line, 0-based
+	anchor        int // start of the log line selection, -1 when none
+
+	searching bool
+	input     string // search query being typed
+	query     string // last search
+	status    string
+	busy      bool
+	history   []snapshot
+
+	theme *Theme
+	color bool
+}
+
+type pane int
+
+const (
+	paneFindings pane = iota
+	paneLogs
+)
+
+// group is a collapsible section of findings
+type group struct {
+	title     string
+	items     []finding
+	collapsed bool
+}
+
+// finding is one bullet, cause, event or action, with the lines it cites
+type finding struct {
+	text     string
+	evidence []LineRange
+	command  string
+}
+
+// row is a visible line of the findings pane: a group title (item -1) or one
+// of its findings
+type row struct {
+	group, item int
+}
+
+// snapshot is an earlier analysis, restored with backspace after a re-run
+type snapshot struct {
+	result *AnalysisResult
+	logs   []string
+	cursor int
+}
+
+func newBrowser(result *AnalysisResult, logs string, theme *Theme, color bool) *browser {
+	if theme == nil {
+		theme = defaultTheme()
+	}
+	b := &browser{anchor: -1, theme: theme, color: color, width: 80, height: 24}
+	b.load(result, logs)
+	return b
+}
+
+// load shows an analysis, replacing the current one
+func (b *browser) load(result *AnalysisResult, logs string) {
+	b.result = result
+	b.logs = nil
+	if logs = strings.TrimRight(strings.ReplaceAll(logs, "\r\n", "\n"), "\n"); logs != "" {
+		b.logs = strings.Split(logs, "\n")
+	}
+	b.groups = findingGroups(result)
+	b.cursor, b.top, b.line, b.logTop, b.anchor = 0, 0, 0, 0, -1
+	b.refresh()
+	b.follow()
+}
+
+// findingGroups flattens a result into the sections shown in the findings pane
+func findingGroups(result *AnalysisResult) []group {
+	var groups []group
+	add := func(title string, items []finding) {
+		if len(items) > 0 {
+			groups = append(groups, group{title: strings.ToUpper(title), items: items})
+		}
+	}
+
+	var items []finding
+	for _, issue := range result.KnownIssues {
+		text := fmt.Sprintf("%s, %d matching lines", issue.Title, issue.Count)
+		if issue.Severity != "" {
+			text = strings.ToUpper(issue.Severity) + " " + text
+		}
+		items = append(items, finding{text: text, evidence: issue.Evidence})
+	}
+	add("Known issues", items)
+
+	for _, section := range result.Sections {
+		items = nil
+		for idx, content := range section.Content {
+			_, text := splitBullet(content)
+			f := finding{text: text}
+			if idx < len(section.Evidence) {
+				f.evidence = section.Evidence[idx]
+			}
+			items = append(items, f)
+		}
+		add(section.Title, items)
+	}
+
+	items = nil
+	for _, cause := range result.Causes {
+		items = append(items, finding{text: fmt.Sprintf("%3d%% %s", int(cause.Confidence*100+0.5), cause.Cause), evidence: cause.Evidence})
+	}
+	add("Likely causes", items)
+
+	items = nil
+	for _, src := range result.Sources {
+		text := fmt.Sprintf("%s: %d lines, %d errors, %d warnings", src.Name, src.Lines, src.Errors, src.Warnings)
+		if src.Summary != "" {
+			text += ". " + src.Summary
+		}
+		items = append(items, finding{text: text})
+	}
+	add("Sources", items)
+
+	items = nil
+	for _, event := range result.Timeline {
+		text := event.Event
+		if event.Component != "" {
+			text = "[" + event.Component + "] " + text
+		}
+		if event.Time != "" {
+			text = event.Time + " " + text
+		}
+		items = append(items, finding{text: text, evidence: event.Evidence})
+	}
+	add("Timeline", items)
+
+	items = nil
+	for _, action := range result.Actions {
+		items = append(items, finding{text: action.Description, command: action.Command})
+	}
+	add("Recommended actions", items)
+
+	return groups
+}
+
+// refresh rebuilds the visible rows after a group is collapsed or expanded
+func (b *browser) refresh() {
+	b.rows = b.rows[:0]
+	for g, grp := range b.groups {
+		b.rows = append(b.rows, row{group: g, item: -1})
+		if grp.collapsed {
+			continue
+		}
+		for i := range grp.items {
+			b.rows = append(b.rows, row{group: g, item: i})
+		}
+	}
+	b.cursor = clamp(b.cursor, 0, len(b.rows)-1)
+}
+
+// selected returns the finding under the cursor, or nil on a group title
+func (b *browser) selected() *finding {
+	if b.cursor >= len(b.rows) || b.rows[b.cursor].item < 0 {
+		return nil
+	}
+	r := b.rows[b.cursor]
+	return &b.groups[r.group].items[r.item]
+}
+
+// follow scrolls the log pane to the first line cited by the selected finding
+func (b *browser) follow() {
+	f := b.selected()
+	if f == nil || len(f.evidence) == 0 {
+		return
+	}
+	b.line = clamp(f.evidence[0].Start-1, 0, len(b.logs)-1)
+	b.logTop = clamp(b.line-b.logHeight()/3, 0, max(len(b.logs)-b.logHeight(), 0))
+}
+
+// cited reports whether the selected finding cites a log line (0-based)
+func (b *browser) cited(line int) bool {
+	f := b.selected()
+	if f == nil {
+		return false
+	}
+	for _, r := range f.evidence {
+		if line+1 >= r.Start && line+1 <= max(r.End, r.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// selection returns the selected log lines, first and last inclusive, and
+// whether there is a selection
+func (b *browser) selection() (int, int, bool) {
+	if b.anchor < 0 || len(b.logs) == 0 {
+		return 0, 0, false
+	}
+	return max(min(b.anchor, b.line), 0), min(max(b.anchor, b.line), len(b.logs)-1), true
+}
+
+// action is what the terminal loop should do after a key
+type action struct {
+	kind actionKind
+	text string
+}
+
+type actionKind int
+
+const (
+	actionNone actionKind = iota
+	actionQuit
+	actionCopy
+	actionRerun
+)
+
+// key handles a keypress
+func (b *browser) key(k key) action {
+	if b.searching {
+		b.searchKey(k)
+		return action{}
+	}
+	b.status = ""
+
+	switch {
+	case k.is('q'), k.code == keyCtrlC, k.code == keyEsc && b.anchor < 0:
+		return action{kind: actionQuit}
+	case k.code == keyEsc:
+		b.anchor = -1
+	case k.code == keyTab, k.code == keyRight && b.focus == paneFindings, k.code == keyLeft && b.focus == paneLogs:
+		b.focus = 1 - b.focus
+	case k.code == keyUp, k.is('k'):
+		b.move(-1)
+	case k.code == keyDown, k.is('j'):
+		b.move(1)
+	case k.code == keyPgUp:
+		b.move(-b.pageHeight())
+	case k.code == keyPgDn, k.is(' ') && b.focus == paneLogs:
+		b.move(b.pageHeight())
+	case k.code == keyHome, k.is('g'):
+		b.move(-1 << 30)
+	case k.code == keyEnd, k.is('G'):
+		b.move(1 << 30)
+	case k.code == keyEnter, k.is(' '):
+		b.toggle()
+	case k.is('/'):
+		b.searching, b.input = true, ""
+	case k.is('n'):
+		b.search(1)
+	case k.is('N'):
+		b.search(-1)
+	case k.is('v'):
+		if b.focus == paneLogs && b.anchor < 0 && len(b.logs) > 0 {
+			b.anchor = b.line
+		} else {
+			b.anchor = -1
+		}
+	case k.is('c'):
+		return b.copy()
+	case k.is('r'):
+		return b.rerun()
+	case k.code == keyBackspace, k.is('b'):
+		b.back()
+	}
+	return action{}
+}
+
+// move moves the cursor of the focused pane by delta rows
+func (b *browser) move(delta int) {
+	if b.focus == paneLogs {
+		b.line = clamp(b.line+delta, 0, len(b.logs)-1)
+		h := b.logHeight()
+		if b.line < b.logTop {
+			b.logTop = b.line
+		} else if b.line >= b.logTop+h {
+			b.logTop = b.line - h + 1
+		}
+		return
+	}
+	b.cursor = clamp(b.cursor+delta, 0, len(b.rows)-1)
+	b.follow()
+}
+
+// toggle collapses or expands the group under the cursor
+func (b *browser) toggle() {
+	if len(b.rows) == 0 || b.focus != paneFindings {
+		return
+	}
+	g := b.rows[b.cursor].group
+	b.groups[g].collapsed = !b.groups[g].collapsed
+	b.refresh()
+	for i, r := range b.rows {
+		if r == (row{group: g, item: -1}) {
+			b.cursor = i
+		}
+	}
+}
+
+func (b *browser) searchKey(k key) {
+	switch {
+	case k.code == keyEsc, k.code == keyCtrlC:
+		b.searching = false
+	case k.code == keyEnter:
+		b.searching = false
+		if b.input != "" {
+			b.query = b.input
+			b.search(1)
+		}
+	case k.code == keyBackspace:
+		if r := []rune(b.input); len(r) > 0 {
+			b.input = string(r[:len(r)-1])
+		}
+	case k.code == keyRune:
+		b.input += string(k.r)
+	}
+}
+
+// search moves to the next (dir 1) or previous (dir -1) finding or log line
+// containing the query, expanding a collapsed group to show a match
+func (b *browser) search(dir int) {
+	if b.query == "" {
+		b.status = "Type / to search"
+		return
+	}
+	query := strings.ToLower(b.query)
+
+	if b.focus == paneLogs {
+		for step := 1; step <= len(b.logs); step++ {
+			i := (b.line + dir*step + len(b.logs)*step) % len(b.logs)
+			if strings.Contains(strings.ToLower(b.logs[i]), query) {
+				b.move(i - b.line)
+				return
+			}
+		}
+		b.status = "No log line matches " + b.query
+		return
+	}
+
+	// Search every finding, including those of collapsed groups
+	var all []row
+	current := 0
+	for g, grp := range b.groups {
+		for i := range grp.items {
+			if b.cursor < len(b.rows) && b.rows[b.cursor] == (row{g, i}) {
+				current = len(all)
+			}
+			all = append(all, row{g, i})
+		}
+	}
+	for step := 1; step <= len(all); step++ {
+		r := all[(current+dir*step+len(all)*step)%len(all)]
+		if !strings.Contains(strings.ToLower(b.groups[r.group].items[r.item].text), query) {
+			continue
+		}
+		b.groups[r.group].collapsed = false
+		b.refresh()
+		for i, vr := range b.rows {
+			if vr == r {
+				b.cursor = i
+			}
+		}
+		b.follow()
+		return
+	}
+	b.status = "No finding matches " + b.query
+}
+
+// copy copies the selected log lines, or the selected finding's command, or
+// its text
+func (b *browser) copy() action {
+	if first, last, ok := b.selection(); ok && b.focus == paneLogs {
+		return action{kind: actionCopy, text: strings.Join(b.logs[first:last+1], "\n")}
+	}
+	f := b.selected()
+	switch {
+	case f == nil:
+		b.status = "Nothing to copy"
+		return action{}
+	case f.command != "":
+		return action{kind: actionCopy, text: f.command}
+	default:
+		return action{kind: actionCopy, text: f.text}
+	}
+}
+
+// rerun asks for a new analysis of the selected log lines, or of the lines
+// cited by the selected finding
+func (b *browser) rerun() action {
+	if b.busy {
+		b.status = "An analysis is already running"
+		return action{}
+	}
+	var lines []string
+	if first, last, ok := b.selection(); ok {
+		lines = b.logs[first : last+1]
+	} else if f := b.selected(); f != nil {
+		for i := range b.logs {
+			if b.cited(i) {
+				lines = append(lines, b.logs[i])
+			}
+		}
+	}
+	if len(lines) == 0 {
+		b.status = "Select lines with v in the log pane, or a finding that cites lines"
+		return action{}
+	}
+	b.busy = true
+	b.status = fmt.Sprintf("Analyzing %s…", countNoun(len(lines), "line"))
+	return action{kind: actionRerun, text: strings.Join(lines, "\n") + "\n"}
+}
+
+// rerunDone shows the analysis of a subset, keeping the current one to go
+// back to
+func (b *browser) rerunDone(result *AnalysisResult, logs string, err error) {
+	b.busy = false
+	if err != nil {
+		b.status = "Analysis failed: " + err.Error()
+		return
+	}
+	b.history = append(b.history, snapshot{b.result, b.logs, b.cursor})
+	b.load(result, logs)
+	b.status = fmt.Sprintf("Analysis of %s. Backspace returns to the previous one", countNoun(len(b.logs), "line"))
+}
+
+// back restores the analysis before the last re-run
+func (b *browser) back() {
+	if len(b.history) == 0 {
+		return
+	}
+	prev := b.history[len(b.history)-1]
+	b.history = b.history[:len(b.history)-1]
+	b.load(prev.result, strings.Join(prev.logs, "\n"))
+	b.cursor = clamp(prev.cursor, 0, len(b.rows)-1)
+	b.follow()
+}
+
+// Layout: two header lines and a rule, the panes, and a status line
+const browserChrome = 4
+
+func (b *browser) pageHeight() int {
+	return max(b.height-browserChrome-1, 1)
+}
+
+// logHeight is the number of log lines shown below the selected finding
+func (b *browser) logHeight() int {
+	return max(b.height-browserChrome-len(b.detail(b.rightWidth()))-1, 1)
+}
+
+func (b *browser) leftWidth() int {
+	return clamp(b.width*2/5, 24, 60)
+}
+
+func (b *browser) rightWidth() int {
+	return b.width - b.leftWidth() - 1
+}
+
+// detail is the selected finding in full, shown above its log lines
+func (b *browser) detail(width int) []string {
+	f := b.selected()
+	if f == nil {
+		if len(b.rows) > 0 {
+			grp := b.groups[b.rows[b.cursor].group]
+			return []string{b.paint(b.theme.title, grp.title) + b.paint(b.theme.dim, fmt.Sprintf(" (%d)", len(grp.items)))}
+		}
+		return nil
+	}
+	lines := wrapText(b.highlight(f.text), width-1, "")
+	if len(lines) > 6 {
+		lines = append(lines[:5], "…")
+	}
+	if refs := formatLineRanges(f.evidence); refs != "" {
+		lines = append(lines, b.paint(b.theme.dim, refs))
+	}
+	if f.command != "" {
+		lines = append(lines, b.paint(b.theme.fix, "$ "+f.command))
+	}
+	return lines
+}
+
+// view draws the frame as exactly height lines of width columns
+func (b *browser) view() []string {
+	width, height := b.width, b.height
+	if width < 40 || height < 10 {
+		return fitLines([]string{"Terminal too small"}, width, height)
+	}
+	lines := make([]string, 0, height)
+
+	// Header
+	head := "loggar"
+	if b.result.Severity != "" {
+		style, ok := b.theme.severity[b.result.Severity]
+		if !ok {
+			style = b.theme.severity[""]
+		}
+		head += " " + b.paint(style, " "+strings.ToUpper(b.result.Severity)+" ")
+	}
+	if b.result.PrimaryIssue != "" {
+		head += " " + b.result.PrimaryIssue
+	}
+	if len(b.history) > 0 {
+		head += b.paint(b.theme.dim, fmt.Sprintf("  (re-run %d)", len(b.history)))
+	}
+	lines = append(lines, fit(head, width), fit(b.paint(b.theme.dim, b.result.Summary), width))
+	lines = append(lines, b.paint(b.theme.dim, strings.Repeat("─", b.leftWidth())+"┬"+strings.Repeat("─", b.rightWidth())))
+
+	// Panes
+	body := height - browserChrome
+	left := b.findingsPane(body)
+	right := b.logPane(body)
+	sep := b.paint(b.theme.dim, "│")
+	for i := 0; i < body; i++ {
+		lines = append(lines, left[i]+sep+right[i])
+	}
+
+	// Status line
+	var status string
+	switch {
+	case b.searching:
+		status = "/" + b.input + "█"
+	case b.status != "":
+		status = b.paint(b.theme.warning, b.status)
+	default:
+		status = b.paint(b.theme.dim, "↑↓ move  ⏎ fold  tab pane  / search  n next  v select  c copy  r re-run  ⌫ back  q quit")
+	}
+	return append(lines, fit(status, width))
+}
+
+func (b *browser) findingsPane(height int) []string {
+	width := b.leftWidth()
+	if b.cursor < b.top {
+		b.top = b.cursor
+	} else if b.cursor >= b.top+height {
+		b.top = b.cursor - height + 1
+	}
+
+	lines := make([]string, 0, height)
+	for i := b.top; i < len(b.rows) && len(lines) < height; i++ {
+		r := b.rows[i]
+		grp := b.groups[r.group]
+		var text string
+		if r.item < 0 {
+			marker := "▾ "
+			if grp.collapsed {
+				marker = "▸ "
+			}
+			text = marker + grp.title + fmt.Sprintf(" (%d)", len(grp.items))
+		} else {
+			bullet := "• "
+			if grp.items[r.item].command != "" {
+				bullet = "→ "
+			}
+			text = "  " + bullet + grp.items[r.item].text
+		}
+		text = fit(text, width)
+
+		switch {
+		case i == b.cursor && b.focus == paneFindings:
+			text = b.paint(Style{color.ReverseVideo}, text)
+		case i == b.cursor:
+			text = b.paint(Style{color.Underline}, text)
+		case r.item < 0:
+			text = b.paint(b.theme.title, text)
+		}
+		lines = append(lines, text)
+	}
+	return fitLines(lines, width, height)
+}
+
+func (b *browser) logPane(height int) []string {
+	width := b.rightWidth()
+	lines := b.detail(width)
+	lines = append(lines, b.paint(b.theme.dim, strings.Repeat("╌", width)))
+	if len(b.logs) == 0 {
+		lines = append(lines, b.paint(b.theme.dim, "No log lines to show"))
+		return fitLines(lines, width, height)
+	}
+
+	first, last, selecting := b.selection()
+	numWidth := len(fmt.Sprint(len(b.logs)))
+	for i := b.logTop; i < len(b.logs) && len(lines) < height; i++ {
+		marker := "  "
+		if b.cited(i) {
+			marker = b.paint(b.theme.bullet, "▌ ")
+		}
+		prefix := fmt.Sprintf("%*d ", numWidth, i+1)
+		text := truncateWidth(strings.ReplaceAll(b.logs[i], "\t", "    "), width-numWidth-3)
+		text = fit(prefix+text, width-2)
+
+		switch {
+		case i == b.line && b.focus == paneLogs:
+			text = b.paint(Style{color.ReverseVideo}, text)
+		case selecting && i >= first && i <= last:
+			text = b.paint(b.theme.accent, text)
+		case b.cited(i):
+			text = b.paint(b.theme.excerpt, text)
+		default:
+			text = b.paint(b.theme.dim, prefix) + text[len(prefix):]
+		}
+		lines = append(lines, marker+text)
+	}
+	return fitLines(lines, width, height)
+}
+
+func (b *browser) paint(style Style, text string) string {
+	c := color.New(style...)
+	if b.color && len(style) > 0 {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	return c.Sprint(text)
+}
+
+func (b *browser) highlight(text string) string {
+	if !b.color {
+		return text
+	}
+	return b.theme.highlight(text, func(s Style) *color.Color {
+		c := color.New(s...)
+		c.EnableColor()
+		return c
+	})
+}
+
+// fit truncates or pads text to exactly width columns
+func fit(text string, width int) string {
+	if w := displayWidth(text); w > width {
+		text = truncateWidth(text, width)
+	} else {
+		text += strings.Repeat(" ", width-w)
+	}
+	return text
+}
+
+// fitLines pads lines to exactly height lines of width columns
+func fitLines(lines []string, width, height int) []string {
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i := range lines {
+		lines[i] = fit(lines[i], width)
+	}
+	for len(lines) < height {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	return lines
+}
+
+func clamp(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
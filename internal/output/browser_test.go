@@ -0,0 +1,164 @@
+package output
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runes(text string) []key {
+	return parseKeys([]byte(text))
+}
+
+func press(b *browser, keys ...key) action {
+	var last action
+	for _, k := range keys {
+		last = b.key(k)
+	}
+	return last
+}
+
+func TestParseKeys(t *testing.T) {
+	assert.Equal(t, []key{
+		{code: keyUp}, {code: keyDown}, {code: keyPgDn}, {code: keyHome},
+		{code: keyRune, r: 'q'}, {code: keyRune, r: 'é'}, {code: keyEnter}, {code: keyBackspace}, {code: keyEsc},
+	}, parseKeys([]byte("\x1b[A\x1bOB\x1b[6~\x1b[Hqé\r\x7f\x1b")))
+
+	assert.Equal(t, []key{{code: keyUnknown}, {code: keyCtrlC}}, parseKeys([]byte("\x1b[1;5A\x03")))
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	b := newBrowser(sampleResult(), sampleLogs, nil, false)
+
+	var titles []string
+	for _, g := range b.groups {
+		titles = append(titles, g.title)
+	}
+	assert.Equal(t, []string{"KNOWN ISSUES", "ROOT CAUSE", "LIKELY CAUSES", "TIMELINE", "RECOMMENDED ACTIONS"}, titles)
+	assert.Nil(t, b.selected())
+
+	// Moving onto a finding jumps the log pane to its cited line
+	press(b, runes("jjj")...)
+	require.NotNil(t, b.selected())
+	assert.Equal(t, "Database pool exhausted on <db-1>", b.selected().text)
+	assert.Equal(t, 1, b.line)
+	assert.True(t, b.cited(1))
+	assert.False(t, b.cited(0))
+
+	// Collapsing a group hides its findings and keeps the cursor on its title
+	press(b, runes("k")...)
+	press(b, key{code: keyEnter})
+	assert.True(t, b.groups[1].collapsed)
+	assert.Equal(t, row{group: 1, item: -1}, b.rows[b.cursor])
+	assert.Len(t, b.rows, 2+1+2+2+2)
+
+	press(b, key{code: keyEnter})
+	assert.False(t, b.groups[1].collapsed)
+
+	assert.Equal(t, actionQuit, press(b, runes("q")...).kind)
+}
+
+func TestBrowserSearch(t *testing.T) {
+	b := newBrowser(sampleResult(), sampleLogs, nil, false)
+	b.groups[4].collapsed = true
+	b.refresh()
+
+	// Matches in collapsed groups expand them
+	press(b, runes("/restart\r")...)
+	assert.False(t, b.searching)
+	assert.False(t, b.groups[4].collapsed)
+	require.NotNil(t, b.selected())
+	assert.Equal(t, "Restart the pool", b.selected().text)
+
+	press(b, runes("/nothing like this\r")...)
+	assert.Contains(t, b.status, "No finding matches")
+
+	// In the log pane, the search moves between lines
+	press(b, key{code: keyTab})
+	press(b, runes("/error\r")...)
+	assert.Equal(t, 1, b.line)
+	press(b, runes("n")...)
+	assert.Equal(t, 2, b.line)
+	press(b, runes("n")...)
+	assert.Equal(t, 1, b.line)
+}
+
+func TestBrowserCopyAndRerun(t *testing.T) {
+	b := newBrowser(sampleResult(), sampleLogs, nil, false)
+
+	// An action copies its command
+	press(b, runes("G")...)
+	act := press(b, runes("c")...)
+	assert.Equal(t, action{kind: actionCopy, text: "kubectl rollout restart deploy/api"}, act)
+
+	// A selection in the log pane is copied and re-analyzed
+	press(b, key{code: keyTab}, key{code: keyHome})
+	press(b, runes("vj")...)
+	act = press(b, runes("c")...)
+	assert.Equal(t, action{kind: actionCopy, text: "10:00:00 INFO start\n10:00:01 ERROR pool exhausted <script>alert(1)</script>"}, act)
+
+	act = press(b, runes("r")...)
+	assert.Equal(t, actionRerun, act.kind)
+	assert.Equal(t, 2, strings.Count(act.text, "\n"))
+	assert.True(t, b.busy)
+	assert.Equal(t, actionNone, press(b, runes("r")...).kind, "one analysis at a time")
+
+	b.rerunDone(nil, "", errors.New("quota exceeded"))
+	assert.Contains(t, b.status, "quota exceeded")
+	assert.False(t, b.busy)
+
+	// A successful re-run replaces the analysis until backspace
+	press(b, runes("r")...)
+	b.rerunDone(&AnalysisResult{Summary: "Pool exhausted", Sections: []Section{{Title: "Cause", Content: []string{"too few connections"}}}}, act.text, nil)
+	assert.Equal(t, "Pool exhausted", b.result.Summary)
+	assert.Len(t, b.logs, 2)
+
+	press(b, key{code: keyBackspace})
+	assert.Equal(t, sampleResult().Summary, b.result.Summary)
+	assert.Len(t, b.logs, 3)
+}
+
+func TestBrowserWithoutLogs(t *testing.T) {
+	b := newBrowser(sampleResult(), "", nil, false)
+	press(b, key{code: keyTab})
+	press(b, runes("vjk")...)
+	_, _, ok := b.selection()
+	assert.False(t, ok, "there are no lines to select")
+
+	// Without a selection or a finding under the cursor there is nothing to
+	// copy or re-run
+	assert.Equal(t, actionNone, press(b, runes("c")...).kind)
+	assert.Equal(t, "Nothing to copy", b.status)
+	assert.Equal(t, actionNone, press(b, runes("r")...).kind)
+	assert.NotPanics(t, func() { b.view() })
+
+	// A selection is clamped to the lines of a shorter analysis
+	b = newBrowser(sampleResult(), sampleLogs, nil, false)
+	b.focus, b.anchor, b.line = paneLogs, 5, 1
+	first, last, ok := b.selection()
+	require.True(t, ok)
+	assert.Equal(t, []int{1, 2}, []int{first, last})
+}
+
+func TestBrowserView(t *testing.T) {
+	b := newBrowser(sampleResult(), sampleLogs, nil, false)
+	b.width, b.height = 100, 20
+	press(b, runes("jjj")...)
+
+	frame := b.view()
+	require.Len(t, frame, 20)
+	for _, line := range frame {
+		assert.Equal(t, 100, displayWidth(line), "%q", line)
+	}
+	assert.Contains(t, frame[0], "HIGH")
+	assert.Contains(t, frame[0], "Connection pool exhausted")
+	joined := strings.Join(frame, "\n")
+	assert.Contains(t, joined, "▾ ROOT CAUSE (2)")
+	assert.Contains(t, joined, "▌ 2 10:00:01 ERROR pool exhausted")
+
+	b.width, b.height = 30, 8
+	assert.Contains(t, b.view()[0], "Terminal too small")
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	surveyterm "github.com/AlecAivazis/survey/v2/terminal"
+)
+
+// ChatOptions configure the follow-up prompt
+type ChatOptions struct {
+	// Theme colors the answers; nil is the dark theme
+	Theme *Theme
+	// Ask sends a question about the analysis and returns the answer, e.g.
+	// with POST /api/analyses/:id/messages
+	Ask func(ctx context.Context, question string) (string, error)
+}
+
+// Chat prompts for follow-up questions about an analysis (loggar ask,
+// analyze --chat) and prints the answers until the user types "exit" or
+// presses Ctrl+D. Ctrl+C cancels a question that is waiting for an answer,
+// and quits at the prompt.
+func Chat(opts ChatOptions) error {
+	if opts.Ask == nil {
+		return errors.New("no way to ask questions")
+	}
+	w := bufio.NewWriter(os.Stdout)
+	t := newTerminal(w, isTerminal(os.Stdout) && colorAllowed(), getTermWidth(), Options{Theme: opts.Theme})
+
+	prompt := &survey.Input{
+		Message: "Ask a follow-up question",
+		Help:    "Questions are answered with the logs and the analysis as context. Type exit or press Ctrl+D to quit.",
+	}
+	for {
+		var question string
+		err := survey.AskOne(prompt, &question)
+		if errors.Is(err, surveyterm.InterruptErr) || errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		question = strings.TrimSpace(question)
+		switch strings.ToLower(question) {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		answer, err := opts.Ask(ctx, question)
+		cancelled := ctx.Err() != nil
+		stop()
+
+		switch {
+		case cancelled:
+			t.style(t.theme.dim...).Fprintln(t.w, "Cancelled")
+		case err != nil:
+			t.style(t.theme.warning...).Fprintf(t.w, "✗ %v\n", err)
+		default:
+			t.answer(answer)
+		}
+		t.w.Flush()
+	}
+}
+
+// answer writes the reply to a follow-up question: paragraphs and list items
+// wrapped to the terminal width and highlighted, code blocks as they are
+func (t *terminal) answer(text string) {
+	bulletColor := t.style(t.theme.bullet...)
+	codeColor := t.style(t.theme.fix...)
+
+	fmt.Fprintln(t.w)
+	code := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			code = !code
+		case code:
+			codeColor.Fprintln(t.w, "  "+line)
+		case trimmed == "":
+			fmt.Fprintln(t.w)
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			bulletColor.Fprint(t.w, "• ")
+			t.typeLines(wrapText(t.highlight(trimmed[2:]), t.width-3, "  "), 0)
+		default:
+			t.typeLines(wrapText(t.highlight(trimmed), t.width-1, ""), 0)
+		}
+	}
+	fmt.Fprintln(t.w)
+}
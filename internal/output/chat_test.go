@@ -0,0 +1,29 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatAnswer(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	term := newTerminal(w, false, 40, Options{})
+
+	term.answer("The pool ran out before the network errors started.\n\n" +
+		"- Timeouts begin at [L2], after the pool warning\n" +
+		"```\nSHOW max_connections;\n```\n")
+	w.Flush()
+
+	assert.Equal(t, "\n"+
+		"The pool ran out before the network\n"+
+		"errors started.\n"+
+		"\n"+
+		"• Timeouts begin at [L2], after the\n"+
+		"  pool warning\n"+
+		"  SHOW max_connections;\n"+
+		"\n", buf.String())
+}
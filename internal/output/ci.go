@@ -0,0 +1,226 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ciFinding is one problem reported to a CI system: a known issue, a likely
+// cause, or a diagnostic bullet that cites log lines
+type ciFinding struct {
+	rule     string // stable identifier, e.g. "known/db-pool"
+	title    string
+	message  string
+	severity string
+	help     string // remediation
+	helpURL  string
+	evidence []LineRange
+}
+
+// ciFindings lists the findings of a result for the CI formats. Causes are
+// reported when the result has them (v2); otherwise section bullets that cite
+// evidence, since recommendations carry no citations. A result with neither
+// is reported as a single finding so the pipeline still sees a failure.
+func ciFindings(result *AnalysisResult) []ciFinding {
+	var findings []ciFinding
+	for _, issue := range result.KnownIssues {
+		findings = append(findings, ciFinding{
+			rule:     "known/" + issue.Rule,
+			title:    issue.Title,
+			message:  fmt.Sprintf("%s (%s)", issue.Title, countNoun(issue.Count, "matching line")),
+			severity: orDefault(issue.Severity, result.Severity),
+			help:     issue.Remediation,
+			helpURL:  issue.RunbookURL,
+			evidence: issue.Evidence,
+		})
+	}
+
+	for i, cause := range result.Causes {
+		findings = append(findings, ciFinding{
+			rule:     fmt.Sprintf("cause/%d", i+1),
+			title:    cause.Cause,
+			message:  fmt.Sprintf("%s (%d%% confidence)", cause.Cause, int(cause.Confidence*100+0.5)),
+			severity: result.Severity,
+			help:     ciRemediation(result),
+			evidence: cause.Evidence,
+		})
+	}
+
+	if len(result.Causes) == 0 {
+		for _, section := range result.Sections {
+			for idx, item := range section.Content {
+				if idx >= len(section.Evidence) || len(section.Evidence[idx]) == 0 {
+					continue
+				}
+				_, text := splitBullet(item)
+				findings = append(findings, ciFinding{
+					rule:     "finding/" + ciSlug(section.Title),
+					title:    text,
+					message:  text,
+					severity: result.Severity,
+					evidence: section.Evidence[idx],
+				})
+			}
+		}
+	}
+
+	if len(findings) == 0 && (result.PrimaryIssue != "" || result.Summary != "") {
+		title := orDefault(result.PrimaryIssue, result.Summary)
+		findings = append(findings, ciFinding{
+			rule:     "summary",
+			title:    title,
+			message:  orDefault(result.Summary, title),
+			severity: result.Severity,
+			help:     ciRemediation(result),
+		})
+	}
+	return findings
+}
+
+// ciLevel maps a severity to "error", "warning" or "notice". Unknown
+// severities are errors: the logs come from a failed run.
+func ciLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "medium":
+		return "warning"
+	case "low", "info":
+		return "notice"
+	default:
+		return "error"
+	}
+}
+
+// ciRemediation joins the recommended actions into one help text
+func ciRemediation(result *AnalysisResult) string {
+	var steps []string
+	for _, action := range result.Actions {
+		step := action.Description
+		if action.Command != "" {
+			step += ": " + action.Command
+		}
+		steps = append(steps, "- "+step)
+	}
+	return strings.Join(steps, "\n")
+}
+
+// ciSlug turns a section title into a rule identifier, e.g. "WHATS BROKEN"
+// into "whats-broken"
+func ciSlug(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	}), "-")
+}
+
+// location is a range of lines in one log file
+type location struct {
+	file       string
+	start, end int
+	lines      []string // the text of the lines, when the logs are known
+}
+
+// locator maps line numbers of the analyzed input back to the log files.
+// A result with a line map, from logs the server cut or merged, is mapped
+// through it. Otherwise input merged from several files has every line
+// prefixed with "[name] "; such lines are attributed to their file and
+// renumbered within it.
+type locator struct {
+	file  string
+	lines []string
+	at    []location // position of each input line in its file, for mapped or merged input
+}
+
+func newLocator(result *AnalysisResult, opts Options) *locator {
+	l := &locator{file: opts.LogFile}
+	if opts.Logs != "" {
+		l.lines = strings.Split(strings.TrimRight(strings.ReplaceAll(opts.Logs, "\r\n", "\n"), "\n"), "\n")
+	}
+	if len(result.LineMap) > 0 {
+		l.mapLines(result.LineMap)
+		return l
+	}
+	if len(result.Sources) < 2 || len(l.lines) == 0 {
+		return l
+	}
+
+	// Longest names first, so "api" does not claim the lines of "api-gateway"
+	names := make([]string, 0, len(result.Sources))
+	for _, src := range result.Sources {
+		names = append(names, src.Name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	counts := make(map[string]int)
+	l.at = make([]location, len(l.lines))
+	for i, line := range l.lines {
+		for _, name := range names {
+			prefix := "[" + name + "] "
+			if strings.HasPrefix(line, prefix) {
+				counts[name]++
+				l.at[i] = location{file: name, start: counts[name], end: counts[name], lines: []string{line[len(prefix):]}}
+				break
+			}
+		}
+	}
+	return l
+}
+
+// mapLines locates analyzed lines through a line map. Lines of the logs sent
+// as text belong to the locator's file, and their text is known when the file
+// is the only input.
+func (l *locator) mapLines(lineMap []LineSpan) {
+	single := true
+	for _, s := range lineMap {
+		single = single && s.Source == lineMap[0].Source
+	}
+	l.at = make([]location, lineMap[len(lineMap)-1].End)
+	for n := 1; n <= len(l.at); n++ {
+		source, line, ok := inputLine(lineMap, n)
+		if !ok {
+			continue
+		}
+		at := location{file: source, start: line, end: line}
+		if source == "" || single && l.file != "" {
+			at.file = l.file
+		}
+		if single && line <= len(l.lines) {
+			at.lines = []string{l.lines[line-1]}
+		}
+		l.at[n-1] = at
+	}
+}
+
+// resolve returns the file locations of cited input lines. Without a file
+// name there is nothing to point at, and no locations are returned.
+func (l *locator) resolve(ranges []LineRange) []location {
+	var locs []location
+	for _, r := range ranges {
+		end := max(r.End, r.Start)
+		if l.at == nil {
+			if l.file == "" {
+				continue
+			}
+			loc := location{file: l.file, start: max(r.Start, 1), end: end}
+			for n := max(r.Start, 1); n <= end && n <= len(l.lines); n++ {
+				loc.lines = append(loc.lines, l.lines[n-1])
+			}
+			locs = append(locs, loc)
+			continue
+		}
+
+		// Merged input: consecutive lines of the same file form one location
+		for n := max(r.Start, 1); n <= end && n <= len(l.at); n++ {
+			at := l.at[n-1]
+			if at.file == "" {
+				continue
+			}
+			if last := len(locs) - 1; last >= 0 && locs[last].file == at.file && locs[last].end+1 == at.start {
+				locs[last].end = at.start
+				locs[last].lines = append(locs[last].lines, at.lines...)
+				continue
+			}
+			locs = append(locs, at)
+		}
+	}
+	return locs
+}
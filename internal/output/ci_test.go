@@ -0,0 +1,156 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIFindings(t *testing.T) {
+	findings := ciFindings(sampleResult())
+	require.Len(t, findings, 2)
+	assert.Equal(t, "known/pool", findings[0].rule)
+	assert.Equal(t, "cause/1", findings[1].rule)
+	assert.Equal(t, "Slow query | lock (80% confidence)", findings[1].message)
+	assert.Equal(t, "- Restart the pool: kubectl rollout restart deploy/api", findings[1].help)
+
+	// Without causes, only the bullets that cite evidence are findings
+	v1 := &AnalysisResult{Summary: "Pool exhausted", Sections: sampleResult().Sections}
+	findings = ciFindings(v1)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "finding/root-cause", findings[0].rule)
+	assert.Equal(t, "Database pool exhausted on <db-1>", findings[0].message)
+
+	// A result without any is still reported
+	findings = ciFindings(&AnalysisResult{Summary: "Pool exhausted"})
+	require.Len(t, findings, 1)
+	assert.Equal(t, "summary", findings[0].rule)
+
+	assert.Equal(t, "error", ciLevel(""))
+	assert.Equal(t, "warning", ciLevel("medium"))
+	assert.Equal(t, "notice", ciLevel("info"))
+}
+
+func TestLocator(t *testing.T) {
+	single := newLocator(sampleResult(), Options{Logs: sampleLogs, LogFile: "app.log"})
+	assert.Equal(t, []location{{file: "app.log", start: 2, end: 3, lines: []string{
+		"10:00:01 ERROR pool exhausted <script>alert(1)</script>", "10:00:02 ERROR timeout",
+	}}}, single.resolve([]LineRange{{Start: 2, End: 3}}))
+
+	// Without a file name, stdin for instance, there is nothing to point at
+	assert.Empty(t, newLocator(sampleResult(), Options{Logs: sampleLogs}).resolve([]LineRange{{Start: 2, End: 3}}))
+
+	// Merged input is mapped back to each file
+	merged := &AnalysisResult{Sources: []SourceBreakdown{{Name: "api"}, {Name: "api-gw"}}}
+	logs := "[api] one\n[api-gw] two\n[api-gw] three\n[api] four\n"
+	assert.Equal(t, []location{
+		{file: "api-gw", start: 1, end: 2, lines: []string{"two", "three"}},
+		{file: "api", start: 2, end: 2, lines: []string{"four"}},
+	}, newLocator(merged, Options{Logs: logs}).resolve([]LineRange{{Start: 2, End: 4}}))
+
+	// Logs cut to a window by the server are mapped through the line map
+	windowed := &AnalysisResult{LineMap: []LineSpan{{Start: 1, End: 2, Line: 2}}}
+	assert.Equal(t, []location{{file: "app.log", start: 3, end: 3, lines: []string{"10:00:02 ERROR timeout"}}},
+		newLocator(windowed, Options{Logs: sampleLogs, LogFile: "app.log"}).resolve([]LineRange{{Start: 2, End: 2}}))
+
+	// Files merged by the server are named in the line map
+	mapped := &AnalysisResult{LineMap: []LineSpan{
+		{Start: 1, End: 1, Source: "db.log", Line: 7},
+		{Start: 2, End: 3, Source: "api.log", Line: 40},
+	}}
+	assert.Equal(t, []location{
+		{file: "db.log", start: 7, end: 7},
+		{file: "api.log", start: 40, end: 41},
+	}, newLocator(mapped, Options{}).resolve([]LineRange{{Start: 1, End: 3}}))
+}
+
+func TestSARIFRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r, _ := NewRenderer(FormatSARIF)
+	require.NoError(t, r.Render(&buf, sampleResult(), Options{Logs: sampleLogs, LogFile: "./logs/app.log"}))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct{ ID string } `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string } `json:"artifactLocation"`
+						Region           struct {
+							StartLine int `json:"startLine"`
+							EndLine   int `json:"endLine"`
+							Snippet   struct{ Text string }
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, 2)
+	require.Len(t, log.Runs[0].Results, 2)
+
+	res := log.Runs[0].Results[1]
+	assert.Equal(t, "cause/1", res.RuleID)
+	assert.Equal(t, "error", res.Level)
+	require.Len(t, res.Locations, 1)
+	loc := res.Locations[0].PhysicalLocation
+	assert.Equal(t, "logs/app.log", loc.ArtifactLocation.URI)
+	assert.Equal(t, 2, loc.Region.StartLine)
+	assert.Equal(t, 3, loc.Region.EndLine)
+	assert.Contains(t, loc.Region.Snippet.Text, "<script>")
+}
+
+func TestJUnitRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r, _ := NewRenderer(FormatJUnit)
+	require.NoError(t, r.Render(&buf, sampleResult(), Options{Logs: sampleLogs, LogFile: "app.log"}))
+
+	var suites junitSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	assert.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 1)
+	cases := suites.Suites[0].Cases
+	require.Len(t, cases, 2)
+	assert.Equal(t, "loggar.cause", cases[1].Classname)
+	assert.Equal(t, "app.log", cases[1].File)
+	assert.Equal(t, 2, cases[1].Line)
+	assert.Equal(t, "high", cases[1].Failure.Type)
+	assert.Contains(t, cases[1].Failure.Text, "app.log:2-3\n    10:00:01 ERROR pool exhausted <script>")
+
+	// A clean analysis passes
+	buf.Reset()
+	require.NoError(t, r.Render(&buf, &AnalysisResult{}, Options{}))
+	var clean junitSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &clean))
+	assert.Equal(t, 0, clean.Failures)
+	assert.Nil(t, clean.Suites[0].Cases[0].Failure)
+}
+
+func TestGHARenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRenderer("github")
+	require.NoError(t, err)
+	require.NoError(t, r.Render(&buf, sampleResult(), Options{Logs: sampleLogs, LogFile: "app.log"}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		"::notice title=loggar%3A Connection pool exhausted::Payments failed after a timeout of 30s",
+		"::error title=Pool exhausted::Pool exhausted (2 matching lines)",
+		"::error file=app.log,line=2,endLine=3,title=Slow query | lock::Slow query | lock (80%25 confidence)%0AFix:%0A- Restart the pool: kubectl rollout restart deploy/api",
+	}, lines)
+}
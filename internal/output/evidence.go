@@ -0,0 +1,96 @@
+package output
+
+import (
+	"sort"
+	"strings"
+)
+
+// Options control how an analysis is printed
+type Options struct {
+	// ShowEvidence prints the cited log lines beneath each bullet (--show-evidence)
+	ShowEvidence bool
+	// Logs is the input, used to look up cited lines. When the result has a
+	// line map, cited lines are translated to lines of this input.
+	Logs string
+	// LogFile is the path of the analyzed file, which the SARIF, JUnit and
+	// GitHub Actions formats point at. Lines of input merged from several
+	// files are attributed to the files named in their "[name] " prefix.
+	LogFile string
+	// NoAnimation prints the result at once instead of typing it out
+	// (--no-animation, or "no_animation" in the config file). The animation
+	// is also skipped whenever stdout is not a terminal.
+	NoAnimation bool
+	// Theme colors the terminal layout; nil is the dark theme
+	Theme *Theme
+}
+
+// maxExcerptLines caps the excerpt printed beneath a single bullet
+const maxExcerptLines = 6
+
+// evidence looks up excerpts of cited log lines. A nil *evidence has none.
+type evidence struct {
+	lines   []string
+	lineMap []LineSpan
+}
+
+// newEvidence reads the input of opts for excerpts. Input merged from several
+// files by the server has no excerpts, since opts holds only one of them.
+func newEvidence(opts Options, lineMap []LineSpan) *evidence {
+	if !opts.ShowEvidence || opts.Logs == "" {
+		return nil
+	}
+	for _, s := range lineMap {
+		if s.Source != lineMap[0].Source {
+			return nil
+		}
+	}
+	logText := strings.TrimRight(strings.ReplaceAll(opts.Logs, "\r\n", "\n"), "\n")
+	return &evidence{lines: strings.Split(logText, "\n"), lineMap: lineMap}
+}
+
+// inputLine returns the file and line of the input that line n of the
+// analyzed logs came from. Without a line map the logs are the input.
+func inputLine(lineMap []LineSpan, n int) (string, int, bool) {
+	if lineMap == nil {
+		return "", n, true
+	}
+	i := sort.Search(len(lineMap), func(i int) bool { return lineMap[i].End >= n })
+	if i == len(lineMap) || n < lineMap[i].Start {
+		return "", 0, false
+	}
+	return lineMap[i].Source, lineMap[i].Line + n - lineMap[i].Start, true
+}
+
+// excerptLine is a cited log line with its 1-based number
+type excerptLine struct {
+	Number int
+	Text   string
+}
+
+// excerpt returns at most maxExcerptLines of the cited lines and how many
+// more were cited
+func (e *evidence) excerpt(ranges []LineRange) ([]excerptLine, int) {
+	if e == nil {
+		return nil, 0
+	}
+	last := len(e.lines)
+	if len(e.lineMap) > 0 {
+		last = e.lineMap[len(e.lineMap)-1].End
+	}
+	var lines []excerptLine
+	more := 0
+	for _, r := range ranges {
+		for n := max(r.Start, 1); n <= r.End && n <= last; n++ {
+			_, line, ok := inputLine(e.lineMap, n)
+			if !ok || line > len(e.lines) {
+				continue
+			}
+			if len(lines) == maxExcerptLines {
+				more++
+				continue
+			}
+			lines = append(lines, excerptLine{Number: line, Text: strings.TrimRight(e.lines[line-1], " \t\r")})
+		}
+	}
+	return lines, more
+}
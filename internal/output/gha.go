@@ -0,0 +1,56 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ghaRenderer writes GitHub Actions workflow commands, which turn findings
+// into error and warning annotations on the run and on the log files
+type ghaRenderer struct{}
+
+func (ghaRenderer) Render(w io.Writer, result *AnalysisResult, opts Options) error {
+	ew := &errWriter{w: w}
+	loc := newLocator(result, opts)
+
+	if result.Summary != "" {
+		fmt.Fprintf(ew, "::notice title=%s::%s\n", ghaProperty("loggar: "+orDefault(result.PrimaryIssue, "log analysis")), ghaData(result.Summary))
+	}
+
+	for _, f := range ciFindings(result) {
+		props := []string{"title=" + ghaProperty(truncateWidth(f.title, 120))}
+		message := f.message
+
+		// An annotation points at one place; further locations are listed
+		locs := loc.resolve(f.evidence)
+		for i, l := range locs {
+			if i == 0 {
+				props = append([]string{"file=" + ghaProperty(ciURI(l.file)), fmt.Sprintf("line=%d", l.start), fmt.Sprintf("endLine=%d", l.end)}, props...)
+				continue
+			}
+			message += fmt.Sprintf("\nAlso at %s:%d", ciURI(l.file), l.start)
+		}
+		if len(locs) == 0 && len(f.evidence) > 0 {
+			message += " (" + formatLineRanges(f.evidence) + ")"
+		}
+		if f.help != "" {
+			message += "\nFix:\n" + f.help
+		}
+		if f.helpURL != "" {
+			message += "\nRunbook: " + f.helpURL
+		}
+		fmt.Fprintf(ew, "::%s %s::%s\n", ciLevel(f.severity), strings.Join(props, ","), ghaData(message))
+	}
+	return ew.err
+}
+
+// ghaData escapes the message of a workflow command
+func ghaData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// ghaProperty escapes a property value of a workflow command
+func ghaProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
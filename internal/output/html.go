@@ -0,0 +1,163 @@
+package output
+
+import (
+	"html/template"
+	"io"
+	"strings"
+)
+
+// htmlRenderer writes a standalone HTML report with inline styles
+type htmlRenderer struct{}
+
+// htmlItem is a section bullet with its references and excerpt
+type htmlItem struct {
+	Text       string
+	Arrow      bool
+	Refs       string
+	Unverified string
+	Excerpt    []excerptLine
+	More       int
+}
+
+type htmlSection struct {
+	Title string
+	Items []htmlItem
+}
+
+func (htmlRenderer) Render(w io.Writer, result *AnalysisResult, opts Options) error {
+	ev := newEvidence(opts, result.LineMap)
+	item := func(text string, ranges []LineRange) htmlItem {
+		excerpt, more := ev.excerpt(ranges)
+		return htmlItem{Text: text, Refs: formatLineRanges(ranges), Excerpt: excerpt, More: more}
+	}
+
+	var sections []htmlSection
+	for _, section := range result.Sections {
+		s := htmlSection{Title: section.Title}
+		for idx, content := range section.Content {
+			bullet, text := splitBullet(content)
+			var ranges []LineRange
+			if idx < len(section.Evidence) {
+				ranges = section.Evidence[idx]
+			}
+			it := item(text, ranges)
+			it.Arrow = bullet == "→ "
+			if idx < len(section.Claims) && !section.Claims[idx].Verified {
+				it.Unverified = unverifiedText(section.Claims[idx])
+			}
+			s.Items = append(s.Items, it)
+		}
+		sections = append(sections, s)
+	}
+
+	var known []htmlItem
+	for _, issue := range result.KnownIssues {
+		known = append(known, item(issue.Title, issue.Evidence))
+	}
+
+	return htmlReport.Execute(w, struct {
+		*AnalysisResult
+		SectionItems []htmlSection
+		KnownItems   []htmlItem
+	}{result, sections, known})
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"upper":  strings.ToUpper,
+	"refs":   formatLineRanges,
+	"pct":    func(c float64) int { return int(c*100 + 0.5) },
+	"join":   strings.Join,
+	"orElse": orDefault,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Log analysis{{if .PrimaryIssue}}: {{.PrimaryIssue}}{{end}}</title>
+<style>
+body{font:15px/1.55 -apple-system,BlinkMacSystemFont,"Segoe UI",Helvetica,Arial,sans-serif;max-width:960px;margin:2rem auto;padding:0 1rem;color:#1f2328;background:#fff}
+h1{font-size:1.5rem;margin-bottom:.25rem}h2{font-size:1.1rem;border-bottom:1px solid #d0d7de;padding-bottom:.25rem;margin-top:2rem}
+.badge{display:inline-block;padding:.1rem .5rem;border-radius:4px;font-size:.8rem;font-weight:600;color:#fff;background:#0969da;vertical-align:middle}
+.critical{background:#cf222e}.high{background:#bc4c00}.medium{background:#9a6700}.low{background:#1a7f37}
+.meta,.refs,footer{color:#59636e;font-size:.85rem}.summary{border-left:4px solid #d0d7de;padding:.25rem 1rem;margin:1rem 0}
+.warn{color:#9a6700;font-size:.85rem}ul{padding-left:1.25rem}li{margin:.35rem 0}li.action{list-style:"→ "}
+pre{background:#f6f8fa;border-radius:6px;padding:.5rem .75rem;overflow-x:auto;font-size:.8rem;margin:.35rem 0}
+table{border-collapse:collapse;width:100%;font-size:.9rem}th,td{border:1px solid #d0d7de;padding:.35rem .6rem;text-align:left;vertical-align:top}th{background:#f6f8fa}
+.bar{display:inline-block;height:.6rem;background:#d4a72c;border-radius:2px;vertical-align:middle}
+code{font-family:ui-monospace,SFMono-Regular,Menlo,Consolas,monospace}
+</style>
+</head>
+<body>
+<h1>{{if .Severity}}<span class="badge {{.Severity}}">{{upper .Severity}}</span> {{end}}{{or .PrimaryIssue "Log analysis"}}</h1>
+{{- if or .AffectedComponents .FirstSeen .Window}}
+<p class="meta">
+{{- if .AffectedComponents}}Affected: {{join .AffectedComponents ", "}}{{end}}
+{{- if .FirstSeen}}{{if .AffectedComponents}} · {{end}}First seen: <code>{{.FirstSeen}}</code>{{end}}
+{{- with .Window}} · Window: {{orElse .Start "start"}} → {{orElse .End "end"}}{{if .Auto}} (error burst){{end}}{{end}}
+</p>
+{{- end}}
+{{- if .Summary}}
+<p class="summary">{{.Summary}}</p>
+{{- end}}
+{{- define "item"}}{{.Text}}{{if .Refs}} <span class="refs">({{.Refs}})</span>{{end}}
+{{- if .Unverified}}<div class="warn">{{.Unverified}}</div>{{end}}
+{{- if .Excerpt}}<pre>{{range .Excerpt}}{{printf "%5d" .Number}} │ {{.Text}}
+{{end}}{{if .More}}… {{.More}} more lines{{end}}</pre>{{end}}
+{{- end}}
+{{- if .KnownIssues}}
+<h2>Known issues</h2>
+<ul>
+{{- range $i, $issue := .KnownIssues}}
+<li>{{if .Severity}}<span class="badge {{.Severity}}">{{upper .Severity}}</span> {{end}}{{template "item" index $.KnownItems $i}}, {{.Count}} matching lines
+{{- if .Remediation}}<br>Fix: {{.Remediation}}{{end}}
+{{- if .RunbookURL}}<br><a href="{{.RunbookURL}}">Runbook</a>{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- range .SectionItems}}
+<h2>{{.Title}}</h2>
+<ul>
+{{- range .Items}}
+<li{{if .Arrow}} class="action"{{end}}>{{template "item" .}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Causes}}
+<h2>Likely causes</h2>
+<table>
+<tr><th>Confidence</th><th>Cause</th><th>Evidence</th></tr>
+{{- range .Causes}}
+<tr><td>{{pct .Confidence}}% <span class="bar" style="width:{{pct .Confidence}}px"></span></td><td>{{.Cause}}</td><td>{{refs .Evidence}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Sources}}
+<h2>Sources</h2>
+<table>
+<tr><th>Source</th><th>Lines</th><th>Errors</th><th>Warnings</th><th>From</th><th>To</th><th>Summary</th></tr>
+{{- range .Sources}}
+<tr><td><code>{{.Name}}</code></td><td>{{.Lines}}</td><td>{{.Errors}}</td><td>{{.Warnings}}</td><td>{{.First}}</td><td>{{.Last}}</td><td>{{.Summary}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Timeline}}
+<h2>Timeline</h2>
+<table>
+<tr><th>Time</th><th>Component</th><th>Event</th><th>Evidence</th></tr>
+{{- range .Timeline}}
+<tr><td><code>{{.Time}}</code></td><td>{{.Component}}</td><td>{{.Event}}</td><td>{{refs .Evidence}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Actions}}
+<h2>Recommended actions</h2>
+<ol>
+{{- range .Actions}}
+<li>{{.Description}}{{if .Command}}<pre><code>{{.Command}}</code></pre>{{end}}</li>
+{{- end}}
+</ol>
+{{- end}}
+<footer><p>loggar v1.0.0</p></footer>
+</body>
+</html>
+`))
@@ -0,0 +1,110 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// junitRenderer writes JUnit XML with one failed test case per finding, the
+// test report format understood by Jenkins, GitLab, CircleCI and most others
+type junitRenderer struct{}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+func (junitRenderer) Render(w io.Writer, result *AnalysisResult, opts Options) error {
+	loc := newLocator(result, opts)
+	suite := junitSuite{Name: "loggar: " + orDefault(result.PrimaryIssue, "log analysis")}
+	for _, p := range []junitProperty{{"severity", result.Severity}, {"summary", result.Summary}} {
+		if p.Value != "" {
+			suite.Properties = append(suite.Properties, p)
+		}
+	}
+
+	for _, f := range ciFindings(result) {
+		tc := junitCase{
+			Name:      truncateWidth(f.title, 120),
+			Classname: "loggar." + strings.SplitN(f.rule, "/", 2)[0],
+			Failure:   &junitFailure{Message: f.message, Type: orDefault(f.severity, "error")},
+		}
+
+		var details []string
+		locs := loc.resolve(f.evidence)
+		for i, l := range locs {
+			if i == 0 {
+				tc.File, tc.Line = ciURI(l.file), l.start
+			}
+			ref := fmt.Sprintf("%s:%d", ciURI(l.file), l.start)
+			if l.end > l.start {
+				ref += fmt.Sprintf("-%d", l.end)
+			}
+			details = append(details, ref)
+			for _, line := range l.lines {
+				details = append(details, "    "+line)
+			}
+		}
+		if len(locs) == 0 && len(f.evidence) > 0 {
+			details = append(details, "Evidence: "+formatLineRanges(f.evidence))
+		}
+		if f.help != "" {
+			details = append(details, "", "Fix:", f.help)
+		}
+		if f.helpURL != "" {
+			details = append(details, "Runbook: "+f.helpURL)
+		}
+		tc.Failure.Text = strings.TrimLeft(strings.Join(details, "\n"), "\n")
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	// A clean analysis is a passing test rather than an empty report
+	suite.Tests, suite.Failures = len(suite.Cases), len(suite.Cases)
+	if len(suite.Cases) == 0 {
+		suite.Cases = append(suite.Cases, junitCase{Name: "no problems found", Classname: "loggar.summary"})
+		suite.Tests = 1
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Name: "loggar", Tests: suite.Tests, Failures: suite.Failures, Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,34 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package output
+
+import "os"
+
+// onKeypress is not supported on this platform; the animation runs to the end
+func onKeypress(in *os.File, pressed func()) (stop func()) {
+	return func() {}
+}
+
+// readKeys sends the bytes read from in until the returned stop function is
+// called. A pending read cannot be interrupted on this platform, but nothing
+// read after stop is delivered.
+func readKeys(in *os.File) (keys <-chan []byte, stop func()) {
+	out := make(chan []byte)
+	done := make(chan struct{})
+	go func() {
+		defer close(out)
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				return
+			}
+			select {
+			case out <- append([]byte(nil), buf[:n]...):
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, func() { close(done) }
+}
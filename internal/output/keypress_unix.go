@@ -0,0 +1,118 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package output
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// onKeypress calls pressed once when a key is pressed on the terminal in,
+// until the returned stop function is called. The terminal is switched to
+// unbuffered, non-echoing input meanwhile so a single key is enough and does
+// not show up in the output. It does nothing when in is not a terminal, e.g.
+// when the logs are piped on stdin.
+func onKeypress(in *os.File, pressed func()) (stop func()) {
+	fd := int(in.Fd())
+	old, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return func() {}
+	}
+	raw := *old
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, &raw); err != nil {
+		return func() {}
+	}
+	restore := func() { unix.IoctlSetTermios(fd, ioctlWriteTermios, old) }
+
+	// Restore the terminal before an interrupt ends the process
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, unix.SIGTERM)
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-interrupt:
+				restore()
+				signal.Stop(interrupt)
+				unix.Kill(os.Getpid(), sig.(unix.Signal))
+				return
+			default:
+			}
+			n, err := unix.Poll(fds, 50)
+			if err != nil && err != unix.EINTR {
+				return
+			}
+			if n > 0 && fds[0].Revents&unix.POLLIN != 0 {
+				var key [16]byte
+				unix.Read(fd, key[:])
+				pressed()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+		signal.Stop(interrupt)
+		restore()
+	}
+}
+
+// readKeys sends the bytes typed on the terminal in until the returned stop
+// function is called. It polls instead of blocking in read, so once stop
+// returns nothing more is read from in.
+func readKeys(in *os.File) (keys <-chan []byte, stop func()) {
+	fd := int(in.Fd())
+	out := make(chan []byte)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer close(out)
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		buf := make([]byte, 256)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			n, err := unix.Poll(fds, 50)
+			if err != nil && err != unix.EINTR {
+				return
+			}
+			if n <= 0 || fds[0].Revents == 0 {
+				continue
+			}
+			n, err = unix.Read(fd, buf)
+			if err == unix.EINTR || err == unix.EAGAIN {
+				continue
+			}
+			if err != nil || n <= 0 {
+				return
+			}
+			select {
+			case out <- append([]byte(nil), buf[:n]...):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return out, func() {
+		close(done)
+		<-finished
+	}
+}
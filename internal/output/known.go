@@ -0,0 +1,40 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// knownIssues writes the KNOWN ISSUES section: rule matches with their
+// remediation and runbook link
+func (t *terminal) knownIssues(issues []KnownIssue) {
+	t.style(t.theme.known...).Fprintln(t.w, "KNOWN ISSUES")
+	for _, issue := range issues {
+		badge, _ := t.severityStyle(issue.Severity)
+		t.style(t.theme.accent...).Fprint(t.w, "● ")
+		if issue.Severity != "" {
+			badge.Fprint(t.w, strings.ToUpper(issue.Severity))
+			fmt.Fprint(t.w, " ")
+		}
+
+		text := fmt.Sprintf("%s, %d matching lines", issue.Title, issue.Count)
+		if t.ev == nil {
+			if refs := formatLineRanges(issue.Evidence); refs != "" {
+				text += " (" + refs + ")"
+			}
+		}
+		for _, line := range wrapText(t.highlight(text), t.width-len(issue.Severity)-4, "  ") {
+			fmt.Fprintln(t.w, line)
+		}
+		t.excerpt(issue.Evidence)
+
+		if issue.Remediation != "" {
+			for _, line := range wrapText("Fix: "+issue.Remediation, t.width-3, "    ") {
+				t.style(t.theme.fix...).Fprintln(t.w, "  "+line)
+			}
+		}
+		if issue.RunbookURL != "" {
+			t.style(t.theme.link...).Fprintln(t.w, "  Runbook: "+issue.RunbookURL)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markdownRenderer writes GitHub-flavored Markdown for pasting into tickets and PRs
+type markdownRenderer struct{}
+
+func (markdownRenderer) Render(w io.Writer, result *AnalysisResult, opts Options) error {
+	ew := &errWriter{w: w}
+	ev := newEvidence(opts, result.LineMap)
+
+	fmt.Fprintln(ew, "## Log analysis")
+	fmt.Fprintln(ew)
+
+	if result.Severity != "" || result.PrimaryIssue != "" {
+		var head []string
+		if result.Severity != "" {
+			head = append(head, "**"+strings.ToUpper(result.Severity)+"**")
+		}
+		if result.PrimaryIssue != "" {
+			head = append(head, mdInline(result.PrimaryIssue))
+		}
+		fmt.Fprintln(ew, strings.Join(head, " · "))
+		fmt.Fprintln(ew)
+
+		var meta []string
+		if len(result.AffectedComponents) > 0 {
+			meta = append(meta, "**Affected:** "+mdInline(strings.Join(result.AffectedComponents, ", ")))
+		}
+		if result.FirstSeen != "" {
+			meta = append(meta, "**First seen:** `"+result.FirstSeen+"`")
+		}
+		if w := result.Window; w != nil {
+			text := "**Window:** " + orDefault(w.Start, "start") + " → " + orDefault(w.End, "end")
+			if w.Auto {
+				text += " (error burst)"
+			}
+			meta = append(meta, text)
+		}
+		if len(meta) > 0 {
+			fmt.Fprintln(ew, strings.Join(meta, " · "))
+			fmt.Fprintln(ew)
+		}
+	}
+
+	if result.Summary != "" {
+		fmt.Fprintln(ew, "> "+mdInline(result.Summary))
+		fmt.Fprintln(ew)
+	}
+
+	if len(result.KnownIssues) > 0 {
+		fmt.Fprintln(ew, "### Known issues")
+		fmt.Fprintln(ew)
+		for _, issue := range result.KnownIssues {
+			text := mdInline(issue.Title) + fmt.Sprintf(", %d matching lines", issue.Count)
+			if issue.Severity != "" {
+				text = "**" + strings.ToUpper(issue.Severity) + "** " + text
+			}
+			fmt.Fprintln(ew, "- "+text+mdRefs(issue.Evidence))
+			if issue.Remediation != "" {
+				fmt.Fprintln(ew, "  - Fix: "+mdInline(issue.Remediation))
+			}
+			if issue.RunbookURL != "" {
+				fmt.Fprintf(ew, "  - Runbook: <%s>\n", issue.RunbookURL)
+			}
+			mdExcerpt(ew, ev, issue.Evidence)
+		}
+		fmt.Fprintln(ew)
+	}
+
+	for _, section := range result.Sections {
+		fmt.Fprintln(ew, "### "+mdInline(section.Title))
+		fmt.Fprintln(ew)
+		for idx, item := range section.Content {
+			bullet, text := splitBullet(item)
+			marker := "-"
+			if bullet == "→ " {
+				marker = "- →"
+			}
+			var ranges []LineRange
+			if idx < len(section.Evidence) {
+				ranges = section.Evidence[idx]
+			}
+			fmt.Fprintln(ew, marker+" "+mdInline(text)+mdRefs(ranges))
+			if idx < len(section.Claims) && !section.Claims[idx].Verified {
+				fmt.Fprintln(ew, "  - _"+mdInline(unverifiedText(section.Claims[idx]))+"_")
+			}
+			mdExcerpt(ew, ev, ranges)
+		}
+		fmt.Fprintln(ew)
+	}
+
+	if len(result.Causes) > 0 {
+		fmt.Fprintln(ew, "### Likely causes")
+		fmt.Fprintln(ew)
+		fmt.Fprintln(ew, "| Confidence | Cause | Evidence |")
+		fmt.Fprintln(ew, "|-----------:|-------|----------|")
+		for _, cause := range result.Causes {
+			fmt.Fprintf(ew, "| %d%% | %s | %s |\n", int(cause.Confidence*100+0.5), mdCell(cause.Cause), formatLineRanges(cause.Evidence))
+		}
+		fmt.Fprintln(ew)
+	}
+
+	if len(result.Sources) > 0 {
+		fmt.Fprintln(ew, "### Sources")
+		fmt.Fprintln(ew)
+		fmt.Fprintln(ew, "| Source | Lines | Errors | Warnings | From | To | Summary |")
+		fmt.Fprintln(ew, "|--------|------:|-------:|---------:|------|----|---------|")
+		for _, src := range result.Sources {
+			fmt.Fprintf(ew, "| %s | %d | %d | %d | %s | %s | %s |\n", mdCell(src.Name), src.Lines, src.Errors, src.Warnings,
+				src.First, src.Last, mdCell(src.Summary))
+		}
+		fmt.Fprintln(ew)
+	}
+
+	if len(result.Timeline) > 0 {
+		fmt.Fprintln(ew, "### Timeline")
+		fmt.Fprintln(ew)
+		fmt.Fprintln(ew, "| Time | Component | Event | Evidence |")
+		fmt.Fprintln(ew, "|------|-----------|-------|----------|")
+		for _, event := range result.Timeline {
+			fmt.Fprintf(ew, "| %s | %s | %s | %s |\n", mdCell(event.Time), mdCell(event.Component), mdCell(event.Event), formatLineRanges(event.Evidence))
+		}
+		fmt.Fprintln(ew)
+	}
+
+	if len(result.Actions) > 0 {
+		fmt.Fprintln(ew, "### Recommended actions")
+		fmt.Fprintln(ew)
+		for i, action := range result.Actions {
+			fmt.Fprintf(ew, "%d. %s\n", i+1, mdInline(action.Description))
+			if action.Command != "" {
+				fmt.Fprintln(ew, "   ```sh")
+				fmt.Fprintln(ew, "   "+action.Command)
+				fmt.Fprintln(ew, "   ```")
+			}
+		}
+		fmt.Fprintln(ew)
+	}
+
+	fmt.Fprintln(ew, "<sub>loggar v1.0.0</sub>")
+	return ew.err
+}
+
+// mdRefs renders evidence references as a suffix, e.g. " _(lines 3-5)_"
+func mdRefs(ranges []LineRange) string {
+	if refs := formatLineRanges(ranges); refs != "" {
+		return " _(" + refs + ")_"
+	}
+	return ""
+}
+
+// mdExcerpt writes the cited lines as an indented code block
+func mdExcerpt(w io.Writer, ev *evidence, ranges []LineRange) {
+	lines, more := ev.excerpt(ranges)
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "  ```text")
+	for _, l := range lines {
+		fmt.Fprintf(w, "  %5d | %s\n", l.Number, strings.ReplaceAll(l.Text, "```", "ˋˋˋ"))
+	}
+	if more > 0 {
+		fmt.Fprintf(w, "  … %d more lines\n", more)
+	}
+	fmt.Fprintln(w, "  ```")
+}
+
+var mdEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "<", "&lt;", ">", "&gt;", "[", `\[`, "]", `\]`)
+
+// mdInline escapes text so model output cannot inject Markdown or HTML
+func mdInline(text string) string {
+	return mdEscaper.Replace(strings.Join(strings.Fields(text), " "))
+}
+
+// mdCell escapes text for a table cell
+func mdCell(text string) string {
+	return strings.ReplaceAll(mdInline(text), "|", `\|`)
+}
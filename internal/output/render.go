@@ -0,0 +1,145 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Output formats selected with --format
+const (
+	FormatTerminal = "terminal"
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+	FormatSARIF    = "sarif"
+	FormatJUnit    = "junit"
+	FormatGHA      = "gha"
+)
+
+// Renderer writes an analysis in one output format
+type Renderer interface {
+	Render(w io.Writer, result *AnalysisResult, opts Options) error
+}
+
+var renderers = map[string]func() Renderer{
+	FormatTerminal: func() Renderer { return &terminalRenderer{color: colorAllowed()} },
+	FormatPlain:    func() Renderer { return &terminalRenderer{} },
+	FormatMarkdown: func() Renderer { return markdownRenderer{} },
+	FormatHTML:     func() Renderer { return htmlRenderer{} },
+	FormatJSON:     func() Renderer { return jsonRenderer{} },
+	FormatSARIF:    func() Renderer { return sarifRenderer{} },
+	FormatJUnit:    func() Renderer { return junitRenderer{} },
+	FormatGHA:      func() Renderer { return ghaRenderer{} },
+}
+
+// Formats lists the supported output formats
+func Formats() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRenderer returns the renderer for a format. "md", "text" and "github" are
+// accepted as aliases of markdown, plain and gha.
+func NewRenderer(format string) (Renderer, error) {
+	switch strings.ToLower(format) {
+	case "md":
+		format = FormatMarkdown
+	case "text", "txt":
+		format = FormatPlain
+	case "github", "github-actions":
+		format = FormatGHA
+	}
+	newRenderer, ok := renderers[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return newRenderer(), nil
+}
+
+// DefaultFormat is the format used without --format: the terminal style for
+// an interactive terminal that allows color, plain text otherwise (pipes,
+// files, NO_COLOR, TERM=dumb)
+func DefaultFormat(w io.Writer) string {
+	if isTerminal(w) && colorAllowed() {
+		return FormatTerminal
+	}
+	return FormatPlain
+}
+
+// isTerminal reports whether w is an interactive terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// colorAllowed honours the NO_COLOR convention (https://no-color.org) and dumb terminals
+func colorAllowed() bool {
+	if _, set := os.LookupEnv("NO_COLOR"); set {
+		return false
+	}
+	return os.Getenv("TERM") != "dumb"
+}
+
+// terminalRenderer writes the human-readable layout, with ANSI colors when
+// color is set. The plain format is the same layout without color. When
+// animate is set and w is a terminal, the result is typed out progressively;
+// pressing a key prints the rest at once.
+type terminalRenderer struct {
+	color   bool
+	animate bool
+}
+
+func (r *terminalRenderer) Render(w io.Writer, result *AnalysisResult, opts Options) error {
+	width := 100
+	if isTerminal(w) {
+		width = getTermWidth()
+	}
+	ew := &errWriter{w: w}
+	t := newTerminal(bufio.NewWriter(ew), r.color, width, opts)
+	t.ev = newEvidence(opts, result.LineMap)
+	t.animate = r.animate && !opts.NoAnimation && isTerminal(w)
+	if t.animate {
+		stop := onKeypress(os.Stdin, func() { t.skip.Store(true) })
+		defer stop()
+	}
+	t.analysis(result)
+	t.w.Flush()
+	return ew.err
+}
+
+// errWriter remembers the first write error, so renderers can write freely
+// and check once at the end
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.err = err
+	return n, err
+}
+
+// jsonRenderer writes the result as indented JSON
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(w io.Writer, result *AnalysisResult, opts Options) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
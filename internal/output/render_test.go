@@ -0,0 +1,153 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleResult() *AnalysisResult {
+	return &AnalysisResult{
+		Summary: "Payments failed after a timeout of 30s",
+		Sections: []Section{{
+			Title:    "Root cause",
+			Content:  []string{"• Database pool exhausted on <db-1>", "→ Raise *max_connections*"},
+			Evidence: [][]LineRange{{{Start: 2, End: 2}}},
+			Claims:   []Claim{{Verified: true}, {Verified: false, Unsupported: []string{"max_connections"}}},
+		}},
+		SchemaVersion: 2,
+		Severity:      "high",
+		PrimaryIssue:  "Connection pool exhausted",
+		Causes:        []Cause{{Cause: "Slow query | lock", Confidence: 0.8, Evidence: []LineRange{{Start: 2, End: 3}}}},
+		Timeline:      []TimelineEvent{{Time: "10:00:01", Component: "api", Event: "timeout"}},
+		Actions:       []Action{{Description: "Restart the pool", Command: "kubectl rollout restart deploy/api"}},
+		KnownIssues:   []KnownIssue{{Rule: "pool", Title: "Pool exhausted", Severity: "high", Count: 2}},
+	}
+}
+
+const sampleLogs = "10:00:00 INFO start\n10:00:01 ERROR pool exhausted <script>alert(1)</script>\n10:00:02 ERROR timeout\n"
+
+func TestNewRenderer(t *testing.T) {
+	for _, format := range append(Formats(), "md", "text", "TXT") {
+		r, err := NewRenderer(format)
+		require.NoError(t, err, format)
+		assert.NotNil(t, r)
+	}
+
+	_, err := NewRenderer("pdf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "markdown")
+}
+
+func TestDefaultFormat(t *testing.T) {
+	assert.Equal(t, FormatPlain, DefaultFormat(&bytes.Buffer{}))
+
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, colorAllowed())
+
+	t.Setenv("NO_COLOR", "")
+	assert.False(t, colorAllowed(), "NO_COLOR applies when set, even if empty")
+}
+
+func TestPlainRenderer(t *testing.T) {
+	r, err := NewRenderer(FormatPlain)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, r.Render(&out, sampleResult(), Options{ShowEvidence: true, Logs: sampleLogs}))
+
+	text := out.String()
+	assert.NotContains(t, text, "\x1b[")
+	assert.Contains(t, text, "ROOT CAUSE")
+	assert.Contains(t, text, "Database pool exhausted on <db-1>")
+	assert.Contains(t, text, "    2 │ 10:00:01 ERROR pool exhausted")
+	assert.Contains(t, text, "unverified: not found in logs: max_connections")
+}
+
+func TestEvidenceLineMap(t *testing.T) {
+	// The server analyzed lines 2-3 of the input as lines 1-2
+	ev := newEvidence(Options{ShowEvidence: true, Logs: sampleLogs}, []LineSpan{{Start: 1, End: 2, Line: 2}})
+	lines, more := ev.excerpt([]LineRange{{Start: 2, End: 9}})
+	assert.Equal(t, []excerptLine{{Number: 3, Text: "10:00:02 ERROR timeout"}}, lines)
+	assert.Zero(t, more)
+
+	// Only one of several merged files is known, so there are no excerpts
+	assert.Nil(t, newEvidence(Options{ShowEvidence: true, Logs: sampleLogs}, []LineSpan{
+		{Start: 1, End: 1, Source: "a.log", Line: 1}, {Start: 2, End: 2, Source: "b.log", Line: 1},
+	}))
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	r, err := NewRenderer(FormatMarkdown)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, r.Render(&out, sampleResult(), Options{ShowEvidence: true, Logs: sampleLogs}))
+
+	text := out.String()
+	assert.Contains(t, text, "**HIGH** · Connection pool exhausted")
+	assert.Contains(t, text, "> Payments failed after a timeout of 30s")
+	assert.Contains(t, text, "### Root cause")
+	assert.Contains(t, text, "- Database pool exhausted on &lt;db-1&gt; _(line 2)_")
+	assert.Contains(t, text, `- → Raise \*max\_connections\*`)
+	assert.Contains(t, text, `| 80% | Slow query \| lock | lines 2-3 |`)
+	assert.Contains(t, text, "  ```text\n      2 | 10:00:01 ERROR pool exhausted")
+	assert.Contains(t, text, "1. Restart the pool\n   ```sh\n   kubectl rollout restart deploy/api\n   ```")
+	assert.NotContains(t, text, "\x1b[")
+}
+
+func TestHTMLRenderer(t *testing.T) {
+	r, err := NewRenderer(FormatHTML)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, r.Render(&out, sampleResult(), Options{ShowEvidence: true, Logs: sampleLogs}))
+
+	page := out.String()
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	assert.Contains(t, page, "<style>")
+	assert.Contains(t, page, `<span class="badge high">HIGH</span> Connection pool exhausted`)
+	assert.Contains(t, page, "Database pool exhausted on &lt;db-1&gt;")
+	assert.Contains(t, page, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.NotContains(t, page, "<script>")
+	assert.Contains(t, page, `<li class="action">Raise *max_connections*`)
+}
+
+func TestJSONRenderer(t *testing.T) {
+	r, err := NewRenderer(FormatJSON)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, r.Render(&out, sampleResult(), Options{}))
+
+	var decoded AnalysisResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, sampleResult(), &decoded)
+}
+
+func TestTerminalAnimation(t *testing.T) {
+	// Not a terminal: animation is requested but the result is printed at once
+	var out bytes.Buffer
+	start := time.Now()
+	r := &terminalRenderer{animate: true}
+	require.NoError(t, r.Render(&out, sampleResult(), Options{}))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Contains(t, out.String(), "loggar v1.0.0")
+
+	// A keypress skips the rest of the typing
+	out.Reset()
+	term := &terminal{w: bufio.NewWriter(&out), animate: true, width: 80}
+	term.skip.Store(true)
+	start = time.Now()
+	term.typeLines([]string{"first line", "second line"}, time.Second)
+	term.pause()
+	term.w.Flush()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, "first line\nsecond line\n", out.String())
+}
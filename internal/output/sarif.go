@@ -0,0 +1,141 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// sarifRenderer writes a SARIF 2.1.0 log, which code scanning tools such as
+// GitHub, GitLab and Azure DevOps display next to the files it points at
+type sarifRenderer struct{}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool              `json:"tool"`
+	Results    []sarifResult          `json:"results"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	ShortDescription sarifMessage  `json:"shortDescription"`
+	Help             *sarifMessage `json:"help,omitempty"`
+	HelpURI          string        `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int           `json:"startLine"`
+	EndLine   int           `json:"endLine"`
+	Snippet   *sarifMessage `json:"snippet,omitempty"`
+}
+
+// sarifLevels maps ciLevel to SARIF result levels
+var sarifLevels = map[string]string{"error": "error", "warning": "warning", "notice": "note"}
+
+func (sarifRenderer) Render(w io.Writer, result *AnalysisResult, opts Options) error {
+	loc := newLocator(result, opts)
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "loggar",
+			Version:        "1.0.0",
+			InformationURI: "https://loggar.dev",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]int)
+	for _, f := range ciFindings(result) {
+		index, ok := rules[f.rule]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			rules[f.rule] = index
+			rule := sarifRule{ID: f.rule, ShortDescription: sarifMessage{f.title}, HelpURI: f.helpURL}
+			if f.help != "" {
+				rule.Help = &sarifMessage{f.help}
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		res := sarifResult{RuleID: f.rule, RuleIndex: index, Level: sarifLevels[ciLevel(f.severity)], Message: sarifMessage{f.message}}
+		for _, l := range loc.resolve(f.evidence) {
+			region := sarifRegion{StartLine: l.start, EndLine: l.end}
+			if len(l.lines) > 0 {
+				region.Snippet = &sarifMessage{strings.Join(l.lines, "\n")}
+			}
+			res.Locations = append(res.Locations, sarifLocation{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifact{ciURI(l.file)},
+				Region:           region,
+			}})
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	properties := map[string]interface{}{}
+	for key, value := range map[string]string{"summary": result.Summary, "severity": result.Severity, "primaryIssue": result.PrimaryIssue} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+	if len(properties) > 0 {
+		run.Properties = properties
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// ciURI writes a file path with forward slashes and without a leading "./",
+// the way CI systems match annotations to files in the repository
+func ciURI(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	return strings.TrimPrefix(path, "./")
+}
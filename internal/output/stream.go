@@ -0,0 +1,125 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// StreamPrinter renders an analysis progressively as it arrives from
+// /api/analyze/stream, instead of replaying a finished result with typing delays
+type StreamPrinter struct {
+	t              *terminal
+	width          int
+	col            int
+	word           strings.Builder
+	summaryStarted bool
+	summaryDone    bool
+	knownPrinted   bool
+}
+
+// NewStreamPrinter creates a printer sized to the current terminal. Color
+// follows the same rules as PrintAnalysis. Output is flushed at the end of
+// every call, so each chunk reaches the terminal as soon as it arrives.
+func NewStreamPrinter(opts Options) *StreamPrinter {
+	t := newTerminal(bufio.NewWriter(os.Stdout), DefaultFormat(os.Stdout) == FormatTerminal, getTermWidth(), opts)
+	return &StreamPrinter{t: t, width: t.width - 3}
+}
+
+// SummaryText prints the next chunk of summary text, wrapping on word boundaries
+func (p *StreamPrinter) SummaryText(text string) {
+	defer p.t.w.Flush()
+	if p.summaryDone {
+		return
+	}
+	if !p.summaryStarted {
+		p.summaryStarted = true
+		fmt.Fprintln(p.t.w)
+		p.t.style(color.FgWhite).Fprint(p.t.w, "💡 ")
+		p.col = 0
+	}
+
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			p.flushWord()
+			continue
+		}
+		p.word.WriteRune(r)
+	}
+}
+
+// KnownIssues prints the KNOWN ISSUES section. Clients that match rules locally
+// can call it before the first section arrives; otherwise Finish prints the
+// known issues of the final result.
+func (p *StreamPrinter) KnownIssues(issues []KnownIssue) {
+	defer p.t.w.Flush()
+	if p.knownPrinted || len(issues) == 0 {
+		return
+	}
+	p.endSummary()
+	p.knownPrinted = true
+	p.t.knownIssues(issues)
+	fmt.Fprintln(p.t.w)
+}
+
+// Section prints a completed section
+func (p *StreamPrinter) Section(section Section) {
+	defer p.t.w.Flush()
+	p.endSummary()
+	p.t.section(section, 0)
+	fmt.Fprintln(p.t.w)
+}
+
+// Finish ends the output once the stream is complete. Typed fields of a v2
+// result only arrive with the final event, so they are printed here.
+func (p *StreamPrinter) Finish(result *AnalysisResult) {
+	defer p.t.w.Flush()
+	p.endSummary()
+	if result != nil {
+		p.KnownIssues(result.KnownIssues)
+	}
+	if result != nil && p.t.structured(result, 0) {
+		fmt.Fprintln(p.t.w)
+	}
+	p.t.footer()
+}
+
+// flushWord prints the buffered word, starting a new indented line if it does not fit
+func (p *StreamPrinter) flushWord() {
+	if p.word.Len() == 0 {
+		return
+	}
+	word := p.word.String()
+	p.word.Reset()
+
+	wordWidth := displayWidth(word)
+	if p.col > 0 && p.col+1+wordWidth > p.width {
+		fmt.Fprint(p.t.w, "\n   ")
+		p.col = 0
+	} else if p.col > 0 {
+		fmt.Fprint(p.t.w, " ")
+		p.col++
+	}
+
+	fmt.Fprint(p.t.w, p.t.highlight(word))
+	p.col += wordWidth
+}
+
+func (p *StreamPrinter) endSummary() {
+	if !p.summaryStarted {
+		fmt.Fprintln(p.t.w)
+		p.summaryStarted = true
+	}
+	if p.summaryDone {
+		return
+	}
+	p.summaryDone = true
+	p.flushWord()
+	if p.col > 0 {
+		fmt.Fprintln(p.t.w)
+	}
+}
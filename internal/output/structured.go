@@ -0,0 +1,178 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// severityStyle maps a v2 severity to its badge color and icon
+func (t *terminal) severityStyle(severity string) (*color.Color, string) {
+	switch severity {
+	case "critical":
+		return t.style(t.theme.severity["critical"]...), "🔴"
+	case "high":
+		return t.style(t.theme.severity["high"]...), "🟠"
+	case "medium":
+		return t.style(t.theme.severity["medium"]...), "🟡"
+	case "low":
+		return t.style(t.theme.severity["low"]...), "🟢"
+	default:
+		return t.style(t.theme.severity[""]...), "🔵"
+	}
+}
+
+// structured writes the typed fields of a v2 result: severity and primary
+// issue, likely causes with confidence bars, the per-source breakdown, the
+// timeline and recommended actions. It reports whether anything was printed.
+func (t *terminal) structured(result *AnalysisResult, delay time.Duration) bool {
+	printed := false
+	titleColor := t.style(t.theme.title...)
+	dim := t.style(t.theme.dim...)
+
+	if result.Severity != "" || result.PrimaryIssue != "" {
+		badge, icon := t.severityStyle(result.Severity)
+		fmt.Fprint(t.w, icon+" ")
+		if result.Severity != "" {
+			badge.Fprint(t.w, " "+strings.ToUpper(result.Severity)+" ")
+			fmt.Fprint(t.w, " ")
+		}
+		t.typeLines(wrapText(result.PrimaryIssue, t.width-15, "   "), delay)
+
+		var meta []string
+		if len(result.AffectedComponents) > 0 {
+			meta = append(meta, "Affected: "+strings.Join(result.AffectedComponents, ", "))
+		}
+		if result.FirstSeen != "" {
+			meta = append(meta, "First seen: "+result.FirstSeen)
+		}
+		if w := result.Window; w != nil {
+			text := "Window: " + orDefault(w.Start, "start") + " → " + orDefault(w.End, "end")
+			if w.Auto {
+				text += " (error burst)"
+			}
+			meta = append(meta, text)
+		}
+		if len(meta) > 0 {
+			for _, line := range wrapText(strings.Join(meta, " · "), t.width-3, "   ") {
+				dim.Fprintln(t.w, "   "+strings.TrimLeft(line, " "))
+			}
+		}
+		fmt.Fprintln(t.w)
+		printed = true
+	}
+
+	if len(result.Causes) > 0 {
+		titleColor.Fprintln(t.w, "LIKELY CAUSES")
+		for _, cause := range result.Causes {
+			pct := int(cause.Confidence*100 + 0.5)
+			t.style(t.theme.bullet...).Fprintf(t.w, "%3d%% ", pct)
+			fmt.Fprint(t.w, t.confidenceBar(cause.Confidence, 10)+"  ")
+
+			text := cause.Cause
+			if refs := formatLineRanges(cause.Evidence); refs != "" {
+				text += " (" + refs + ")"
+			}
+			t.typeLines(wrapText(t.highlight(text), t.width-18, strings.Repeat(" ", 17)), delay)
+			t.excerpt(cause.Evidence)
+		}
+		fmt.Fprintln(t.w)
+		printed = true
+	}
+
+	if len(result.Sources) > 0 {
+		titleColor.Fprintln(t.w, "SOURCES")
+		for _, src := range result.Sources {
+			counts := t.style(t.theme.bullet...)
+			if src.Errors > 0 {
+				counts = t.style(t.theme.arrow...)
+			}
+			t.style(t.theme.label...).Fprint(t.w, src.Name)
+			counts.Fprintf(t.w, "  %d lines, %d errors, %d warnings", src.Lines, src.Errors, src.Warnings)
+			if src.First != "" {
+				dim.Fprintf(t.w, "  %s → %s", src.First, src.Last)
+			}
+			fmt.Fprintln(t.w)
+			if src.Summary != "" {
+				fmt.Fprint(t.w, "  ")
+				t.typeLines(wrapText(t.highlight(src.Summary), t.width-3, "  "), delay)
+			}
+		}
+		fmt.Fprintln(t.w)
+		printed = true
+	}
+
+	if len(result.Timeline) > 0 {
+		titleColor.Fprintln(t.w, "TIMELINE")
+		for _, event := range result.Timeline {
+			if event.Time != "" {
+				t.style(t.theme.accent...).Fprint(t.w, event.Time+"  ")
+			}
+			text := event.Event
+			if event.Component != "" {
+				text = "[" + event.Component + "] " + text
+			}
+			t.typeLines(wrapText(t.highlight(text), t.width-displayWidth(event.Time)-5, "  "), delay)
+			t.excerpt(event.Evidence)
+		}
+		fmt.Fprintln(t.w)
+		printed = true
+	}
+
+	if len(result.Actions) > 0 {
+		titleColor.Fprintln(t.w, "RECOMMENDED ACTIONS")
+		for _, action := range result.Actions {
+			t.style(t.theme.arrow...).Fprint(t.w, "→ ")
+			t.typeLines(wrapText(t.highlight(action.Description), t.width-3, "  "), delay)
+			if action.Command != "" {
+				t.style(t.theme.fix...).Fprintln(t.w, "  $ "+action.Command)
+			}
+		}
+		printed = true
+	}
+
+	return printed
+}
+
+func orDefault(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// confidenceBar draws a confidence between 0 and 1 as a fixed-width bar
+func (t *terminal) confidenceBar(confidence float64, width int) string {
+	filled := int(confidence*float64(width) + 0.5)
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > width {
+		filled = width
+	}
+	return t.style(t.theme.bullet...).Sprint(strings.Repeat("█", filled)) +
+		t.style(t.theme.dim...).Sprint(strings.Repeat("░", width-filled))
+}
+
+// formatLineRanges renders evidence references as "line 4" or "lines 12-14, 20"
+func formatLineRanges(ranges []LineRange) string {
+	if len(ranges) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(ranges))
+	single := len(ranges) == 1
+	for _, r := range ranges {
+		if r.End <= r.Start {
+			parts = append(parts, fmt.Sprint(r.Start))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", r.Start, r.End))
+			single = false
+		}
+	}
+	if single {
+		return "line " + parts[0]
+	}
+	return "lines " + strings.Join(parts, ", ")
+}
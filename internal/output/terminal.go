@@ -0,0 +1,229 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// terminal writes the human-readable layout: colored when color is set, and
+// typed out progressively when animate is set. Output is buffered; the
+// animation flushes as it types, everyone else flushes when done.
+type terminal struct {
+	w       *bufio.Writer
+	color   bool
+	animate bool
+	width   int
+	ev      *evidence
+	theme   *Theme
+
+	// skip is set by a keypress to print the rest of the animation at once
+	skip atomic.Bool
+}
+
+// animating reports whether output is still being typed out
+func (t *terminal) animating() bool {
+	return t.animate && !t.skip.Load()
+}
+
+// newTerminal creates a printer with the options' evidence and theme
+func newTerminal(w *bufio.Writer, color bool, width int, opts Options) *terminal {
+	theme := opts.Theme
+	if theme == nil {
+		theme = defaultTheme()
+	}
+	return &terminal{w: w, color: color, width: width, ev: newEvidence(opts, nil), theme: theme}
+}
+
+// style returns a color that is only applied when the terminal has color
+func (t *terminal) style(attrs ...color.Attribute) *color.Color {
+	c := color.New(attrs...)
+	if t.color && len(attrs) > 0 {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	return c
+}
+
+// analysis writes a complete result
+func (t *terminal) analysis(result *AnalysisResult) {
+	fmt.Fprintln(t.w)
+
+	// Summary
+	if result.Summary != "" {
+		fmt.Fprint(t.w, "💡 ")
+		t.typeLines(wrapText(t.highlight(result.Summary), t.width-3, "   "), 5*time.Millisecond)
+		t.pause()
+	}
+
+	// Known issues from detection rules
+	if len(result.KnownIssues) > 0 {
+		t.knownIssues(result.KnownIssues)
+		t.pause()
+		fmt.Fprintln(t.w)
+	}
+
+	// Dynamic Sections
+	for _, section := range result.Sections {
+		t.section(section, 15*time.Millisecond)
+		t.pause()
+		fmt.Fprintln(t.w)
+	}
+
+	// Typed fields (v2 schema)
+	if t.structured(result, 15*time.Millisecond) {
+		t.pause()
+		fmt.Fprintln(t.w)
+	}
+
+	t.footer()
+}
+
+// typeLines writes wrapped lines, character by character with the given
+// delay when animating
+func (t *terminal) typeLines(lines []string, delay time.Duration) {
+	for i, line := range lines {
+		if delay == 0 || !t.animating() {
+			t.w.WriteString(line)
+		} else {
+			for j := 0; j < len(line); {
+				if !t.animating() {
+					t.w.WriteString(line[j:])
+					break
+				}
+				// Color sequences are written at once, only visible text is typed
+				if line[j] == '\x1b' {
+					if loc := sgrPattern.FindStringIndex(line[j:]); loc != nil && loc[0] == 0 {
+						t.w.WriteString(line[j : j+loc[1]])
+						j += loc[1]
+						continue
+					}
+				}
+				r, size := utf8.DecodeRuneInString(line[j:])
+				t.w.WriteRune(r)
+				j += size
+				t.w.Flush()
+				time.Sleep(delay)
+			}
+		}
+		// If there's a next line, print a newline.
+		// If it was the last line, the caller adds its own newlines to separate sections.
+		if i < len(lines)-1 {
+			fmt.Fprintln(t.w)
+		}
+	}
+	fmt.Fprintln(t.w)
+}
+
+// pause adds a small pause between sections when animating
+func (t *terminal) pause() {
+	if t.animating() {
+		t.w.Flush()
+		time.Sleep(150 * time.Millisecond)
+	}
+}
+
+// highlight colors patterns in the text with the theme's highlight rules
+// when the terminal has color
+func (t *terminal) highlight(text string) string {
+	if !t.color {
+		return text
+	}
+	return t.theme.highlight(text, func(s Style) *color.Color { return t.style(s...) })
+}
+
+// section writes a section title and its bullet points, typing each item with
+// the given delay, followed by the cited log lines when evidence is shown
+func (t *terminal) section(section Section, delay time.Duration) {
+	titleColor := t.style(t.theme.title...)
+	bulletColor := t.style(t.theme.bullet...)
+	arrowColor := t.style(t.theme.arrow...)
+
+	tColor := titleColor
+	if strings.Contains(strings.ToUpper(section.Title), "RESOLUTION") {
+		tColor = t.style(t.theme.resolution...)
+	}
+
+	tColor.Fprintln(t.w, strings.ToUpper(section.Title))
+
+	for idx, item := range section.Content {
+		bullet, cleanItem := splitBullet(item)
+		bColor := bulletColor
+		if bullet == "→ " {
+			bColor = arrowColor
+		}
+
+		bColor.Fprint(t.w, bullet)
+
+		// Without excerpts, keep the citations visible as line references
+		if t.ev == nil && idx < len(section.Evidence) {
+			if refs := formatLineRanges(section.Evidence[idx]); refs != "" {
+				cleanItem += " (" + refs + ")"
+			}
+		}
+
+		t.typeLines(wrapText(t.highlight(cleanItem), t.width-3, "  "), delay)
+
+		if idx < len(section.Claims) && !section.Claims[idx].Verified {
+			t.unverified(section.Claims[idx])
+		}
+		if idx < len(section.Evidence) {
+			t.excerpt(section.Evidence[idx])
+		}
+	}
+}
+
+// splitBullet separates a leading "→" or "•" marker from a section item
+func splitBullet(item string) (string, string) {
+	switch {
+	case strings.HasPrefix(item, "→"):
+		return "→ ", strings.TrimSpace(strings.TrimPrefix(item, "→"))
+	case strings.HasPrefix(item, "•"):
+		return "• ", strings.TrimSpace(strings.TrimPrefix(item, "•"))
+	default:
+		return "• ", item
+	}
+}
+
+// unverified warns that a bullet mentions identifiers that are not in the logs
+func (t *terminal) unverified(claim Claim) {
+	for _, line := range wrapText(unverifiedText(claim), t.width-3, "    ") {
+		t.style(t.theme.warning...).Fprintln(t.w, "  "+line)
+	}
+}
+
+func unverifiedText(claim Claim) string {
+	text := "⚠ unverified: not found in logs"
+	if len(claim.Unsupported) > 0 {
+		text += ": " + strings.Join(claim.Unsupported, ", ")
+	}
+	return text
+}
+
+// excerpt writes the cited lines, numbered and truncated to the terminal width
+func (t *terminal) excerpt(ranges []LineRange) {
+	lines, more := t.ev.excerpt(ranges)
+	numColor := t.style(t.theme.dim...)
+	textColor := t.style(t.theme.excerpt...)
+
+	for _, l := range lines {
+		prefix := fmt.Sprintf("    %5d │ ", l.Number)
+		numColor.Fprint(t.w, prefix)
+		textColor.Fprintln(t.w, truncateWidth(l.Text, t.width-displayWidth(prefix)))
+	}
+	if more > 0 {
+		numColor.Fprintf(t.w, "          … %d more lines\n", more)
+	}
+}
+
+// footer writes the version line that ends every analysis
+func (t *terminal) footer() {
+	t.style(t.theme.dim...).Fprintln(t.w, "loggar v1.0.0")
+	fmt.Fprintln(t.w)
+}
@@ -0,0 +1,318 @@
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Built-in themes, selected with "theme" in the config file
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeMonochrome   = "monochrome"
+)
+
+// Style is a set of terminal attributes: colors, bold, underline...
+type Style []color.Attribute
+
+// Theme assigns a style to each part of the terminal layout, and holds the
+// highlight rules applied to analysis text
+type Theme struct {
+	name string
+
+	title      Style // section titles
+	resolution Style // titles of resolution sections
+	known      Style // the KNOWN ISSUES title
+	bullet     Style // "•" markers, confidence figures and bars
+	arrow      Style // "→" action markers
+	accent     Style // known issue markers and timeline times
+	label      Style // source names
+	fix        Style // remediations and commands
+	link       Style // runbook links
+	warning    Style // notes on unverified claims
+	dim        Style // metadata, line numbers and the footer
+	excerpt    Style // cited log lines
+	severity   map[string]Style
+
+	highlights []highlightRule
+}
+
+// Name returns the theme name
+func (th *Theme) Name() string {
+	return th.name
+}
+
+// highlightRule colors every match of a precompiled pattern
+type highlightRule struct {
+	pattern *regexp.Regexp
+	style   Style
+}
+
+// Patterns of the built-in highlight rules, compiled once
+var (
+	// Durations, sizes and percentages, e.g. 104ms, 10-15s, 98%
+	quantityPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ms|s|%|kb|mb)\b`)
+	// IDs and codes, e.g. TX_9921, user_99a82, /v1/payment_intents
+	identifierPattern = regexp.MustCompile(`\b[A-Za-z0-9_/-]{4,}\d+[A-Za-z0-9_/-]*\b`)
+	quotedPattern     = regexp.MustCompile(`"[^"]+"`)
+	failurePattern    = regexp.MustCompile(`(?i)\b(timeout|failed|failure|error|critical|collapsed|prohibited|refused)\b`)
+	successPattern    = regexp.MustCompile(`(?i)\b(success|resolved|healthy|stable|ok)\b`)
+)
+
+// builtinHighlights returns the built-in highlight rules with the given styles
+func builtinHighlights(quantity, identifier, failure, success Style) []highlightRule {
+	return []highlightRule{
+		{quantityPattern, quantity},
+		{identifierPattern, identifier},
+		{quotedPattern, identifier},
+		{failurePattern, failure},
+		{successPattern, success},
+	}
+}
+
+var themes = map[string]func() *Theme{
+	// The original palette, for dark backgrounds
+	ThemeDark: func() *Theme {
+		return &Theme{
+			title:      Style{color.FgHiMagenta, color.Bold},
+			resolution: Style{color.FgHiBlue, color.Bold},
+			known:      Style{color.FgHiCyan, color.Bold},
+			bullet:     Style{color.FgHiYellow},
+			arrow:      Style{color.FgHiRed},
+			accent:     Style{color.FgHiCyan},
+			label:      Style{color.FgHiCyan, color.Bold},
+			fix:        Style{color.FgHiGreen},
+			link:       Style{color.FgHiBlue, color.Underline},
+			warning:    Style{color.FgHiYellow, color.Faint},
+			dim:        Style{color.FgHiBlack},
+			excerpt:    Style{color.FgWhite, color.Faint},
+			severity: map[string]Style{
+				"critical": {color.FgHiWhite, color.BgRed, color.Bold},
+				"high":     {color.FgHiRed, color.Bold},
+				"medium":   {color.FgHiYellow, color.Bold},
+				"low":      {color.FgHiGreen, color.Bold},
+				"":         {color.FgHiBlue, color.Bold},
+			},
+			highlights: builtinHighlights(Style{color.FgHiYellow}, Style{color.FgHiCyan}, Style{color.FgHiRed}, Style{color.FgHiGreen}),
+		}
+	},
+	// Darker colors that stay readable on white and pale backgrounds
+	ThemeLight: func() *Theme {
+		return &Theme{
+			title:      Style{color.FgMagenta, color.Bold},
+			resolution: Style{color.FgBlue, color.Bold},
+			known:      Style{color.FgCyan, color.Bold},
+			bullet:     Style{color.FgBlue},
+			arrow:      Style{color.FgRed},
+			accent:     Style{color.FgCyan},
+			label:      Style{color.FgCyan, color.Bold},
+			fix:        Style{color.FgGreen},
+			link:       Style{color.FgBlue, color.Underline},
+			warning:    Style{color.FgMagenta},
+			dim:        Style{color.FgHiBlack},
+			excerpt:    Style{color.FgBlack},
+			severity: map[string]Style{
+				"critical": {color.FgHiWhite, color.BgRed, color.Bold},
+				"high":     {color.FgRed, color.Bold},
+				"medium":   {color.FgMagenta, color.Bold},
+				"low":      {color.FgGreen, color.Bold},
+				"":         {color.FgBlue, color.Bold},
+			},
+			highlights: builtinHighlights(Style{color.FgBlue}, Style{color.FgCyan}, Style{color.FgRed, color.Bold}, Style{color.FgGreen}),
+		}
+	},
+	// Bright, bold colors that never tell things apart by red and green
+	// alone: failures are yellow and underlined, successes blue
+	ThemeHighContrast: func() *Theme {
+		return &Theme{
+			title:      Style{color.FgHiWhite, color.Bold, color.Underline},
+			resolution: Style{color.FgHiCyan, color.Bold, color.Underline},
+			known:      Style{color.FgHiCyan, color.Bold},
+			bullet:     Style{color.FgHiWhite, color.Bold},
+			arrow:      Style{color.FgHiYellow, color.Bold},
+			accent:     Style{color.FgHiCyan},
+			label:      Style{color.FgHiCyan, color.Bold},
+			fix:        Style{color.FgHiCyan},
+			link:       Style{color.FgHiCyan, color.Underline},
+			warning:    Style{color.FgHiYellow, color.Bold},
+			dim:        Style{color.FgWhite},
+			excerpt:    Style{color.FgHiWhite},
+			severity: map[string]Style{
+				"critical": {color.FgBlack, color.BgHiYellow, color.Bold},
+				"high":     {color.FgHiYellow, color.Bold, color.Underline},
+				"medium":   {color.FgHiYellow, color.Bold},
+				"low":      {color.FgHiBlue, color.Bold},
+				"":         {color.FgHiWhite, color.Bold},
+			},
+			highlights: builtinHighlights(Style{color.FgHiWhite, color.Bold}, Style{color.FgHiCyan},
+				Style{color.FgHiYellow, color.Bold, color.Underline}, Style{color.FgHiBlue, color.Bold}),
+		}
+	},
+	// No colors, only bold, underline and faint text
+	ThemeMonochrome: func() *Theme {
+		return &Theme{
+			title:      Style{color.Bold, color.Underline},
+			resolution: Style{color.Bold, color.Underline},
+			known:      Style{color.Bold},
+			bullet:     Style{},
+			arrow:      Style{color.Bold},
+			accent:     Style{color.Bold},
+			label:      Style{color.Bold},
+			fix:        Style{},
+			link:       Style{color.Underline},
+			warning:    Style{color.Italic},
+			dim:        Style{color.Faint},
+			excerpt:    Style{color.Faint},
+			severity: map[string]Style{
+				"critical": {color.ReverseVideo, color.Bold},
+				"high":     {color.Bold, color.Underline},
+				"medium":   {color.Bold},
+				"low":      {},
+				"":         {color.Bold},
+			},
+			highlights: builtinHighlights(Style{color.Bold}, Style{color.Underline}, Style{color.Bold}, Style{}),
+		}
+	},
+}
+
+// Themes lists the built-in themes
+func Themes() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Highlight is a user-defined highlight rule from the config file, e.g.
+// {"pattern": "payment-svc", "color": "hi-magenta bold"}
+type Highlight struct {
+	Pattern string
+	Color   string
+}
+
+// NewTheme returns a built-in theme ("" is dark) with the user's highlight
+// rules, which take precedence over the built-in ones
+func NewTheme(name string, highlights ...Highlight) (*Theme, error) {
+	if name == "" {
+		name = ThemeDark
+	}
+	newTheme, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(Themes(), ", "))
+	}
+	th := newTheme()
+	th.name = strings.ToLower(name)
+
+	var rules []highlightRule
+	for _, h := range highlights {
+		re, err := regexp.Compile(h.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("highlight %q: %w", h.Pattern, err)
+		}
+		style, err := ParseStyle(h.Color)
+		if err != nil {
+			return nil, fmt.Errorf("highlight %q: %w", h.Pattern, err)
+		}
+		rules = append(rules, highlightRule{re, style})
+	}
+	th.highlights = append(rules, th.highlights...)
+	return th, nil
+}
+
+// defaultTheme is used when Options.Theme is not set
+func defaultTheme() *Theme {
+	th := themes[ThemeDark]()
+	th.name = ThemeDark
+	return th
+}
+
+var styleNames = map[string]color.Attribute{
+	"black": color.FgBlack, "red": color.FgRed, "green": color.FgGreen, "yellow": color.FgYellow,
+	"blue": color.FgBlue, "magenta": color.FgMagenta, "cyan": color.FgCyan, "white": color.FgWhite,
+	"bold": color.Bold, "faint": color.Faint, "italic": color.Italic, "underline": color.Underline,
+	"reverse": color.ReverseVideo,
+}
+
+// ParseStyle parses a style such as "hi-red bold" or "black on-yellow":
+// color names, optionally prefixed with "hi-" for the bright variant and
+// "on-" for the background, and bold, faint, italic, underline or reverse
+func ParseStyle(spec string) (Style, error) {
+	var style Style
+	for _, word := range strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool { return r == ' ' || r == ',' }) {
+		name := word
+		background := strings.HasPrefix(name, "on-")
+		name = strings.TrimPrefix(name, "on-")
+		bright := strings.HasPrefix(name, "hi-")
+		name = strings.TrimPrefix(name, "hi-")
+
+		attr, ok := styleNames[name]
+		isColor := ok && attr >= color.FgBlack && attr <= color.FgWhite
+		if !ok || (!isColor && (background || bright)) {
+			return nil, fmt.Errorf("unknown style %q", word)
+		}
+		if bright {
+			attr += color.FgHiBlack - color.FgBlack
+		}
+		if background {
+			attr += color.BgBlack - color.FgBlack
+		}
+		style = append(style, attr)
+	}
+	if len(style) == 0 {
+		return nil, fmt.Errorf("empty style")
+	}
+	return style, nil
+}
+
+// highlight applies the theme's highlight rules to text. Rules are tried in
+// order and the first one to match a part of the text colors it, so matches
+// never nest.
+func (th *Theme) highlight(text string, style func(Style) *color.Color) string {
+	owner := make([]int, len(text))
+	matched := false
+	for i, rule := range th.highlights {
+		for _, loc := range rule.pattern.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] || !free(owner[loc[0]:loc[1]]) {
+				continue
+			}
+			for j := loc[0]; j < loc[1]; j++ {
+				owner[j] = i + 1
+			}
+			matched = true
+		}
+	}
+	if !matched {
+		return text
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(text); {
+		end := start + 1
+		for end < len(text) && owner[end] == owner[start] {
+			end++
+		}
+		if owner[start] == 0 {
+			out.WriteString(text[start:end])
+		} else {
+			out.WriteString(style(th.highlights[owner[start]-1].style).Sprint(text[start:end]))
+		}
+		start = end
+	}
+	return out.String()
+}
+
+func free(owner []int) bool {
+	for _, o := range owner {
+		if o != 0 {
+			return false
+		}
+	}
+	return true
+}
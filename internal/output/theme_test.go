@@ -0,0 +1,78 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTheme(t *testing.T) {
+	for _, name := range Themes() {
+		th, err := NewTheme(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, name, th.Name())
+	}
+
+	th, err := NewTheme("")
+	require.NoError(t, err)
+	assert.Equal(t, ThemeDark, th.Name())
+
+	_, err = NewTheme("solarized")
+	assert.ErrorContains(t, err, "high-contrast")
+
+	_, err = NewTheme(ThemeLight, Highlight{Pattern: "(", Color: "red"})
+	assert.ErrorContains(t, err, "highlight")
+
+	_, err = NewTheme(ThemeLight, Highlight{Pattern: "svc", Color: "purple"})
+	assert.ErrorContains(t, err, `unknown style "purple"`)
+}
+
+func TestParseStyle(t *testing.T) {
+	style, err := ParseStyle("hi-red bold")
+	require.NoError(t, err)
+	assert.Equal(t, Style{color.FgHiRed, color.Bold}, style)
+
+	style, err = ParseStyle("black, on-hi-yellow")
+	require.NoError(t, err)
+	assert.Equal(t, Style{color.FgBlack, color.BgHiYellow}, style)
+
+	_, err = ParseStyle("on-bold")
+	assert.Error(t, err)
+	_, err = ParseStyle(" ")
+	assert.Error(t, err)
+}
+
+func TestHighlight(t *testing.T) {
+	th, err := NewTheme(ThemeDark, Highlight{Pattern: `payment-svc`, Color: "magenta"})
+	require.NoError(t, err)
+
+	term := &terminal{w: bufio.NewWriter(&bytes.Buffer{}), color: true, theme: th}
+	got := term.highlight(`payment-svc failed after 30s on "db timeout"`)
+
+	// User rules win, and matches never nest: "timeout" inside the quotes
+	// keeps the quoted color
+	assert.Equal(t, "\x1b[35mpayment-svc\x1b[0m \x1b[91mfailed\x1b[0m after \x1b[93m30s\x1b[0m on \x1b[96m\"db timeout\"\x1b[0m", got)
+
+	term.color = false
+	assert.Equal(t, "payment-svc failed", term.highlight("payment-svc failed"))
+}
+
+func TestMonochromeTheme(t *testing.T) {
+	th, err := NewTheme(ThemeMonochrome)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	term := newTerminal(bufio.NewWriter(&out), true, 80, Options{Theme: th})
+	term.analysis(sampleResult())
+	term.w.Flush()
+
+	// Attributes only: bold, faint, italic, underline, reverse video and
+	// their resets, never a color
+	for _, seq := range sgrPattern.FindAllString(out.String(), -1) {
+		assert.Regexp(t, `^\x1b\[([0-4]|7|2[2-7])(;([0-4]|7|2[2-7]))*m$`, seq)
+	}
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// PrintWatching prints the banner shown while `loggar watch` waits for bursts
+func PrintWatching(source string) {
+	color.New(color.FgHiBlack).Printf("👀 Watching %s for error bursts (Ctrl+C to stop)\n", source)
+}
+
+// PrintIncident prints the rule above an incident's inline analysis in watch mode
+func PrintIncident(start, end time.Time, errors, lines int) {
+	title := fmt.Sprintf(" INCIDENT %s–%s · %s in %s ", start.Format("15:04:05"), end.Format("15:04:05"),
+		countNoun(errors, "error"), countNoun(lines, "line"))
+	width := getTermWidth()
+	rule := ""
+	if pad := width - displayWidth(title) - 2; pad > 0 {
+		rule = strings.Repeat("━", pad)
+	}
+	fmt.Println()
+	color.New(color.FgHiRed, color.Bold).Println("━━" + title + rule)
+}
+
+// PrintDuplicate notes a repeated incident that was not analyzed again
+func PrintDuplicate(start time.Time, occurrences int, primaryIssue string) {
+	text := fmt.Sprintf("↻ %s: same incident again (%d× since last analysis)", start.Format("15:04:05"), occurrences)
+	if primaryIssue != "" {
+		text += ": " + primaryIssue
+	}
+	color.New(color.FgHiYellow).Println(text)
+}
+
+func countNoun(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,157 @@
+package output
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// sgrPattern matches the ANSI color sequences written by fatih/color
+var sgrPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+const (
+	sgrReset        = "\x1b[0m"
+	zeroWidthJoiner = '\u200d'
+)
+
+// displayWidth returns the number of terminal columns text occupies. Color
+// sequences, combining marks and the parts of a joined emoji sequence after
+// the first take no columns; wide East Asian characters and emoji take two.
+func displayWidth(text string) int {
+	if strings.IndexByte(text, '\x1b') >= 0 {
+		text = sgrPattern.ReplaceAllString(text, "")
+	}
+	total := 0
+	joined := false
+	for _, r := range text {
+		if r == zeroWidthJoiner {
+			joined = true
+			continue
+		}
+		if joined {
+			joined = false
+			continue
+		}
+		total += runeWidth(r)
+	}
+	return total
+}
+
+// runeWidth returns the number of columns a single rune occupies
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf), r >= 0xfe00 && r <= 0xfe0f:
+		// Combining marks, format characters and variation selectors
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// truncateWidth shortens text to at most max columns, ending with "…" when
+// anything was cut. Color sequences are dropped from truncated text.
+func truncateWidth(text string, max int) string {
+	if max < 1 || displayWidth(text) <= max {
+		return text
+	}
+	text = sgrPattern.ReplaceAllString(text, "")
+
+	var out strings.Builder
+	used := 0
+	joined := false
+	for _, r := range text {
+		w := runeWidth(r)
+		if r == zeroWidthJoiner || joined {
+			joined = r == zeroWidthJoiner
+			w = 0
+		}
+		if used+w > max-1 {
+			break
+		}
+		out.WriteRune(r)
+		used += w
+	}
+	return out.String() + "…"
+}
+
+// wrapText wraps text to the given display width and adds indent to every
+// line after the first. Words wider than the line, such as URLs and paths,
+// are never broken; they get a line of their own. Text may already be
+// highlighted: color sequences take no width, and a color that is open at a
+// line break is closed before it and reopened after the indent.
+func wrapText(text string, width int, indent string) []string {
+	if width <= 0 {
+		return []string{text}
+	}
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	var line strings.Builder
+	lineWidth := 0
+	indentWidth := displayWidth(indent)
+	open := "" // color sequences in effect at the end of the line so far
+
+	for i, word := range words {
+		wordWidth := displayWidth(word)
+		switch {
+		case i == 0:
+		case lineWidth+1+wordWidth > width:
+			if open != "" {
+				line.WriteString(sgrReset)
+			}
+			lines = append(lines, line.String())
+			line.Reset()
+			line.WriteString(indent)
+			line.WriteString(open)
+			lineWidth = indentWidth
+		default:
+			line.WriteByte(' ')
+			lineWidth++
+		}
+		line.WriteString(word)
+		lineWidth += wordWidth
+		open = openColors(open, word)
+	}
+	return append(lines, line.String())
+}
+
+// openColors returns the color sequences still in effect after text, given
+// those in effect before it. Highlights never nest, so any reset, including
+// the attribute-specific ones fatih/color writes (22 for bold, 24 for
+// underline...), closes everything.
+func openColors(open, text string) string {
+	if strings.IndexByte(text, '\x1b') < 0 {
+		return open
+	}
+	for _, seq := range sgrPattern.FindAllString(text, -1) {
+		if isReset(seq) {
+			open = ""
+		} else {
+			open += seq
+		}
+	}
+	return open
+}
+
+// isReset reports whether a color sequence turns attributes off
+func isReset(seq string) bool {
+	params := strings.TrimSuffix(strings.TrimPrefix(seq, "\x1b["), "m")
+	first, _, _ := strings.Cut(params, ";")
+	switch first {
+	case "", "0", "22", "23", "24", "25", "27", "28", "29", "39", "49":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+	}{
+		{"timeout", 7},
+		{"café", 4},
+		{"cafe\u0301", 4}, // combining accent
+		{"数据库", 6},
+		{"💡 tip", 6},
+		{"\U0001F468\u200d\U0001F469\u200d\U0001F467", 2}, // joined family emoji
+		{"\x1b[91merror\x1b[0m", 5},
+		{"\u26a0\ufe0f", 1}, // variation selector
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.width, displayWidth(tt.text), "%q", tt.text)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, []string{"aaa bbb", "  ccc"}, wrapText("aaa bbb ccc", 8, "  "))
+
+	// Wide characters count twice
+	assert.Equal(t, []string{"数据库 连接", "  超时"}, wrapText("数据库 连接 超时", 11, "  "))
+
+	// Long paths are never broken and get a line of their own
+	path := "/var/lib/postgresql/data/pg_wal/000000010000000000000042"
+	assert.Equal(t, []string{"see", "  " + path, "  for", "  details"}, wrapText("see "+path+" for details", 10, "  "))
+	assert.Equal(t, []string{path}, wrapText(path, 10, "  "))
+
+	assert.Equal(t, []string{""}, wrapText("   ", 10, ""))
+}
+
+func TestWrapTextColors(t *testing.T) {
+	red := "\x1b[91m"
+	text := "the " + red + "connection refused by upstream" + sgrReset + " again"
+
+	lines := wrapText(text, 16, "  ")
+	assert.Equal(t, []string{
+		"the " + red + "connection" + sgrReset,
+		"  " + red + "refused by" + sgrReset,
+		"  " + red + "upstream" + sgrReset + " again",
+	}, lines)
+
+	// Attribute-specific resets close the color too
+	bold := wrapText("\x1b[1mvery important\x1b[22m note", 10, "")
+	assert.Equal(t, []string{"\x1b[1mvery" + sgrReset, "\x1b[1mimportant\x1b[22m", "note"}, bold)
+
+	// Color sequences take no width
+	for _, line := range lines {
+		assert.LessOrEqual(t, displayWidth(line), 16)
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	assert.Equal(t, "short", truncateWidth("short", 10))
+	assert.Equal(t, "abcd…", truncateWidth("abcdefgh", 5))
+	assert.Equal(t, "数据…", truncateWidth("数据库连接", 6))
+	assert.Equal(t, "err…", truncateWidth("\x1b[91merror\x1b[0m", 4))
+	assert.Equal(t, strings.Repeat("x", 3), truncateWidth("xxx", 0))
+}
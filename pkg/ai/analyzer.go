@@ -2,37 +2,94 @@ package ai
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// AnalysisResult represents the structured output from AI
+// AnalysisResult represents the structured output from AI. Summary and
+// Sections form the v1 schema; the typed fields are only sent to v2 clients.
 type AnalysisResult struct {
 	Summary  string    `json:"summary"`
 	Sections []Section `json:"sections"`
+
+	SchemaVersion      int               `json:"schema_version,omitempty"`
+	Severity           string            `json:"severity,omitempty"`
+	PrimaryIssue       string            `json:"primary_issue,omitempty"`
+	AffectedComponents []string          `json:"affected_components,omitempty"`
+	FirstSeen          string            `json:"first_seen,omitempty"`
+	Causes             []Cause           `json:"causes,omitempty"`
+	Timeline           []TimelineEvent   `json:"timeline,omitempty"`
+	Actions            []Action          `json:"actions,omitempty"`
+	KnownIssues        []KnownIssue      `json:"known_issues,omitempty"`
+	Sources            []SourceBreakdown `json:"sources,omitempty"`
+	Window             *TimeWindow       `json:"window,omitempty"`
+	LineMap            []LineSpan        `json:"line_map,omitempty"`
+
+	// Profile and PromptVersion record which prompt template produced the result
+	Profile       string `json:"profile,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
 }
 
 type Section struct {
 	Title   string   `json:"title"`
 	Content []string `json:"content"`
+
+	// Evidence holds the input lines cited by each bullet, indexed like Content (v2 only)
+	Evidence [][]LineRange `json:"evidence,omitempty"`
+	// Claims flags bullets that mention identifiers missing from the logs, indexed like Content (v2 only)
+	Claims []Claim `json:"claims,omitempty"`
 }
 
-// ... (Cause and PastIncident removed if not used in new schema, but let's keep it simple for now)
+// ErrProviderUnavailable is returned when the AI provider cannot be reached:
+// no API key is configured, or every retry failed with a network or server error
+var ErrProviderUnavailable = errors.New("AI provider unavailable")
+
+// Options tune a single analysis
+type Options struct {
+	// Profile selects the prompt template, e.g. "kubernetes". Empty means DefaultProfile.
+	Profile string
+	// KnownIssues are rule matches found before the AI call. They are included
+	// in the prompt and copied to the result.
+	KnownIssues []KnownIssue
+	// Sources describes the files merged into the logs when more than one was
+	// given. The prompt asks the model to reason across them.
+	Sources []SourceBreakdown
+	// ClockSkew is how far apart timestamps of different sources may be and
+	// still be treated as simultaneous
+	ClockSkew time.Duration
+	// Window is the time range the logs were filtered to, if any. It is
+	// mentioned in the prompt and copied to the result.
+	Window *TimeWindow
+	// LineMap locates the lines of logs cut to a window or merged from
+	// several files in the input. It is copied to the result.
+	LineMap []LineSpan
+}
 
 // AnalyzeLogs sends logs to Google AI Studio and returns structured analysis
-func AnalyzeLogs(logText string) (*AnalysisResult, error) {
+func AnalyzeLogs(logText string, opts Options) (*AnalysisResult, error) {
 	apiKey := os.Getenv("GOOGLE_AI_KEY")
 	if apiKey == "" {
-		return nil, fmt.Errorf("GOOGLE_AI_KEY environment variable not set")
+		return nil, fmt.Errorf("%w: GOOGLE_AI_KEY environment variable not set", ErrProviderUnavailable)
 	}
 
 	// Build the prompt
-	prompt := buildPrompt(logText)
+	tmpl, err := GetPrompt(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := tmpl.Render(logText, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// Call Google AI Studio API
 	response, err := callGoogleAI(apiKey, prompt)
@@ -45,43 +102,91 @@ func AnalyzeLogs(logText string) (*AnalysisResult, error) {
 	if err := json.Unmarshal([]byte(response), &result); err != nil {
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
+	result.normalize()
+	result.attachEvidence(len(splitLines(logText)))
+	result.verifySections(logText)
+	result.KnownIssues = opts.KnownIssues
+	result.mergeSources(opts.Sources)
+	result.Window = opts.Window
+	result.LineMap = opts.LineMap
+	result.Profile = tmpl.Profile
+	result.PromptVersion = tmpl.Version
 
 	return &result, nil
 }
 
-// buildPrompt creates the prompt for the AI
-func buildPrompt(logText string) string {
-	systemPrompt := `You are a world-class Principal Software Engineer (L8+). 
-Analyze the provided logs with surgical precision. Your triage must be high-signal, concise, and intellectually punchy.
-
-Rules:
-1. "summary": A single, dense paragraph. Synthesize the failure into a technical narrative. No filler.
-2. "sections": Provide exactly 2-3 sections. Use titles that reflect high-level architecture (e.g., "CORE DIAGNOSIS", "IMMEDIATE RESOLUTION").
-3. Expert Parsimony: Use fewer words to say more. Avoid generic "potential causes" list. Focus on the most probable architectural or code-level failure.
-4. Technical Depth: If you see a stack trace or code path, call out the exact point of failure and why it's likely occurring (e.g., "race condition in connection pooling handler").
-5. Respond ONLY in JSON.
-
-Schema:
-{
-  "summary": "string",
-  "sections": [
-    {
-      "title": "string",
-      "content": ["string"]
-    }
-  ]
+// CacheKey identifies an analysis of logText by its normalised content, the
+// profile and version of the prompt, the model and any known issues, so
+// equivalent inputs share a cached result
+func CacheKey(logText string, opts Options) string {
+	profile, version := opts.Profile, "unknown"
+	if p, err := GetPrompt(opts.Profile); err == nil {
+		profile, version = p.Profile, p.Version
+	}
+
+	h := sha256.New()
+	for _, part := range []string{profile, version, geminiModel, normalizeLogs(logText)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	if len(opts.KnownIssues) > 0 {
+		known, _ := json.Marshal(opts.KnownIssues)
+		h.Write(known)
+	}
+	if len(opts.Sources) > 1 {
+		sources, _ := json.Marshal(opts.Sources)
+		h.Write(sources)
+		h.Write([]byte(opts.ClockSkew.String()))
+	}
+	if opts.Window != nil {
+		window, _ := json.Marshal(opts.Window)
+		h.Write(window)
+	}
+	if len(opts.LineMap) > 0 {
+		lineMap, _ := json.Marshal(opts.LineMap)
+		h.Write(lineMap)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeLogs removes differences that do not change an analysis:
+// line endings, trailing whitespace and trailing blank lines. Leading blank
+// lines are kept because they shift the line numbers cited as evidence.
+func normalizeLogs(logText string) string {
+	lines := strings.Split(strings.ReplaceAll(logText, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
 }
 
-Logs to analyze:
-`
-	return systemPrompt + "\n" + logText
+// geminiModel is the Google AI Studio model used for analysis
+const geminiModel = "gemini-3-flash-preview"
+
+// defaultGeminiBaseURL is the Google AI Studio API, unless GOOGLE_AI_BASE_URL
+// points elsewhere, e.g. at a proxy
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// geminiURL returns the endpoint for a Gemini API method such as generateContent
+func geminiURL(method, apiKey string) string {
+	base := strings.TrimRight(os.Getenv("GOOGLE_AI_BASE_URL"), "/")
+	if base == "" {
+		base = defaultGeminiBaseURL
+	}
+	return fmt.Sprintf("%s/models/%s:%s?key=%s", base, geminiModel, method, apiKey)
 }
 
-// callGoogleAI makes the API call to Google AI Studio with exponential backoff retry
-func callGoogleAI(apiKey, prompt string) (string, error) {
-	// Using Google AI Studio Gemini 3 Flash Preview (Experimental model with high quota)
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-3-flash-preview:generateContent?key=%s", apiKey)
+// maxAttempts returns how many times a call to the provider is tried:
+// GOOGLE_AI_MAX_ATTEMPTS, 5 by default
+func maxAttempts() int {
+	if n, err := strconv.Atoi(os.Getenv("GOOGLE_AI_MAX_ATTEMPTS")); err == nil && n > 0 {
+		return n
+	}
+	return 5
+}
 
+// geminiRequestBody builds the request payload shared by the blocking and streaming calls
+func geminiRequestBody(prompt string) ([]byte, error) {
 	requestBody := map[string]interface{}{
 		"contents": []map[string]interface{}{
 			{
@@ -92,27 +197,75 @@ func callGoogleAI(apiKey, prompt string) (string, error) {
 		},
 		"generationConfig": map[string]interface{}{
 			"temperature":     0.2,
-			"maxOutputTokens": 2048,
+			"maxOutputTokens": 4096,
 		},
 	}
+	return json.Marshal(requestBody)
+}
 
-	jsonData, err := json.Marshal(requestBody)
+// geminiResponse is the response body of generateContent, and of each
+// streamGenerateContent chunk
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+}
+
+// text concatenates the parts of the first candidate
+func (r geminiResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		sb.WriteString(part.Text)
+	}
+	return sb.String()
+}
+
+// retryDelay returns the exponential backoff before the given attempt: 1s, 2s, 4s, 8s, 10s (max)
+func retryDelay(attempt int) time.Duration {
+	delay := time.Duration(1<<uint(attempt-1)) * time.Second
+	if delay > 10*time.Second {
+		delay = 10 * time.Second
+	}
+	return delay
+}
+
+// callGoogleAI makes the API call to Google AI Studio with exponential backoff retry
+func callGoogleAI(apiKey, prompt string) (string, error) {
+	jsonData, err := geminiRequestBody(prompt)
 	if err != nil {
 		return "", err
 	}
 
+	text, err := generateContent(apiKey, jsonData)
+	if err != nil {
+		return "", err
+	}
+
+	// Sanitize response (remove possible markdown code blocks)
+	return sanitizeJSON(text), nil
+}
+
+// generateContent posts a request body to generateContent, retrying rate
+// limits and server errors, and returns the text of the first candidate
+func generateContent(apiKey string, jsonData []byte) (string, error) {
+	// Using Google AI Studio Gemini 3 Flash Preview (Experimental model with high quota)
+	url := geminiURL("generateContent", apiKey)
+
 	var lastErr error
 	var body []byte
+	ok := false
 
-	// Retry mechanism: 5 attempts with exponential backoff
-	for i := 0; i < 5; i++ {
+	// Retry mechanism: exponential backoff between attempts
+	for i := 0; i < maxAttempts() && !ok; i++ {
 		if i > 0 {
-			// Exponential backoff: 1s, 2s, 4s, 8s, 10s (max)
-			delay := time.Duration(1<<uint(i-1)) * time.Second
-			if delay > 10*time.Second {
-				delay = 10 * time.Second
-			}
-			time.Sleep(delay)
+			time.Sleep(retryDelay(i))
 		}
 
 		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
@@ -122,15 +275,15 @@ func callGoogleAI(apiKey, prompt string) (string, error) {
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			body, _ = io.ReadAll(resp.Body)
+			errBody, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
 
 			// Only retry for rate limits (429) or server errors (500/503)
 			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-				lastErr = fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+				lastErr = fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(errBody))
 				continue
 			}
-			return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+			return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(errBody))
 		}
 
 		body, err = io.ReadAll(resp.Body)
@@ -139,26 +292,15 @@ func callGoogleAI(apiKey, prompt string) (string, error) {
 			lastErr = fmt.Errorf("failed to read response body: %w", err)
 			continue
 		}
-
-		// If we got here, we have a successful response
-		break
+		ok = true
 	}
 
-	if body == nil {
-		return "", fmt.Errorf("all retry attempts failed: %w", lastErr)
+	if !ok {
+		return "", fmt.Errorf("%w: all retry attempts failed: %w", ErrProviderUnavailable, lastErr)
 	}
 
 	// Parse Google AI response
-	var aiResponse struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
+	var aiResponse geminiResponse
 	if err := json.Unmarshal(body, &aiResponse); err != nil {
 		return "", err
 	}
@@ -167,12 +309,7 @@ func callGoogleAI(apiKey, prompt string) (string, error) {
 		return "", fmt.Errorf("no response from AI after parsing")
 	}
 
-	text := aiResponse.Candidates[0].Content.Parts[0].Text
-
-	// Sanitize response (remove possible markdown code blocks)
-	text = sanitizeJSON(text)
-
-	return text, nil
+	return aiResponse.Candidates[0].Content.Parts[0].Text, nil
 }
 
 func sanitizeJSON(input string) string {
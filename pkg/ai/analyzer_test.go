@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableProvider points the client at a server that answers 503 to
+// every request and returns the number of requests it received
+func unavailableProvider(t *testing.T) *int32 {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":{"code":503,"message":"The model is overloaded."}}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("GOOGLE_AI_KEY", "test-key")
+	t.Setenv("GOOGLE_AI_BASE_URL", srv.URL)
+	t.Setenv("GOOGLE_AI_MAX_ATTEMPTS", "2")
+	return &calls
+}
+
+func TestProviderUnavailable(t *testing.T) {
+	calls := unavailableProvider(t)
+
+	_, err := AnalyzeLogs("ERROR boom\n", Options{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrProviderUnavailable), err.Error())
+	assert.Contains(t, err.Error(), "status 503")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	_, err = Ask("ERROR boom\n", &AnalysisResult{Summary: "boom"}, nil, "Why?")
+	assert.True(t, errors.Is(err, ErrProviderUnavailable))
+}
+
+func TestProviderClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	t.Setenv("GOOGLE_AI_KEY", "test-key")
+	t.Setenv("GOOGLE_AI_BASE_URL", srv.URL)
+
+	// Client errors are not retried and do not trigger the offline fallback
+	_, err := AnalyzeLogs("ERROR boom\n", Options{})
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrProviderUnavailable))
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Roles of the messages in a follow-up conversation
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a follow-up conversation about an analysis
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatInstructions frame a follow-up conversation. The logs and the analysis
+// are appended, so answers can cite lines like the analysis does.
+const chatInstructions = `You are a world-class Principal Software Engineer (L8+) who has just triaged the logs below.
+Answer the user's follow-up questions about your analysis. Be direct and concise, and reason from the logs:
+cite the lines that support a claim, e.g. [L12] or [L12-L14]. If the logs do not settle a question, say so
+and explain what additional evidence would. Reply in plain text or Markdown, not JSON.`
+
+// Ask continues a conversation about an analysis: the original logs and
+// result are sent as context, followed by the previous messages and the
+// new question. It returns the answer.
+func Ask(logText string, result *AnalysisResult, history []Message, question string) (string, error) {
+	if strings.TrimSpace(question) == "" {
+		return "", errors.New("question is empty")
+	}
+	apiKey := os.Getenv("GOOGLE_AI_KEY")
+	if apiKey == "" {
+		return "", fmt.Errorf("%w: GOOGLE_AI_KEY environment variable not set", ErrProviderUnavailable)
+	}
+
+	body, err := chatRequestBody(logText, result, history, question)
+	if err != nil {
+		return "", err
+	}
+	answer, err := generateContent(apiKey, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Google AI: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// chatRequestBody builds a multi-turn generateContent payload. The context
+// goes in the system instruction; assistant messages are "model" turns.
+func chatRequestBody(logText string, result *AnalysisResult, history []Message, question string) ([]byte, error) {
+	analysis, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	background := fmt.Sprintf("%s\n\nLogs (%d lines):\n%s\n\nYour analysis:\n%s",
+		chatInstructions, len(splitLines(logText)), numberLines(logText), analysis)
+
+	type part struct {
+		Text string `json:"text"`
+	}
+	type content struct {
+		Role  string `json:"role,omitempty"`
+		Parts []part `json:"parts"`
+	}
+
+	contents := make([]content, 0, len(history)+1)
+	for _, m := range history {
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "model"
+		}
+		contents = append(contents, content{Role: role, Parts: []part{{m.Content}}})
+	}
+	contents = append(contents, content{Role: "user", Parts: []part{{question}}})
+
+	return json.Marshal(map[string]interface{}{
+		"systemInstruction": content{Parts: []part{{background}}},
+		"contents":          contents,
+		"generationConfig": map[string]interface{}{
+			"temperature":     0.3,
+			"maxOutputTokens": 2048,
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatRequestBody(t *testing.T) {
+	result := &AnalysisResult{Summary: "Connection pool exhausted", Severity: "high"}
+	history := []Message{
+		{Role: RoleUser, Content: "Why the pool?"},
+		{Role: RoleAssistant, Content: "Timeouts start after [L2]."},
+	}
+
+	body, err := chatRequestBody("10:00:00 INFO start\n10:00:01 ERROR pool exhausted\n", result, history, "And not the network?")
+	require.NoError(t, err)
+
+	var req struct {
+		SystemInstruction struct {
+			Parts []struct{ Text string } `json:"parts"`
+		} `json:"systemInstruction"`
+		Contents []struct {
+			Role  string                  `json:"role"`
+			Parts []struct{ Text string } `json:"parts"`
+		} `json:"contents"`
+	}
+	require.NoError(t, json.Unmarshal(body, &req))
+
+	require.Len(t, req.SystemInstruction.Parts, 1)
+	system := req.SystemInstruction.Parts[0].Text
+	assert.Contains(t, system, "Logs (2 lines):\nL1: 10:00:00 INFO start\nL2: 10:00:01 ERROR pool exhausted\n")
+	assert.Contains(t, system, `"summary": "Connection pool exhausted"`)
+
+	require.Len(t, req.Contents, 3)
+	var roles, texts []string
+	for _, c := range req.Contents {
+		roles = append(roles, c.Role)
+		texts = append(texts, c.Parts[0].Text)
+	}
+	assert.Equal(t, []string{"user", "model", "user"}, roles)
+	assert.Equal(t, []string{"Why the pool?", "Timeouts start after [L2].", "And not the network?"}, texts)
+	assert.Len(t, history, 2)
+}
+
+func TestAskWithoutKey(t *testing.T) {
+	t.Setenv("GOOGLE_AI_KEY", "")
+
+	_, err := Ask("logs", &AnalysisResult{}, nil, "why?")
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+
+	_, err = Ask("logs", &AnalysisResult{}, nil, "  ")
+	assert.EqualError(t, err, "question is empty")
+}
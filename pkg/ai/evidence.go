@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Citations in section bullets look like [L12], [L12-L14] or [L3, L8-L9]
+var (
+	citationPattern = regexp.MustCompile(`\s*\[(L\d+(?:\s*[-–]\s*L?\d+)?(?:\s*,\s*L\d+(?:\s*[-–]\s*L?\d+)?)*)\]`)
+	citedRange      = regexp.MustCompile(`L(\d+)(?:\s*[-–]\s*L?(\d+))?`)
+)
+
+// splitLines splits logs into the lines that are numbered for the model
+func splitLines(logText string) []string {
+	logText = strings.TrimRight(strings.ReplaceAll(logText, "\r\n", "\n"), "\n")
+	if logText == "" {
+		return nil
+	}
+	return strings.Split(logText, "\n")
+}
+
+// numberLines prefixes every line with its 1-based number ("L12: ...") so the
+// model can cite evidence
+func numberLines(logText string) string {
+	var sb strings.Builder
+	for i, line := range splitLines(logText) {
+		fmt.Fprintf(&sb, "L%d: %s\n", i+1, line)
+	}
+	return sb.String()
+}
+
+// attachEvidence moves citations out of section bullets into Section.Evidence
+// and drops cited ranges that fall outside the lineCount input lines
+func (r *AnalysisResult) attachEvidence(lineCount int) {
+	for i := range r.Causes {
+		r.Causes[i].Evidence = validRanges(r.Causes[i].Evidence, lineCount)
+	}
+	for i := range r.Timeline {
+		r.Timeline[i].Evidence = validRanges(r.Timeline[i].Evidence, lineCount)
+	}
+	for i := range r.Sections {
+		r.Sections[i].extractCitations(lineCount)
+	}
+}
+
+// extractCitations strips citation markers from each bullet, recording the
+// valid ranges in Evidence at the same index as the bullet
+func (s *Section) extractCitations(lineCount int) {
+	evidence := make([][]LineRange, len(s.Content))
+	found := false
+	for i, item := range s.Content {
+		var ranges []LineRange
+		for _, m := range citationPattern.FindAllStringSubmatch(item, -1) {
+			ranges = append(ranges, parseCitation(m[1])...)
+		}
+		s.Content[i] = strings.TrimSpace(citationPattern.ReplaceAllString(item, ""))
+		evidence[i] = validRanges(ranges, lineCount)
+		if len(evidence[i]) > 0 {
+			found = true
+		}
+	}
+	if found {
+		s.Evidence = evidence
+	} else {
+		s.Evidence = nil
+	}
+}
+
+// parseCitation parses the inside of a citation marker, e.g. "L3, L8-L9"
+func parseCitation(text string) []LineRange {
+	var ranges []LineRange
+	for _, m := range citedRange.FindAllStringSubmatch(text, -1) {
+		start, _ := strconv.Atoi(m[1])
+		end := start
+		if m[2] != "" {
+			end, _ = strconv.Atoi(m[2])
+		}
+		ranges = append(ranges, LineRange{Start: start, End: end})
+	}
+	return ranges
+}
+
+// LineRanges collapses sorted line numbers into at most max ranges
+func LineRanges(lines []int, max int) []LineRange {
+	var ranges []LineRange
+	for _, n := range lines {
+		if last := len(ranges) - 1; last >= 0 && ranges[last].End == n-1 {
+			ranges[last].End = n
+			continue
+		}
+		if len(ranges) == max {
+			break
+		}
+		ranges = append(ranges, LineRange{Start: n, End: n})
+	}
+	return ranges
+}
+
+// ValidKnownIssues drops the evidence of issues that falls outside the lines
+// of logText, as for the model's own citations
+func ValidKnownIssues(issues []KnownIssue, logText string) []KnownIssue {
+	lineCount := len(splitLines(logText))
+	for i := range issues {
+		issues[i].Evidence = validRanges(issues[i].Evidence, lineCount)
+	}
+	return issues
+}
+
+// validRanges fixes reversed or open ranges and drops ranges outside 1..lineCount
+func validRanges(ranges []LineRange, lineCount int) []LineRange {
+	var valid []LineRange
+	for _, r := range ranges {
+		if r.End == 0 {
+			r.End = r.Start
+		}
+		if r.End < r.Start {
+			r.Start, r.End = r.End, r.Start
+		}
+		if r.Start < 1 || r.End > lineCount {
+			continue
+		}
+		valid = append(valid, r)
+	}
+	return valid
+}
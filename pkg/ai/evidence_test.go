@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberLines(t *testing.T) {
+	assert.Equal(t, "L1: first\nL2: \nL3: third\n", numberLines("first\r\n\r\nthird\n"))
+	assert.Equal(t, "", numberLines(""))
+}
+
+func TestAttachEvidence(t *testing.T) {
+	result := &AnalysisResult{
+		Sections: []Section{
+			{Title: "CORE DIAGNOSIS", Content: []string{
+				"Pool exhausted before the auth timeouts [L2-L3]",
+				"Payments fail afterwards [L4, L9] [L40]",
+				"→ Raise the pool size",
+			}},
+			{Title: "NOTES", Content: []string{"Nothing cited"}},
+		},
+		Causes: []Cause{{Cause: "Leak", Evidence: []LineRange{{Start: 3, End: 1}, {Start: 0, End: 2}, {Start: 5}}}},
+	}
+
+	result.attachEvidence(10)
+
+	assert.Equal(t, []string{
+		"Pool exhausted before the auth timeouts",
+		"Payments fail afterwards",
+		"→ Raise the pool size",
+	}, result.Sections[0].Content)
+	assert.Equal(t, [][]LineRange{
+		{{Start: 2, End: 3}},
+		{{Start: 4, End: 4}, {Start: 9, End: 9}},
+		nil,
+	}, result.Sections[0].Evidence)
+	assert.Nil(t, result.Sections[1].Evidence)
+	assert.Equal(t, []LineRange{{Start: 1, End: 3}, {Start: 5, End: 5}}, result.Causes[0].Evidence)
+}
+
+func TestLineRanges(t *testing.T) {
+	assert.Equal(t, []LineRange{{Start: 1, End: 3}, {Start: 7, End: 7}}, LineRanges([]int{1, 2, 3, 7, 9}, 2))
+	assert.Nil(t, LineRanges(nil, 5))
+}
+
+func TestValidKnownIssues(t *testing.T) {
+	issues := ValidKnownIssues([]KnownIssue{
+		{Rule: "oom", Evidence: []LineRange{{Start: 2}, {Start: 3, End: 1}, {Start: 2, End: 40}}},
+	}, "a\nb\nc\n")
+	assert.Equal(t, []LineRange{{Start: 2, End: 2}, {Start: 1, End: 3}}, issues[0].Evidence)
+}
+
+func TestSeverityRank(t *testing.T) {
+	assert.Less(t, SeverityRank(SeverityCritical), SeverityRank(SeverityHigh))
+	assert.Less(t, SeverityRank(SeverityLow), SeverityRank(SeverityInfo))
+	assert.Less(t, SeverityRank(SeverityInfo), SeverityRank("bogus"))
+}
@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed prompts/*.tmpl
+var promptFiles embed.FS
+
+// DefaultProfile is used when a request does not select an analysis profile
+const DefaultProfile = "default"
+
+// baseTemplate holds the shared definitions ("persona", "rules", "schema", "logs")
+// available to every profile template
+const baseTemplate = "base.tmpl"
+
+// Prompt is a versioned prompt template for one analysis profile
+type Prompt struct {
+	Profile string
+	Version string
+	tmpl    *template.Template
+}
+
+// promptData is passed to prompt templates. Logs are numbered ("L12: ...")
+// so the model can cite the lines supporting each claim.
+type promptData struct {
+	Logs        string
+	LineCount   int
+	KnownIssues []KnownIssue
+	Sources     []SourceBreakdown
+	ClockSkew   string
+	Window      *TimeWindow
+}
+
+var versionPattern = regexp.MustCompile(`/\*\s*version:\s*([\w.\-]+)\s*\*/`)
+
+var (
+	promptsMu sync.RWMutex
+	prompts   = mustLoadEmbeddedPrompts()
+)
+
+func mustLoadEmbeddedPrompts() map[string]*Prompt {
+	base, err := promptFiles.ReadFile("prompts/" + baseTemplate)
+	if err != nil {
+		panic(err)
+	}
+	entries, err := promptFiles.ReadDir("prompts")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]*Prompt)
+	for _, entry := range entries {
+		if entry.Name() == baseTemplate {
+			continue
+		}
+		content, err := promptFiles.ReadFile("prompts/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		p, err := parsePrompt(entry.Name(), string(base), string(content))
+		if err != nil {
+			panic(err)
+		}
+		loaded[p.Profile] = p
+	}
+	return loaded
+}
+
+// parsePrompt parses a profile template together with the shared base definitions
+func parsePrompt(filename, base, content string) (*Prompt, error) {
+	profile := strings.TrimSuffix(filepath.Base(filename), ".tmpl")
+
+	tmpl, err := template.New(baseTemplate).Option("missingkey=error").Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", baseTemplate, err)
+	}
+	if _, err := tmpl.New(profile).Parse(content); err != nil {
+		return nil, fmt.Errorf("parse prompt %s: %w", filename, err)
+	}
+
+	version := "unversioned"
+	if m := versionPattern.FindStringSubmatch(content); m != nil {
+		version = m[1]
+	}
+
+	return &Prompt{Profile: profile, Version: version, tmpl: tmpl}, nil
+}
+
+// LoadPromptDir loads user-supplied <profile>.tmpl files from dir. They can use
+// the shared definitions from the embedded base template and override built-in
+// profiles of the same name.
+func LoadPromptDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	base, err := promptFiles.ReadFile("prompts/" + baseTemplate)
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]*Prompt)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read prompt %s: %w", file, err)
+		}
+		p, err := parsePrompt(file, string(base), string(content))
+		if err != nil {
+			return err
+		}
+		loaded[p.Profile] = p
+	}
+
+	promptsMu.Lock()
+	defer promptsMu.Unlock()
+	for profile, p := range loaded {
+		prompts[profile] = p
+	}
+	return nil
+}
+
+// Profiles lists the available analysis profiles
+func Profiles() []string {
+	promptsMu.RLock()
+	defer promptsMu.RUnlock()
+	return profileNames()
+}
+
+// GetPrompt returns the prompt for a profile, or the default prompt when profile is empty
+func GetPrompt(profile string) (*Prompt, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	promptsMu.RLock()
+	defer promptsMu.RUnlock()
+
+	p, ok := prompts[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown analysis profile %q (available: %s)", profile, strings.Join(profileNames(), ", "))
+	}
+	return p, nil
+}
+
+// profileNames lists profiles; callers must hold promptsMu
+func profileNames() []string {
+	names := make([]string, 0, len(prompts))
+	for name := range prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render builds the full prompt for the given logs, rule matches and sources
+func (p *Prompt) Render(logText string, opts Options) (string, error) {
+	data := promptData{
+		Logs:        numberLines(logText),
+		LineCount:   len(splitLines(logText)),
+		KnownIssues: opts.KnownIssues,
+		Window:      opts.Window,
+	}
+	if len(opts.Sources) > 1 {
+		data.Sources = opts.Sources
+		data.ClockSkew = opts.ClockSkew.String()
+	}
+
+	var sb strings.Builder
+	if err := p.tmpl.ExecuteTemplate(&sb, p.Profile, data); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", p.Profile, err)
+	}
+	return sb.String(), nil
+}
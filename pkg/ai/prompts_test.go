@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinProfilesRender(t *testing.T) {
+	for _, profile := range Profiles() {
+		p, err := GetPrompt(profile)
+		require.NoError(t, err)
+		assert.Equal(t, "5", p.Version, profile)
+
+		prompt, err := p.Render("ERROR: connection refused", Options{})
+		require.NoError(t, err, profile)
+		assert.Contains(t, prompt, "L1: ERROR: connection refused", profile)
+		assert.Contains(t, prompt, `"sections"`, profile)
+	}
+}
+
+func TestGetPrompt(t *testing.T) {
+	p, err := GetPrompt("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultProfile, p.Profile)
+
+	_, err = GetPrompt("mainframe")
+	assert.ErrorContains(t, err, "kubernetes")
+}
+
+func TestLoadPromptDir(t *testing.T) {
+	dir := t.TempDir()
+	content := "{{/* version: 7 */}}{{template \"persona\"}}\nCustom.\n{{template \"logs\" .}}"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "custom.tmpl"), []byte(content), 0o644))
+	require.NoError(t, LoadPromptDir(dir))
+
+	p, err := GetPrompt("custom")
+	require.NoError(t, err)
+	assert.Equal(t, "7", p.Version)
+
+	prompt, err := p.Render("panic: nil map", Options{})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "Custom.")
+	assert.Contains(t, prompt, "panic: nil map")
+}
+
+func TestCacheKeyIncludesProfile(t *testing.T) {
+	logs := "ERROR: timeout"
+	assert.Equal(t, CacheKey(logs, Options{}), CacheKey(logs, Options{Profile: DefaultProfile}))
+	assert.NotEqual(t, CacheKey(logs, Options{}), CacheKey(logs, Options{Profile: "database"}))
+}
+
+func TestRenderKnownIssues(t *testing.T) {
+	p, err := GetPrompt("")
+	require.NoError(t, err)
+
+	prompt, err := p.Render("a\nb\nc", Options{KnownIssues: []KnownIssue{{
+		Title:       "Postgres pool exhausted",
+		Severity:    SeverityHigh,
+		Count:       2,
+		Evidence:    []LineRange{{Start: 2, End: 3}},
+		Remediation: "Raise PGBOUNCER_POOL_SIZE",
+	}}})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "- Postgres pool exhausted (high): 2 matching lines [L2-L3]. Known fix: Raise PGBOUNCER_POOL_SIZE")
+
+	prompt, err = p.Render("a", Options{})
+	require.NoError(t, err)
+	assert.NotContains(t, prompt, "Known issues")
+}
+
+func TestRenderSources(t *testing.T) {
+	p, err := GetPrompt("")
+	require.NoError(t, err)
+
+	opts := Options{
+		Sources: []SourceBreakdown{
+			{Name: "api.log", Lines: 2, Errors: 1, First: "2026-01-15T10:00:01Z", Last: "2026-01-15T10:00:03Z"},
+			{Name: "db.log", Lines: 1},
+		},
+		ClockSkew: 2 * time.Second,
+	}
+	prompt, err := p.Render("[db.log] restart\n[api.log] ERROR 502\n[api.log] ok", opts)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "- api.log: 2 lines, 1 errors, 0 warnings, 2026-01-15T10:00:01Z to 2026-01-15T10:00:03Z")
+	assert.Contains(t, prompt, "- db.log: 1 lines, 0 errors, 0 warnings")
+	assert.Contains(t, prompt, "within 2s")
+
+	opts.Sources = opts.Sources[:1]
+	prompt, err = p.Render("restart", opts)
+	require.NoError(t, err)
+	assert.NotContains(t, prompt, "merged from")
+}
+
+func TestRenderWindow(t *testing.T) {
+	p, err := GetPrompt("")
+	require.NoError(t, err)
+
+	prompt, err := p.Render("a", Options{Window: &TimeWindow{Start: "2026-01-15T10:00:00Z", End: "2026-01-15T10:05:00Z", Auto: true}})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "cut to the densest burst of errors, with surrounding context: from 2026-01-15T10:00:00Z until 2026-01-15T10:05:00Z.")
+
+	prompt, err = p.Render("a", Options{Window: &TimeWindow{End: "2026-01-15T10:05:00Z"}})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "cut to a time window: from the beginning until 2026-01-15T10:05:00Z.")
+}
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+)
+
+// Response schema versions. Version 1 is the original summary and free-text
+// sections; version 2 adds typed fields alongside them.
+const (
+	SchemaV1 = 1
+	SchemaV2 = 2
+)
+
+// Severity levels reported in the v2 schema, from most to least urgent
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+// Severities lists the severity levels from most to least urgent
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo}
+
+// SeverityRank orders severities from most to least urgent; unknown
+// severities rank last
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// LineRange references input log lines, 1-based and inclusive
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// LineSpan locates analyzed lines in the input when the logs were cut to a
+// time window or merged from several files: lines Start to End are the lines
+// from Line on of the file named Source ("" for logs sent as text). Evidence
+// cites analyzed lines, which the map translates.
+type LineSpan struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Source string `json:"source,omitempty"`
+	Line   int    `json:"line"`
+}
+
+// Cause is a candidate root cause with the model's confidence between 0 and 1
+type Cause struct {
+	Cause      string      `json:"cause"`
+	Confidence float64     `json:"confidence"`
+	Evidence   []LineRange `json:"evidence,omitempty"`
+}
+
+// TimelineEvent is a key moment in the incident
+type TimelineEvent struct {
+	Time      string      `json:"time,omitempty"`
+	Component string      `json:"component,omitempty"`
+	Event     string      `json:"event"`
+	Evidence  []LineRange `json:"evidence,omitempty"`
+}
+
+// Action is a recommended remediation step, optionally with a command to run
+type Action struct {
+	Description string `json:"description"`
+	Command     string `json:"command,omitempty"`
+}
+
+// KnownIssue is a failure signature matched by a user-defined rule before the
+// AI call. Known issues are passed to the model as established findings.
+type KnownIssue struct {
+	Rule        string      `json:"rule"`
+	Title       string      `json:"title"`
+	Severity    string      `json:"severity,omitempty"`
+	Remediation string      `json:"remediation,omitempty"`
+	RunbookURL  string      `json:"runbook_url,omitempty"`
+	Count       int         `json:"count"`
+	Evidence    []LineRange `json:"evidence,omitempty"`
+}
+
+// SourceBreakdown summarises one input file of a multi-source analysis. The
+// counts and time range are computed from the logs; the model writes Summary.
+type SourceBreakdown struct {
+	Name     string `json:"name"`
+	Lines    int    `json:"lines"`
+	Errors   int    `json:"errors"`
+	Warnings int    `json:"warnings"`
+	First    string `json:"first,omitempty"`
+	Last     string `json:"last,omitempty"`
+	Summary  string `json:"summary,omitempty"`
+}
+
+// TimeWindow is the time range the logs were cut to before analysis. An
+// empty Start or End leaves that side open; Auto marks a detected burst.
+type TimeWindow struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	Auto  bool   `json:"auto,omitempty"`
+}
+
+// V1 returns the result in the original schema, for clients that did not ask for v2
+func (r *AnalysisResult) V1() *AnalysisResult {
+	if r == nil {
+		return nil
+	}
+	sections := make([]Section, len(r.Sections))
+	for i, s := range r.Sections {
+		sections[i] = Section{Title: s.Title, Content: s.Content}
+	}
+	return &AnalysisResult{
+		Summary:       r.Summary,
+		Sections:      sections,
+		Profile:       r.Profile,
+		PromptVersion: r.PromptVersion,
+	}
+}
+
+// Version returns the result in the requested schema version
+func (r *AnalysisResult) Version(version int) *AnalysisResult {
+	if version >= SchemaV2 {
+		return r
+	}
+	return r.V1()
+}
+
+// normalize cleans up typed fields after parsing model output: confidences
+// given as percentages are scaled to 0-1, causes are ordered by confidence
+// and unknown severities are dropped
+func (r *AnalysisResult) normalize() {
+	r.SchemaVersion = SchemaV2
+
+	r.Severity = strings.ToLower(strings.TrimSpace(r.Severity))
+	known := false
+	for _, s := range Severities {
+		if r.Severity == s {
+			known = true
+			break
+		}
+	}
+	if !known {
+		r.Severity = ""
+	}
+
+	for i := range r.Causes {
+		c := &r.Causes[i]
+		if c.Confidence > 1 {
+			c.Confidence /= 100
+		}
+		if c.Confidence < 0 {
+			c.Confidence = 0
+		}
+		if c.Confidence > 1 {
+			c.Confidence = 1
+		}
+	}
+	sort.SliceStable(r.Causes, func(i, j int) bool {
+		return r.Causes[i].Confidence > r.Causes[j].Confidence
+	})
+}
+
+// mergeSources replaces the model's source breakdown with the computed one,
+// keeping the model's summary of each source. Nothing is reported for a
+// single source.
+func (r *AnalysisResult) mergeSources(sources []SourceBreakdown) {
+	if len(sources) < 2 {
+		r.Sources = nil
+		return
+	}
+	summaries := make(map[string]string, len(r.Sources))
+	for _, s := range r.Sources {
+		summaries[s.Name] = s.Summary
+	}
+	r.Sources = make([]SourceBreakdown, len(sources))
+	for i, s := range sources {
+		if summary := summaries[s.Name]; summary != "" {
+			s.Summary = summary
+		}
+		r.Sources[i] = s
+	}
+}
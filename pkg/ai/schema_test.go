@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	var result AnalysisResult
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"summary": "Pool exhausted",
+		"severity": " High ",
+		"causes": [
+			{"cause": "Traffic spike", "confidence": 27},
+			{"cause": "Leaked connections", "confidence": 0.63, "evidence": [{"start": 3, "end": 5}]}
+		]
+	}`), &result))
+
+	result.normalize()
+
+	assert.Equal(t, SchemaV2, result.SchemaVersion)
+	assert.Equal(t, SeverityHigh, result.Severity)
+	require.Len(t, result.Causes, 2)
+	assert.Equal(t, "Leaked connections", result.Causes[0].Cause)
+	assert.Equal(t, []LineRange{{Start: 3, End: 5}}, result.Causes[0].Evidence)
+	assert.InDelta(t, 0.27, result.Causes[1].Confidence, 1e-9)
+
+	result.Severity = "catastrophic"
+	result.normalize()
+	assert.Empty(t, result.Severity)
+}
+
+func TestVersion(t *testing.T) {
+	result := &AnalysisResult{
+		Summary:       "Pool exhausted",
+		Sections:      []Section{{Title: "CORE DIAGNOSIS", Content: []string{"..."}}},
+		SchemaVersion: SchemaV2,
+		Severity:      SeverityCritical,
+		Causes:        []Cause{{Cause: "Leak", Confidence: 0.9}},
+		Profile:       DefaultProfile,
+	}
+
+	v1, err := json.Marshal(result.Version(SchemaV1))
+	require.NoError(t, err)
+	assert.NotContains(t, string(v1), "severity")
+	assert.NotContains(t, string(v1), "causes")
+	assert.Contains(t, string(v1), `"sections"`)
+	assert.Contains(t, string(v1), `"profile":"default"`)
+
+	assert.Same(t, result, result.Version(SchemaV2))
+}
+
+func TestMergeSources(t *testing.T) {
+	result := &AnalysisResult{Sources: []SourceBreakdown{
+		{Name: "db.log", Lines: 999, Summary: "Postgres restarted at 10:00:01"},
+		{Name: "made-up.log", Summary: "Not an input"},
+	}}
+	result.mergeSources([]SourceBreakdown{
+		{Name: "api.log", Lines: 3, Errors: 2},
+		{Name: "db.log", Lines: 2},
+	})
+	assert.Equal(t, []SourceBreakdown{
+		{Name: "api.log", Lines: 3, Errors: 2},
+		{Name: "db.log", Lines: 2, Summary: "Postgres restarted at 10:00:01"},
+	}, result.Sources)
+
+	result.mergeSources(nil)
+	assert.Nil(t, result.Sources)
+}
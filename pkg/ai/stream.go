@@ -0,0 +1,427 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Stream event types, also used as the SSE event names on /api/analyze/stream
+const (
+	EventSummary = "summary"
+	EventSection = "section"
+	EventDone    = "done"
+	EventError   = "error"
+)
+
+// StreamEvent is a piece of an analysis delivered while the model is still generating.
+// Summary events carry the next chunk of summary text, section events carry a
+// completed section and the done event carries the full result.
+type StreamEvent struct {
+	Type    string          `json:"type"`
+	Text    string          `json:"text,omitempty"`
+	Section *Section        `json:"section,omitempty"`
+	Result  *AnalysisResult `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	// AnalysisID identifies the stored analysis on the done event, for follow-up questions
+	AnalysisID string `json:"analysis_id,omitempty"`
+}
+
+// AnalyzeLogsStream is like AnalyzeLogs but calls onEvent as the summary text
+// and each section become available. It returns the complete result.
+func AnalyzeLogsStream(logText string, opts Options, onEvent func(StreamEvent)) (*AnalysisResult, error) {
+	apiKey := os.Getenv("GOOGLE_AI_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: GOOGLE_AI_KEY environment variable not set", ErrProviderUnavailable)
+	}
+
+	tmpl, err := GetPrompt(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := tmpl.Render(logText, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := openGoogleAIStream(apiKey, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Google AI: %w", err)
+	}
+	defer body.Close()
+
+	parser := &streamParser{lineCount: len(splitLines(logText)), verifier: newVerifier(logText)}
+	err = readSSE(body, func(_, _, data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		for _, event := range parser.feed(chunk.text()) {
+			onEvent(event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parser.result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	result.normalize()
+	result.attachEvidence(parser.lineCount)
+	result.verifySections(logText)
+	result.KnownIssues = opts.KnownIssues
+	result.mergeSources(opts.Sources)
+	result.Window = opts.Window
+	result.LineMap = opts.LineMap
+	result.Profile = tmpl.Profile
+	result.PromptVersion = tmpl.Version
+	onEvent(StreamEvent{Type: EventDone, Result: result})
+	return result, nil
+}
+
+// openGoogleAIStream starts a streamGenerateContent call. Retries follow the same
+// backoff as callGoogleAI but only happen before any output has been received.
+func openGoogleAIStream(apiKey, prompt string) (io.ReadCloser, error) {
+	url := geminiURL("streamGenerateContent", apiKey) + "&alt=sse"
+
+	jsonData, err := geminiRequestBody(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for i := 0; i < maxAttempts(); i++ {
+		if i > 0 {
+			time.Sleep(retryDelay(i))
+		}
+
+		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			lastErr = fmt.Errorf("network error: %w", err)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			lastErr = fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+				continue
+			}
+			return nil, lastErr
+		}
+
+		return resp.Body, nil
+	}
+
+	return nil, fmt.Errorf("%w: all retry attempts failed: %w", ErrProviderUnavailable, lastErr)
+}
+
+// ReadAnalysisStream consumes the SSE stream written by /api/analyze/stream,
+// calling onEvent for each event, and returns the final result
+func ReadAnalysisStream(r io.Reader, onEvent func(StreamEvent)) (*AnalysisResult, error) {
+	var result *AnalysisResult
+	err := readSSE(r, func(name, id, data string) error {
+		event := StreamEvent{Type: name}
+		switch name {
+		case EventSummary:
+			var payload struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(data), &payload); err != nil {
+				return fmt.Errorf("invalid summary event: %w", err)
+			}
+			event.Text = payload.Text
+		case EventSection:
+			var section Section
+			if err := json.Unmarshal([]byte(data), &section); err != nil {
+				return fmt.Errorf("invalid section event: %w", err)
+			}
+			event.Section = &section
+		case EventDone:
+			result = &AnalysisResult{}
+			if err := json.Unmarshal([]byte(data), result); err != nil {
+				return fmt.Errorf("invalid done event: %w", err)
+			}
+			event.Result = result
+			event.AnalysisID = id
+		case EventError:
+			var payload struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal([]byte(data), &payload)
+			return fmt.Errorf("analysis failed: %s", payload.Error)
+		default:
+			return nil
+		}
+		onEvent(event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("stream ended before the analysis completed")
+	}
+	return result, nil
+}
+
+// readSSE calls fn for every event in a text/event-stream body
+func readSSE(r io.Reader, fn func(event, id, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var event, id string
+	var data []string
+	dispatch := func() error {
+		defer func() { event, id, data = "", "", nil }()
+		if len(data) == 0 {
+			return nil
+		}
+		return fn(event, id, strings.Join(data, "\n"))
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return dispatch()
+}
+
+// streamParser incrementally extracts the summary text and completed sections
+// from the model's JSON output while it is still being generated
+type streamParser struct {
+	buf         strings.Builder
+	summarySent int // bytes of decoded summary already emitted
+	sectionPos  int // offset in buf after the last emitted section
+	lineCount   int // input lines, for validating citations
+	verifier    *verifier
+}
+
+// feed appends a chunk of model output and returns any newly available events
+func (p *streamParser) feed(chunk string) []StreamEvent {
+	p.buf.WriteString(chunk)
+	text := p.buf.String()
+
+	var events []StreamEvent
+
+	if summary, ok := partialStringValue(text, "summary"); ok {
+		summary = trimPartialRune(summary)
+		if len(summary) > p.summarySent {
+			events = append(events, StreamEvent{Type: EventSummary, Text: summary[p.summarySent:]})
+			p.summarySent = len(summary)
+		}
+	}
+
+	if p.sectionPos == 0 {
+		p.sectionPos = arrayStart(text, "sections")
+	}
+	for p.sectionPos > 0 {
+		start, end, ok := nextObject(text, p.sectionPos)
+		if !ok {
+			break
+		}
+		var section Section
+		if err := json.Unmarshal([]byte(text[start:end]), &section); err != nil {
+			break
+		}
+		p.sectionPos = end
+		section.extractCitations(p.lineCount)
+		if p.verifier != nil {
+			section.verify(p.verifier)
+		}
+		events = append(events, StreamEvent{Type: EventSection, Section: &section})
+	}
+
+	return events
+}
+
+// result parses the complete output once the stream has ended
+func (p *streamParser) result() (*AnalysisResult, error) {
+	var result AnalysisResult
+	if err := json.Unmarshal([]byte(sanitizeJSON(p.buf.String())), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// valueStart returns the offset just after `"key":` in text, or -1. Only keys
+// of the root object match, not those of nested objects such as sources.
+func valueStart(text, key string) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case '"':
+			end := stringEnd(text, i)
+			if end < 0 {
+				return -1
+			}
+			if depth == 1 && text[i+1:end-1] == key {
+				if j := skipSpace(text, end); j < len(text) && text[j] == ':' {
+					return j + 1
+				}
+			}
+			i = end - 1
+		}
+	}
+	return -1
+}
+
+// stringEnd returns the offset just after the string starting at text[start],
+// or -1 if it has not been closed yet
+func stringEnd(text string, start int) int {
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// partialStringValue decodes as much of the string value of key as has been received
+func partialStringValue(text, key string) (string, bool) {
+	i := valueStart(text, key)
+	if i < 0 {
+		return "", false
+	}
+	i = skipSpace(text, i)
+	if i >= len(text) || text[i] != '"' {
+		return "", false
+	}
+	i++
+
+	var out strings.Builder
+	for i < len(text) {
+		c := text[i]
+		if c == '"' {
+			break
+		}
+		if c != '\\' {
+			out.WriteByte(c)
+			i++
+			continue
+		}
+		// Escape sequence: only decode once it is complete
+		if i+1 >= len(text) {
+			break
+		}
+		seqLen := 2
+		if text[i+1] == 'u' {
+			seqLen = 6
+		}
+		if i+seqLen > len(text) {
+			break
+		}
+		var decoded string
+		if err := json.Unmarshal([]byte(`"`+text[i:i+seqLen]+`"`), &decoded); err == nil {
+			out.WriteString(decoded)
+		}
+		i += seqLen
+	}
+	return out.String(), true
+}
+
+// arrayStart returns the offset just after the opening bracket of key's array value, or 0
+func arrayStart(text, key string) int {
+	i := valueStart(text, key)
+	if i < 0 {
+		return 0
+	}
+	i = skipSpace(text, i)
+	if i >= len(text) || text[i] != '[' {
+		return 0
+	}
+	return i + 1
+}
+
+// nextObject finds the next complete JSON object in an array starting at pos
+func nextObject(text string, pos int) (int, int, bool) {
+	i := skipSpace(text, pos)
+	if i < len(text) && text[i] == ',' {
+		i = skipSpace(text, i+1)
+	}
+	if i >= len(text) || text[i] != '{' {
+		return 0, 0, false
+	}
+
+	start := i
+	depth := 0
+	inString := false
+	for ; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return start, i + 1, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// trimPartialRune drops a trailing UTF-8 sequence that was cut off mid-chunk
+func trimPartialRune(s string) string {
+	for i := len(s) - 1; i >= 0 && i >= len(s)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			if !utf8.FullRuneInString(s[i:]) {
+				return s[:i]
+			}
+			break
+		}
+	}
+	return s
+}
+
+func skipSpace(text string, i int) int {
+	for i < len(text) && (text[i] == ' ' || text[i] == '\n' || text[i] == '\r' || text[i] == '\t') {
+		i++
+	}
+	return i
+}
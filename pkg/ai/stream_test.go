@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamParser(t *testing.T) {
+	output := "```json\n" + `{
+  "summary": "Pool exhausted \"db-1\" — café",
+  "sections": [
+    {"title": "CORE DIAGNOSIS", "content": ["leak in {auth}"]},
+    {"title": "IMMEDIATE RESOLUTION", "content": ["raise max_conns"]}
+  ]
+}` + "\n```"
+
+	// Feed the output in small chunks that split escapes and multi-byte runes
+	p := &streamParser{}
+	var summary strings.Builder
+	var sections []Section
+	for i := 0; i < len(output); i += 3 {
+		end := i + 3
+		if end > len(output) {
+			end = len(output)
+		}
+		for _, e := range p.feed(output[i:end]) {
+			switch e.Type {
+			case EventSummary:
+				summary.WriteString(e.Text)
+			case EventSection:
+				sections = append(sections, *e.Section)
+			}
+		}
+	}
+
+	assert.Equal(t, `Pool exhausted "db-1" — café`, summary.String())
+	require.Len(t, sections, 2)
+	assert.Equal(t, "CORE DIAGNOSIS", sections[0].Title)
+	assert.Equal(t, []string{"leak in {auth}"}, sections[0].Content)
+	assert.Equal(t, "IMMEDIATE RESOLUTION", sections[1].Title)
+
+	result, err := p.result()
+	require.NoError(t, err)
+	assert.Equal(t, summary.String(), result.Summary)
+	assert.Len(t, result.Sections, 2)
+}
+
+func TestStreamParserNestedKeys(t *testing.T) {
+	output := `{
+  "sources": [{"name": "api.log", "summary": "api \"summary\": fine", "sections": []}],
+  "summary": "Database down",
+  "sections": [{"title": "CORE DIAGNOSIS", "content": ["db"]}]
+}`
+
+	// Only the root summary and sections are streamed, whole or in chunks
+	for _, size := range []int{len(output), 4} {
+		p := &streamParser{}
+		var summary strings.Builder
+		var sections []Section
+		for i := 0; i < len(output); i += size {
+			end := i + size
+			if end > len(output) {
+				end = len(output)
+			}
+			for _, e := range p.feed(output[i:end]) {
+				switch e.Type {
+				case EventSummary:
+					summary.WriteString(e.Text)
+				case EventSection:
+					sections = append(sections, *e.Section)
+				}
+			}
+		}
+		assert.Equal(t, "Database down", summary.String())
+		require.Len(t, sections, 1)
+		assert.Equal(t, "CORE DIAGNOSIS", sections[0].Title)
+	}
+}
+
+func TestReadAnalysisStream(t *testing.T) {
+	stream := `event:summary
+data:{"text":"Pool "}
+
+event:summary
+data:{"text":"exhausted"}
+
+event:section
+data:{"title":"FIX","content":["restart"]}
+
+event:done
+id:42
+data:{"summary":"Pool exhausted","sections":[{"title":"FIX","content":["restart"]}]}
+
+`
+	var types []string
+	var analysisID string
+	result, err := ReadAnalysisStream(strings.NewReader(stream), func(e StreamEvent) {
+		types = append(types, e.Type)
+		analysisID += e.AnalysisID
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventSummary, EventSummary, EventSection, EventDone}, types)
+	assert.Equal(t, "42", analysisID)
+	assert.Equal(t, "Pool exhausted", result.Summary)
+
+	_, err = ReadAnalysisStream(strings.NewReader("event:error\ndata:{\"error\":\"boom\"}\n\n"), func(StreamEvent) {})
+	assert.EqualError(t, err, "analysis failed: boom")
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Claim is the verification result for one section bullet
+type Claim struct {
+	Verified bool `json:"verified"`
+	// Unsupported lists identifiers from the bullet that do not occur in the logs
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+// Patterns for the concrete references a bullet can make about the logs. Each
+// match (or its first group, when the pattern has one) must occur in the input.
+var referencePatterns = []*regexp.Regexp{
+	// File paths: /var/log/app.log, ./internal/db/pool.go
+	regexp.MustCompile(`(?:\.{0,2}/[\w.\-]+){2,}`),
+	// Source files with optional line: pool.go:142
+	regexp.MustCompile(`\b[\w\-]+\.(?:go|py|js|ts|java|rb|rs|c|cpp|cs|php|sql|yaml|yml|json|conf|log)(?::\d+)?\b`),
+	// IPv4 addresses with optional port
+	regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d{1,5})?\b`),
+	// Hostnames: db-01.internal, payments.svc.cluster.local, api.stripe.com
+	regexp.MustCompile(`\b[a-z0-9][a-z0-9\-]*(?:\.[a-z0-9\-]+)*\.(?:com|net|org|io|dev|internal|local|svc|cluster|cloud|aws|amazonaws)\b`),
+	// Ports: "port 5432", ":6379"
+	regexp.MustCompile(`(?i)\bport\s+(\d{2,5})\b`),
+	regexp.MustCompile(`(?:^|\s)(:\d{2,5})\b`),
+	// Error codes: ECONNREFUSED, ERR_TLS_CERT_ALTNAME_INVALID, ORA-00942, SQLSTATE 40P01
+	regexp.MustCompile(`\bE[A-Z]{4,}\b`),
+	regexp.MustCompile(`\b[A-Z][A-Z0-9]*(?:_[A-Z0-9]+)+\b`),
+	regexp.MustCompile(`\b[A-Z]{2,}-\d{2,}\b`),
+	// HTTP status codes: "HTTP 503", "status 429"
+	regexp.MustCompile(`(?i)\b(?:HTTP|status(?: code)?)\s+([45]\d{2})\b`),
+	// Identifiers containing a separator and a digit: TX_9921, user-99a82, pod-7f9c
+	regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9]*[_\-][A-Za-z0-9_\-]*\d[A-Za-z0-9_\-]*\b`),
+	// Quoted strings
+	regexp.MustCompile(`"([^"]{3,})"`),
+}
+
+// verifier checks bullet references against the submitted logs
+type verifier struct {
+	logs string // lower-cased input
+}
+
+func newVerifier(logText string) *verifier {
+	return &verifier{logs: strings.ToLower(logText)}
+}
+
+// references extracts the concrete identifiers mentioned in text
+func references(text string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, re := range referencePatterns {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			ref := m[0]
+			if len(m) > 1 {
+				ref = m[1]
+			}
+			ref = strings.TrimSpace(ref)
+			if ref == "" || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	// Drop references that are part of a longer one, e.g. the hostname in a path
+	var longest []string
+	for _, ref := range refs {
+		contained := false
+		for _, other := range refs {
+			if other != ref && strings.Contains(other, ref) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			longest = append(longest, ref)
+		}
+	}
+	return longest
+}
+
+// check returns the claim for one bullet. Recommendations ("→ ...") describe
+// what to do rather than what happened, so they are not checked.
+func (v *verifier) check(item string) Claim {
+	if strings.HasPrefix(item, "→") {
+		return Claim{Verified: true}
+	}
+	claim := Claim{Verified: true}
+	for _, ref := range references(item) {
+		if !strings.Contains(v.logs, strings.ToLower(ref)) {
+			claim.Verified = false
+			claim.Unsupported = append(claim.Unsupported, ref)
+		}
+	}
+	return claim
+}
+
+// verify records a Claim for every bullet of the section
+func (s *Section) verify(v *verifier) {
+	s.Claims = make([]Claim, len(s.Content))
+	for i, item := range s.Content {
+		s.Claims[i] = v.check(item)
+	}
+}
+
+// verifySections checks every section bullet of the result against logText
+func (r *AnalysisResult) verifySections(logText string) {
+	v := newVerifier(logText)
+	for i := range r.Sections {
+		r.Sections[i].verify(v)
+	}
+}
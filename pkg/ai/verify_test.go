@@ -0,0 +1,38 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferences(t *testing.T) {
+	refs := references(`Connection to db-01.internal:5432 failed with ECONNREFUSED in /srv/app/db/pool.go (HTTP 503, "pool exhausted")`)
+	assert.Contains(t, refs, "db-01.internal")
+	assert.Contains(t, refs, "ECONNREFUSED")
+	assert.Contains(t, refs, "/srv/app/db/pool.go")
+	assert.Contains(t, refs, "503")
+	assert.Contains(t, refs, "pool exhausted")
+}
+
+func TestVerifySections(t *testing.T) {
+	logs := "10:23:45 ERROR dial tcp 10.0.3.7:5432: connect: ECONNREFUSED\n10:23:46 ERROR payment TX_9921 failed: status 503\n"
+	result := &AnalysisResult{Sections: []Section{{
+		Title: "CORE DIAGNOSIS",
+		Content: []string{
+			"Postgres at 10.0.3.7:5432 refused connections (ECONNREFUSED)",
+			"Payment TX_9921 failed with HTTP 503",
+			"Replica pg-replica-2.internal lagged behind with ERR_REPLICATION_SLOT",
+			"→ Restart pg-bouncer-01 with `kubectl rollout restart`",
+		},
+	}}}
+
+	result.verifySections(logs)
+
+	claims := result.Sections[0].Claims
+	assert.True(t, claims[0].Verified)
+	assert.True(t, claims[1].Verified)
+	assert.False(t, claims[2].Verified)
+	assert.ElementsMatch(t, []string{"pg-replica-2.internal", "ERR_REPLICATION_SLOT"}, claims[2].Unsupported)
+	assert.True(t, claims[3].Verified)
+}
@@ -0,0 +1,149 @@
+package ingest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/watch"
+)
+
+// DefaultStream names the lines of a batch that names no stream
+const DefaultStream = "default"
+
+// DefaultMaxStreams caps the streams of an account when NewBuffer is given no limit
+const DefaultMaxStreams = 100
+
+// Key identifies a stream: an account and the stream's name
+type Key struct {
+	UserID int
+	Stream string
+}
+
+// Incident is an error burst detected in a stream
+type Incident struct {
+	Key
+	*watch.Incident
+}
+
+// Buffer holds the recent lines of each stream and detects error bursts in
+// them as package watch does for a followed file. A burst is reported once
+// it has ended, unless the same failure was reported for the stream within
+// the dedup period. Once an account has maxStreams streams, lines for new
+// ones go to its default stream.
+type Buffer struct {
+	cfg        watch.Config
+	maxStreams int
+	onIncident func(Incident)
+	now        func() time.Time
+
+	mu      sync.Mutex
+	streams map[Key]*stream
+	perUser map[int]int
+}
+
+type stream struct {
+	detector *watch.Detector
+	dedup    *watch.Deduper
+	lastSeen time.Time
+}
+
+// NewBuffer creates a buffer that detects bursts with cfg, keeps at most
+// maxStreams streams per account and passes each new burst to onIncident
+func NewBuffer(cfg watch.Config, maxStreams int, onIncident func(Incident)) *Buffer {
+	if cfg.DedupFor <= 0 {
+		cfg.DedupFor = 30 * time.Minute
+	}
+	if maxStreams <= 0 {
+		maxStreams = DefaultMaxStreams
+	}
+	return &Buffer{
+		cfg:        cfg,
+		maxStreams: maxStreams,
+		onIncident: onIncident,
+		now:        time.Now,
+		streams:    make(map[Key]*stream),
+		perUser:    make(map[int]int),
+	}
+}
+
+// Add appends lines to a stream and returns the key they were added under,
+// which names the default stream once the account has too many
+func (b *Buffer) Add(key Key, lines []string) Key {
+	var incidents []Incident
+
+	b.mu.Lock()
+	now := b.now()
+	s, ok := b.streams[key]
+	if !ok && key.Stream != DefaultStream && b.perUser[key.UserID] >= b.maxStreams {
+		key.Stream = DefaultStream
+		s, ok = b.streams[key]
+	}
+	if !ok {
+		s = &stream{detector: watch.NewDetector(b.cfg), dedup: watch.NewDeduper(b.cfg.DedupFor)}
+		b.streams[key] = s
+		b.perUser[key.UserID]++
+	}
+	s.lastSeen = now
+	for _, line := range lines {
+		if inc := s.detector.Add(line, now); inc != nil && !s.dedup.Seen(inc.Fingerprint, now) {
+			incidents = append(incidents, Incident{key, inc})
+		}
+	}
+	b.mu.Unlock()
+
+	b.report(incidents)
+	return key
+}
+
+// Tick ends bursts that have gone quiet and forgets streams that have sent
+// nothing for the dedup period
+func (b *Buffer) Tick() {
+	var incidents []Incident
+
+	b.mu.Lock()
+	now := b.now()
+	for key, s := range b.streams {
+		if inc := s.detector.Tick(now); inc != nil && !s.dedup.Seen(inc.Fingerprint, now) {
+			incidents = append(incidents, Incident{key, inc})
+		}
+		if now.Sub(s.lastSeen) > b.cfg.DedupFor {
+			if inc := s.detector.Flush(); inc != nil && !s.dedup.Seen(inc.Fingerprint, now) {
+				incidents = append(incidents, Incident{key, inc})
+			}
+			delete(b.streams, key)
+			if b.perUser[key.UserID]--; b.perUser[key.UserID] <= 0 {
+				delete(b.perUser, key.UserID)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	b.report(incidents)
+}
+
+// Start ticks the buffer every second until ctx is cancelled
+func (b *Buffer) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.Tick()
+			}
+		}
+	}()
+}
+
+// report is called without the lock held, so onIncident may take its time
+func (b *Buffer) report(incidents []Incident) {
+	if b.onIncident == nil {
+		return
+	}
+	for _, inc := range incidents {
+		b.onIncident(inc)
+	}
+}
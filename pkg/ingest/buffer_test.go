@@ -0,0 +1,71 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuffer(t *testing.T) {
+	var incidents []Incident
+	b := NewBuffer(watch.Config{Threshold: 2, Window: 10 * time.Second, Quiet: 5 * time.Second, Context: 1}, 0,
+		func(inc Incident) { incidents = append(incidents, inc) })
+	now := t0
+	b.now = func() time.Time { return now }
+
+	checkout := Key{UserID: 1, Stream: "checkout"}
+	other := Key{UserID: 2, Stream: "checkout"}
+	b.Add(checkout, []string{"INFO ready", "ERROR db timeout"})
+	// Streams are counted separately, also across accounts
+	b.Add(other, []string{"ERROR db timeout"})
+	now = now.Add(time.Second)
+	b.Add(checkout, []string{"ERROR db timeout", "INFO retrying"})
+	b.Tick()
+	assert.Empty(t, incidents)
+
+	now = now.Add(5 * time.Second)
+	b.Tick()
+	require.Len(t, incidents, 1)
+	assert.Equal(t, checkout, incidents[0].Key)
+	assert.Equal(t, []string{"INFO ready", "ERROR db timeout", "ERROR db timeout", "INFO retrying"}, incidents[0].Lines)
+	assert.Equal(t, 2, incidents[0].Errors)
+
+	// The same failure again within the dedup period is not reported
+	b.Add(checkout, []string{"ERROR db timeout", "ERROR db timeout"})
+	now = now.Add(time.Minute)
+	b.Tick()
+	assert.Len(t, incidents, 1)
+
+	// Idle streams are forgotten
+	now = now.Add(31 * time.Minute)
+	b.Tick()
+	b.mu.Lock()
+	assert.Empty(t, b.streams)
+	b.mu.Unlock()
+}
+
+func TestBufferMaxStreams(t *testing.T) {
+	b := NewBuffer(watch.Config{Threshold: 10, Window: 10 * time.Second, Quiet: 5 * time.Second}, 2, func(Incident) {})
+	now := t0
+	b.now = func() time.Time { return now }
+
+	assert.Equal(t, Key{UserID: 1, Stream: "a"}, b.Add(Key{UserID: 1, Stream: "a"}, []string{"INFO a"}))
+	assert.Equal(t, Key{UserID: 1, Stream: "b"}, b.Add(Key{UserID: 1, Stream: "b"}, []string{"INFO b"}))
+	// Further streams go to the default stream, existing ones are kept
+	assert.Equal(t, Key{UserID: 1, Stream: DefaultStream}, b.Add(Key{UserID: 1, Stream: "c"}, []string{"INFO c"}))
+	assert.Equal(t, Key{UserID: 1, Stream: DefaultStream}, b.Add(Key{UserID: 1, Stream: "d"}, []string{"INFO d"}))
+	assert.Equal(t, Key{UserID: 1, Stream: "a"}, b.Add(Key{UserID: 1, Stream: "a"}, []string{"INFO a"}))
+	// Other accounts have their own limit
+	assert.Equal(t, Key{UserID: 2, Stream: "c"}, b.Add(Key{UserID: 2, Stream: "c"}, []string{"INFO c"}))
+	b.mu.Lock()
+	assert.Len(t, b.streams, 4)
+	b.mu.Unlock()
+
+	// Evicting idle streams frees the limit
+	now = now.Add(31 * time.Minute)
+	b.Tick()
+	assert.Equal(t, Key{UserID: 1, Stream: "c"}, b.Add(Key{UserID: 1, Stream: "c"}, []string{"INFO c"}))
+}
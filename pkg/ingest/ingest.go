@@ -0,0 +1,283 @@
+// Package ingest decodes the batches log shippers send (JSON lines, Fluent
+// Bit and Vector JSON, OTLP logs) and buffers them per stream, reporting
+// bursts of errors worth analyzing
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/logs"
+)
+
+// Formats recognised by Decode
+const (
+	// FormatJSONLines is one JSON object or plain text line per line, as sent
+	// by Fluent Bit's json_lines format and Vector's newline delimited framing
+	FormatJSONLines = "json_lines"
+	// FormatJSON is an array of JSON objects, as sent by Fluent Bit's json
+	// format and Vector's json codec
+	FormatJSON = "json"
+	// FormatOTLP is an OTLP/HTTP logs export request in protobuf
+	FormatOTLP = "otlp"
+	// FormatOTLPJSON is an OTLP/HTTP logs export request in JSON
+	FormatOTLPJSON = "otlp_json"
+)
+
+// Record is one log record from a shipper
+type Record struct {
+	Time time.Time
+	// Stream is the service the record names, "" when it names none
+	Stream string
+	// Level is one of the logs.Level* constants, "" when the record states
+	// none. Lines without one are classified by their text.
+	Level   string
+	Message string
+}
+
+// timeLayout writes record times with a fixed width
+const timeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Lines returns the record as log lines: its time, level and the first line
+// of the message, followed by any further lines such as stack frames
+func (r Record) Lines() []string {
+	lines := strings.Split(strings.TrimRight(r.Message, "\r\n"), "\n")
+	var prefix []string
+	if !r.Time.IsZero() {
+		prefix = append(prefix, r.Time.UTC().Format(timeLayout))
+	}
+	if r.Level != "" {
+		prefix = append(prefix, r.Level)
+	}
+	if len(prefix) > 0 {
+		lines[0] = strings.Join(append(prefix, lines[0]), " ")
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	return lines
+}
+
+// Decode reads a batch sent with the given Content-Type. Protobuf bodies are
+// OTLP, JSON objects with "resourceLogs" are OTLP JSON, JSON arrays hold one
+// record per element, and anything else is read line by line: JSON objects
+// as records and other lines as plain text.
+func Decode(contentType string, body []byte) ([]Record, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-protobuf", "application/protobuf":
+		records, err := decodeOTLP(body)
+		return records, FormatOTLP, err
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case len(trimmed) == 0:
+		return nil, FormatJSONLines, nil
+	case trimmed[0] == '[':
+		records, err := decodeArray(trimmed)
+		return records, FormatJSON, err
+	case trimmed[0] == '{' && isOTLPJSON(trimmed):
+		records, err := decodeOTLPJSON(trimmed)
+		return records, FormatOTLPJSON, err
+	}
+	records, err := decodeLines(trimmed)
+	return records, FormatJSONLines, err
+}
+
+func decodeArray(body []byte) ([]Record, error) {
+	var objects []map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("invalid JSON array: %w", err)
+	}
+	records := make([]Record, 0, len(objects))
+	for _, o := range objects {
+		records = append(records, objectRecord(o))
+	}
+	return records, nil
+}
+
+// maxLineBytes caps a single line of a JSON lines body
+const maxLineBytes = 1 << 20
+
+func decodeLines(body []byte) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			var o map[string]interface{}
+			dec := json.NewDecoder(strings.NewReader(line))
+			dec.UseNumber()
+			if dec.Decode(&o) == nil {
+				records = append(records, objectRecord(o))
+				continue
+			}
+		}
+		records = append(records, Record{Message: line})
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return nil, fmt.Errorf("line longer than %d bytes", maxLineBytes)
+	}
+	return records, scanner.Err()
+}
+
+// Field names read from JSON records, in order of preference. Fluent Bit
+// puts container output in "log" and the time in "date".
+var (
+	messageKeys = []string{"message", "msg", "log", "text", "body"}
+	levelKeys   = []string{"level", "severity", "lvl", "loglevel", "log.level", "severity_text"}
+	timeKeys    = []string{"timestamp", "time", "@timestamp", "ts", "date"}
+	streamKeys  = []string{"service.name", "service", "app", "application"}
+)
+
+// objectRecord reads a JSON record. Fields other than the message, level,
+// time and service are appended to the message as key=value pairs.
+func objectRecord(o map[string]interface{}) Record {
+	var r Record
+	if k, v, ok := take(o, messageKeys); ok {
+		r.Message = fieldString(v)
+		delete(o, k)
+	}
+	if k, v, ok := take(o, levelKeys); ok {
+		r.Level = normalizeLevel(v)
+		delete(o, k)
+	}
+	if k, v, ok := take(o, timeKeys); ok {
+		if t, ok := parseTime(v); ok {
+			r.Time = t
+			delete(o, k)
+		}
+	}
+	if k, v, ok := take(o, streamKeys); ok {
+		r.Stream = fieldString(v)
+		delete(o, k)
+	}
+
+	r.Message = appendFields(r.Message, o)
+	return r
+}
+
+func take(o map[string]interface{}, keys []string) (string, interface{}, bool) {
+	for _, k := range keys {
+		if v, ok := o[k]; ok && v != nil {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// appendFields writes fields after a message as sorted key=value pairs
+func appendFields(message string, fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(message)
+	for _, k := range keys {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		value := fieldString(fields[k])
+		if strings.ContainsAny(value, " \"\n") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(k + "=" + value)
+	}
+	return b.String()
+}
+
+// fieldString writes a JSON value as text: strings and numbers as they
+// are, objects and arrays as compact JSON
+func fieldString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// normalizeLevel maps level names and the numeric levels of pino and
+// bunyan onto the logs.Level* constants
+func normalizeLevel(v interface{}) string {
+	if n, ok := v.(json.Number); ok {
+		level, err := n.Int64()
+		if err != nil {
+			return ""
+		}
+		switch {
+		case level >= 60:
+			return logs.LevelFatal
+		case level >= 50:
+			return logs.LevelError
+		case level >= 40:
+			return logs.LevelWarn
+		case level >= 30:
+			return logs.LevelInfo
+		default:
+			return logs.LevelDebug
+		}
+	}
+	return logs.Level(fieldString(v))
+}
+
+// parseTime reads a timestamp string, or epoch seconds, milliseconds,
+// microseconds or nanoseconds told apart by their magnitude. Fluent Bit
+// sends fractional seconds.
+func parseTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, true
+		}
+		return logs.ParseTimestamp(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil || f <= 0 {
+			return time.Time{}, false
+		}
+		return epochTime(f), true
+	}
+	return time.Time{}, false
+}
+
+func epochTime(f float64) time.Time {
+	switch {
+	case f >= 1e17:
+		return time.Unix(0, int64(f))
+	case f >= 1e14:
+		return time.UnixMicro(int64(f))
+	case f >= 1e11:
+		return time.UnixMilli(int64(f))
+	default:
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3)
+	}
+}
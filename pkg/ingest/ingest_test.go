@@ -0,0 +1,148 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var t0 = time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func TestDecodeJSONLines(t *testing.T) {
+	body := `{"time":"2026-01-15T10:00:00Z","level":"error","msg":"payment failed","service":"checkout","order_id":8812,"err":"card declined"}
+{"ts":1768471201.5,"level":50,"message":"db timeout"}
+
+2026-01-15 10:00:02 WARN plain text line
+`
+	records, format, err := Decode("application/x-ndjson", []byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSONLines, format)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, Record{
+		Time:    t0,
+		Stream:  "checkout",
+		Level:   logs.LevelError,
+		Message: `payment failed err="card declined" order_id=8812`,
+	}, records[0])
+	assert.Equal(t, []string{`2026-01-15T10:00:00.000Z ERROR payment failed err="card declined" order_id=8812`}, records[0].Lines())
+
+	assert.Equal(t, t0.Add(time.Second+500*time.Millisecond), records[1].Time.UTC())
+	assert.Equal(t, logs.LevelError, records[1].Level)
+
+	assert.Equal(t, Record{Message: "2026-01-15 10:00:02 WARN plain text line"}, records[2])
+	assert.Equal(t, []string{"2026-01-15 10:00:02 WARN plain text line"}, records[2].Lines())
+}
+
+func TestDecodeFluentBit(t *testing.T) {
+	// Fluent Bit's http output with Format json and a docker parser
+	body := `[
+		{"date":1768471200.25,"log":"panic: runtime error\ngoroutine 1 [running]:\nmain.main()","stream":"stderr"},
+		{"date":1768471201,"log":"listening on :8080","stream":"stdout"}
+	]`
+	records, format, err := Decode("application/json", []byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, t0.Add(250*time.Millisecond), records[0].Time.UTC())
+	assert.Equal(t, []string{
+		"2026-01-15T10:00:00.250Z panic: runtime error",
+		"goroutine 1 [running]:",
+		"main.main() stream=stderr",
+	}, records[0].Lines())
+
+	_, _, err = Decode("application/json", []byte(`[{"log":`))
+	assert.Error(t, err)
+}
+
+func testLogsData() *logspb.LogsData {
+	return &logspb.LogsData{ResourceLogs: []*logspb.ResourceLogs{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "checkout"}}},
+		}},
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			{
+				TimeUnixNano:   uint64(t0.UnixNano()),
+				SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2,
+				Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "payment failed"}},
+				Attributes: []*commonpb.KeyValue{
+					{Key: "order.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 8812}}},
+				},
+				TraceId: []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+			},
+			{
+				ObservedTimeUnixNano: uint64(t0.Add(time.Second).UnixNano()),
+				SeverityText:         "Warning",
+				Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "retrying"}},
+			},
+		}}},
+	}}}
+}
+
+func TestDecodeOTLP(t *testing.T) {
+	body, err := proto.Marshal(testLogsData())
+	require.NoError(t, err)
+
+	records, format, err := Decode("application/x-protobuf", body)
+	require.NoError(t, err)
+	assert.Equal(t, FormatOTLP, format)
+	assert.Equal(t, []Record{
+		{Time: t0, Stream: "checkout", Level: logs.LevelError, Message: "payment failed order.id=8812 trace_id=5b8efff798038103d269b633813fc60c"},
+		{Time: t0.Add(time.Second), Stream: "checkout", Level: logs.LevelWarn, Message: "retrying"},
+	}, utc(records))
+
+	_, _, err = Decode("application/x-protobuf", []byte{0xff, 0xff})
+	assert.Error(t, err)
+}
+
+func TestDecodeOTLPJSON(t *testing.T) {
+	body := `{"resourceLogs":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},
+		"scopeLogs":[{"scope":{"name":"app"},"logRecords":[
+			{
+				"timeUnixNano":"1768471200000000000",
+				"severityNumber":18,
+				"body":{"stringValue":"payment failed"},
+				"attributes":[{"key":"order.id","value":{"intValue":"8812"}}],
+				"traceId":"5b8efff798038103d269b633813fc60c",
+				"spanId":"eee19b7ec3c1b174"
+			},
+			{
+				"observedTimeUnixNano":1768471201000000000,
+				"severityText":"Warning",
+				"body":{"stringValue":"retrying"}
+			},
+			{
+				"timeUnixNano":"1768471202000000000",
+				"body":{"kvlistValue":{"values":[{"key":"event","value":{"stringValue":"shutdown"}},{"key":"ok","value":{"boolValue":true}}]}}
+			}
+		]}]
+	}]}`
+
+	records, format, err := Decode("application/json", []byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, FormatOTLPJSON, format)
+	assert.Equal(t, []Record{
+		{Time: t0, Stream: "checkout", Level: logs.LevelError, Message: "payment failed order.id=8812 trace_id=5b8efff798038103d269b633813fc60c"},
+		{Time: t0.Add(time.Second), Stream: "checkout", Level: logs.LevelWarn, Message: "retrying"},
+		{Time: t0.Add(2 * time.Second), Stream: "checkout", Message: `{"event":"shutdown","ok":true}`},
+	}, utc(records))
+
+	_, _, err = Decode("application/json", []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"zz"}]}]}]}`))
+	assert.Error(t, err)
+}
+
+func utc(records []Record) []Record {
+	for i := range records {
+		records[i].Time = records[i].Time.UTC()
+	}
+	return records
+}
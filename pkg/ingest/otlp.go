@@ -0,0 +1,279 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/logs"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// decodeOTLP reads an ExportLogsServiceRequest, which has the same encoding
+// as LogsData
+func decodeOTLP(body []byte) ([]Record, error) {
+	var data logspb.LogsData
+	if err := proto.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("invalid OTLP protobuf: %w", err)
+	}
+	return otlpRecords(&data), nil
+}
+
+// otlpRecords flattens the records of every resource and scope. The stream
+// is the resource's service.name.
+func otlpRecords(data *logspb.LogsData) []Record {
+	var records []Record
+	for _, rl := range data.GetResourceLogs() {
+		service := ""
+		for _, kv := range rl.GetResource().GetAttributes() {
+			if kv.GetKey() == "service.name" {
+				service = anyValueString(kv.GetValue())
+			}
+		}
+		for _, sl := range rl.GetScopeLogs() {
+			for _, lr := range sl.GetLogRecords() {
+				records = append(records, otlpRecord(lr, service))
+			}
+		}
+	}
+	return records
+}
+
+func otlpRecord(lr *logspb.LogRecord, service string) Record {
+	r := Record{Stream: service, Level: severityLevel(lr.GetSeverityNumber())}
+	if ts := lr.GetTimeUnixNano(); ts != 0 {
+		r.Time = time.Unix(0, int64(ts))
+	} else if ts := lr.GetObservedTimeUnixNano(); ts != 0 {
+		r.Time = time.Unix(0, int64(ts))
+	}
+	if r.Level == "" && lr.GetSeverityText() != "" {
+		r.Level = logs.Level(lr.GetSeverityText())
+	}
+
+	fields := make(map[string]interface{})
+	for _, kv := range lr.GetAttributes() {
+		fields[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	if len(lr.GetTraceId()) > 0 {
+		fields["trace_id"] = hex.EncodeToString(lr.GetTraceId())
+	}
+	r.Message = appendFields(anyValueString(lr.GetBody()), fields)
+	return r
+}
+
+// severityLevel maps OTLP severity numbers onto the logs.Level* constants
+func severityLevel(n logspb.SeverityNumber) string {
+	switch {
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return logs.LevelFatal
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return logs.LevelError
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return logs.LevelWarn
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return logs.LevelInfo
+	case n > logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED:
+		return logs.LevelDebug
+	default:
+		return ""
+	}
+}
+
+// anyValueString writes an attribute or body value as text: scalars as they
+// are, arrays and maps as JSON
+func anyValueString(v *commonpb.AnyValue) string {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue, *commonpb.AnyValue_KvlistValue:
+		b, _ := json.Marshal(anyValueJSON(&commonpb.AnyValue{Value: v}))
+		return string(b)
+	default:
+		return ""
+	}
+}
+
+func anyValueJSON(v *commonpb.AnyValue) interface{} {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_ArrayValue:
+		values := []interface{}{}
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, anyValueJSON(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := map[string]interface{}{}
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.GetKey()] = anyValueJSON(kv.GetValue())
+		}
+		return values
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	default:
+		return anyValueString(&commonpb.AnyValue{Value: v})
+	}
+}
+
+// The OTLP JSON encoding differs from the standard protobuf JSON mapping:
+// trace and span IDs are hex rather than base64. These types decode it into
+// the protobuf messages.
+
+type jsonLogsData struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []jsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []jsonLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type jsonLogRecord struct {
+	TimeUnixNano         jsonInt        `json:"timeUnixNano"`
+	ObservedTimeUnixNano jsonInt        `json:"observedTimeUnixNano"`
+	SeverityNumber       jsonInt        `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 *jsonAnyValue  `json:"body"`
+	Attributes           []jsonKeyValue `json:"attributes"`
+	TraceID              string         `json:"traceId"`
+}
+
+type jsonKeyValue struct {
+	Key   string        `json:"key"`
+	Value *jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string  `json:"stringValue"`
+	BoolValue   *bool    `json:"boolValue"`
+	IntValue    *jsonInt `json:"intValue"`
+	DoubleValue *float64 `json:"doubleValue"`
+	BytesValue  *string  `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []*jsonAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []jsonKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+// jsonInt is a 64-bit integer, which OTLP JSON may send as a string
+type jsonInt int64
+
+func (n *jsonInt) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		u, uerr := strconv.ParseUint(s, 10, 64)
+		if uerr != nil {
+			return fmt.Errorf("invalid integer %s", b)
+		}
+		v = int64(u)
+	}
+	*n = jsonInt(v)
+	return nil
+}
+
+// isOTLPJSON reports whether a JSON object is an OTLP export request
+func isOTLPJSON(body []byte) bool {
+	var probe struct {
+		ResourceLogs json.RawMessage `json:"resourceLogs"`
+	}
+	return json.NewDecoder(bytes.NewReader(body)).Decode(&probe) == nil && probe.ResourceLogs != nil
+}
+
+func decodeOTLPJSON(body []byte) ([]Record, error) {
+	var doc jsonLogsData
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+
+	data := &logspb.LogsData{}
+	for _, jrl := range doc.ResourceLogs {
+		rl := &logspb.ResourceLogs{Resource: &resourcepb.Resource{Attributes: keyValues(jrl.Resource.Attributes)}}
+		for _, jsl := range jrl.ScopeLogs {
+			sl := &logspb.ScopeLogs{}
+			for _, jlr := range jsl.LogRecords {
+				lr := &logspb.LogRecord{
+					TimeUnixNano:         uint64(jlr.TimeUnixNano),
+					ObservedTimeUnixNano: uint64(jlr.ObservedTimeUnixNano),
+					SeverityNumber:       logspb.SeverityNumber(jlr.SeverityNumber),
+					SeverityText:         jlr.SeverityText,
+					Body:                 jlr.Body.proto(),
+					Attributes:           keyValues(jlr.Attributes),
+				}
+				if jlr.TraceID != "" {
+					traceID, err := hex.DecodeString(jlr.TraceID)
+					if err != nil {
+						return nil, fmt.Errorf("invalid OTLP JSON: traceId %q is not hex", jlr.TraceID)
+					}
+					lr.TraceId = traceID
+				}
+				sl.LogRecords = append(sl.LogRecords, lr)
+			}
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		data.ResourceLogs = append(data.ResourceLogs, rl)
+	}
+	return otlpRecords(data), nil
+}
+
+func keyValues(kvs []jsonKeyValue) []*commonpb.KeyValue {
+	var out []*commonpb.KeyValue
+	for _, kv := range kvs {
+		out = append(out, &commonpb.KeyValue{Key: kv.Key, Value: kv.Value.proto()})
+	}
+	return out
+}
+
+func (v *jsonAnyValue) proto() *commonpb.AnyValue {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: *v.StringValue}}
+	case v.BoolValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: *v.BoolValue}}
+	case v.IntValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(*v.IntValue)}}
+	case v.DoubleValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: *v.DoubleValue}}
+	case v.BytesValue != nil:
+		b, _ := base64.StdEncoding.DecodeString(*v.BytesValue)
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: b}}
+	case v.ArrayValue != nil:
+		array := &commonpb.ArrayValue{}
+		for _, item := range v.ArrayValue.Values {
+			array.Values = append(array.Values, item.proto())
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+	case v.KvlistValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: keyValues(v.KvlistValue.Values)}}}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,192 @@
+package logs
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultClockSkew is the tolerance used when aligning sources whose clocks
+// may disagree slightly
+const DefaultClockSkew = 2 * time.Second
+
+// Stats describes one source of a correlated analysis
+type Stats struct {
+	Name     string
+	Lines    int
+	Errors   int
+	Warnings int
+	// First and Last are the earliest and latest timestamps, zero when the
+	// source has none
+	First time.Time
+	Last  time.Time
+}
+
+// record is a timestamped line of one source with the untimestamped lines
+// that follow it, such as stack frames
+type record struct {
+	time time.Time
+	// line is the number of the first line in its source, 1-based
+	line  int
+	lines []string
+}
+
+// Span locates lines of correlated logs in their input: lines Start to End,
+// 1-based and inclusive, are the lines from Line on of the source named Source
+type Span struct {
+	Start  int
+	End    int
+	Source string
+	Line   int
+}
+
+// Correlate merges sources into one timeline, prefixing every line with the
+// name of its source. Records are ordered by timestamp while each source keeps
+// its own order. Records less than skew apart are treated as simultaneous, so
+// the merge does not switch sources for differences that clock skew could
+// explain. Sources without any timestamps are appended after the timeline.
+//
+// Only records within w are kept, and the stats count only those. Untimestamped
+// lines, such as stack frames, go with the record before them; sources without
+// any timestamps are kept whole, since there is nothing to filter on.
+//
+// The spans map the returned lines back to their sources. A single source is
+// not prefixed, and is returned unchanged with no spans when nothing is cut.
+func Correlate(sources []Source, skew time.Duration, w Window) (string, []Stats, []Span) {
+	stats := make([]Stats, len(sources))
+	timed := make([][]record, len(sources))
+	var untimed []int
+
+	for i, src := range sources {
+		stats[i].Name = src.Name
+		records, ok := split(src.Text)
+		if !ok {
+			for _, r := range records {
+				stats[i].count(r)
+			}
+			untimed = append(untimed, i)
+			continue
+		}
+		for _, r := range records {
+			if w.Contains(r.time) {
+				timed[i] = append(timed[i], r)
+				stats[i].count(r)
+			}
+		}
+	}
+
+	if len(sources) == 1 && (w.IsZero() || len(untimed) == 1) {
+		return sources[0].Text, stats, nil
+	}
+
+	var (
+		sb    strings.Builder
+		spans []Span
+		n     int
+	)
+	write := func(i int, r record) {
+		for _, line := range r.lines {
+			if len(sources) > 1 {
+				sb.WriteString("[")
+				sb.WriteString(sources[i].Name)
+				sb.WriteString("] ")
+			}
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+		if len(r.lines) == 0 {
+			return
+		}
+		start := n + 1
+		n += len(r.lines)
+		if last := len(spans) - 1; last >= 0 && spans[last].Source == sources[i].Name &&
+			spans[last].Line+spans[last].End-spans[last].Start+1 == r.line {
+			spans[last].End = n
+			return
+		}
+		spans = append(spans, Span{Start: start, End: n, Source: sources[i].Name, Line: r.line})
+	}
+
+	current := -1
+	for {
+		next := -1
+		for i, records := range timed {
+			if len(records) > 0 && (next < 0 || records[0].time.Before(timed[next][0].time)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		if current >= 0 && current != next && len(timed[current]) > 0 &&
+			timed[current][0].time.Sub(timed[next][0].time) <= skew {
+			next = current
+		}
+		write(next, timed[next][0])
+		timed[next] = timed[next][1:]
+		current = next
+	}
+
+	for _, i := range untimed {
+		write(i, record{line: 1, lines: splitLines(sources[i].Text)})
+	}
+	return sb.String(), stats, spans
+}
+
+// split groups a source into records, reporting whether any line has a
+// timestamp. Lines before the first timestamp are given the time of the
+// first record.
+func split(text string) ([]record, bool) {
+	var records []record
+	timed := false
+	for i, line := range splitLines(text) {
+		t, ok := ParseTimestamp(line)
+		if !ok {
+			if len(records) == 0 {
+				records = append(records, record{line: i + 1})
+			}
+			last := &records[len(records)-1]
+			last.lines = append(last.lines, line)
+			continue
+		}
+
+		if len(records) == 1 && !timed {
+			records[0].time = t
+			records[0].lines = append(records[0].lines, line)
+			timed = true
+			continue
+		}
+		timed = true
+		records = append(records, record{time: t, line: i + 1, lines: []string{line}})
+	}
+	return records, timed
+}
+
+// count adds the lines, errors and warnings of a record and its time
+func (s *Stats) count(r record) {
+	for _, line := range r.lines {
+		s.Lines++
+		switch level := Level(line); {
+		case IsError(level):
+			s.Errors++
+		case level == LevelWarn:
+			s.Warnings++
+		}
+	}
+	if r.time.IsZero() {
+		return
+	}
+	if s.First.IsZero() || r.time.Before(s.First) {
+		s.First = r.time
+	}
+	if r.time.After(s.Last) {
+		s.Last = r.time
+	}
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
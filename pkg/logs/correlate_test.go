@@ -0,0 +1,113 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"2026-01-15T10:23:45.123Z ERROR boom", "2026-01-15T10:23:45.123Z"},
+		{"2026-01-15 10:23:45,500 +0100 WARN slow", "2026-01-15T09:23:45.5Z"},
+		{`{"ts":"2026-01-15T10:23:45+02:00","level":"error"}`, "2026-01-15T08:23:45Z"},
+		{`10.0.0.1 - - [15/Jan/2026:10:23:45 +0000] "GET / HTTP/1.1" 502`, "2026-01-15T10:23:45Z"},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTimestamp(tt.line)
+		require.True(t, ok, tt.line)
+		assert.Equal(t, tt.want, got.UTC().Format(time.RFC3339Nano), tt.line)
+	}
+
+	got, ok := ParseTimestamp("Jan 15 10:23:45 host postgres[12]: restarting")
+	require.True(t, ok)
+	assert.Equal(t, time.Now().Year(), got.Year())
+
+	_, ok = ParseTimestamp("no time here")
+	assert.False(t, ok)
+}
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, LevelError, Level("2026-01-15 ERROR boom"))
+	assert.Equal(t, LevelFatal, Level(`level=panic msg="nil map"`))
+	assert.Equal(t, LevelWarn, Level("[warning] disk at 90%"))
+	assert.Equal(t, "", Level("GET /healthz 200"))
+}
+
+func TestCorrelate(t *testing.T) {
+	api := Source{Name: "api.log", Text: "2026-01-15T10:00:03Z ERROR upstream returned 502\n" +
+		"2026-01-15T10:00:05Z ERROR upstream returned 502\n" +
+		"    at handler.go:42\n"}
+	db := Source{Name: "db.log", Text: "2026-01-15T10:00:01Z LOG database system is shutting down\n" +
+		"2026-01-15T10:00:04Z LOG database system is ready\n"}
+	worker := Source{Name: "worker.log", Text: "job 1 done\njob 2 failed: error\n"}
+
+	merged, stats, spans := Correlate([]Source{api, db, worker}, 0, Window{})
+	assert.Equal(t, "[db.log] 2026-01-15T10:00:01Z LOG database system is shutting down\n"+
+		"[api.log] 2026-01-15T10:00:03Z ERROR upstream returned 502\n"+
+		"[db.log] 2026-01-15T10:00:04Z LOG database system is ready\n"+
+		"[api.log] 2026-01-15T10:00:05Z ERROR upstream returned 502\n"+
+		"[api.log]     at handler.go:42\n"+
+		"[worker.log] job 1 done\n"+
+		"[worker.log] job 2 failed: error\n", merged)
+
+	require.Len(t, stats, 3)
+	assert.Equal(t, Stats{
+		Name:   "api.log",
+		Lines:  3,
+		Errors: 2,
+		First:  time.Date(2026, 1, 15, 10, 0, 3, 0, time.UTC),
+		Last:   time.Date(2026, 1, 15, 10, 0, 5, 0, time.UTC),
+	}, stats[0])
+	assert.True(t, stats[2].First.IsZero())
+	assert.Equal(t, 1, stats[2].Errors)
+	assert.Equal(t, []Span{
+		{Start: 1, End: 1, Source: "db.log", Line: 1},
+		{Start: 2, End: 2, Source: "api.log", Line: 1},
+		{Start: 3, End: 3, Source: "db.log", Line: 2},
+		{Start: 4, End: 5, Source: "api.log", Line: 2},
+		{Start: 6, End: 7, Source: "worker.log", Line: 1},
+	}, spans)
+
+	t.Run("clock skew keeps nearby records together", func(t *testing.T) {
+		merged, _, _ := Correlate([]Source{api, db}, 2*time.Second, Window{})
+		// 10:00:04 is within 2s of 10:00:03, so db.log is not interrupted
+		assert.Equal(t, "[db.log] 2026-01-15T10:00:01Z LOG database system is shutting down\n"+
+			"[db.log] 2026-01-15T10:00:04Z LOG database system is ready\n"+
+			"[api.log] 2026-01-15T10:00:03Z ERROR upstream returned 502\n"+
+			"[api.log] 2026-01-15T10:00:05Z ERROR upstream returned 502\n"+
+			"[api.log]     at handler.go:42\n", merged)
+	})
+
+	t.Run("single source is unchanged", func(t *testing.T) {
+		merged, stats, spans := Correlate([]Source{api}, DefaultClockSkew, Window{})
+		assert.Equal(t, api.Text, merged)
+		assert.Equal(t, 3, stats[0].Lines)
+		assert.Nil(t, spans)
+	})
+
+	t.Run("window counts only the records kept", func(t *testing.T) {
+		w := Window{Since: time.Date(2026, 1, 15, 10, 0, 4, 0, time.UTC)}
+		merged, stats, spans := Correlate([]Source{api, db}, 0, w)
+		assert.Equal(t, "[db.log] 2026-01-15T10:00:04Z LOG database system is ready\n"+
+			"[api.log] 2026-01-15T10:00:05Z ERROR upstream returned 502\n"+
+			"[api.log]     at handler.go:42\n", merged)
+		assert.Equal(t, Stats{
+			Name:   "api.log",
+			Lines:  2,
+			Errors: 1,
+			First:  time.Date(2026, 1, 15, 10, 0, 5, 0, time.UTC),
+			Last:   time.Date(2026, 1, 15, 10, 0, 5, 0, time.UTC),
+		}, stats[0])
+		assert.Equal(t, 1, stats[1].Lines)
+		assert.Equal(t, []Span{
+			{Start: 1, End: 1, Source: "db.log", Line: 2},
+			{Start: 2, End: 3, Source: "api.log", Line: 2},
+		}, spans)
+	})
+}
@@ -0,0 +1,208 @@
+package logs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrTooLarge is returned when the decompressed input exceeds the budget
+var ErrTooLarge = errors.New("decompressed logs exceed the maximum size")
+
+// Source is the text of one log file
+type Source struct {
+	Name string `json:"name"`
+	Text string `json:"-"`
+}
+
+// Budget caps the total number of decompressed bytes read across all sources,
+// so a small archive cannot expand into gigabytes (zip bombs)
+type Budget struct {
+	remaining int64
+}
+
+// NewBudget creates a budget of max bytes
+func NewBudget(max int64) *Budget {
+	return &Budget{remaining: max}
+}
+
+// readAll reads r until EOF, failing with ErrTooLarge once the budget is spent
+func (b *Budget) readAll(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, b.remaining+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > b.remaining {
+		return nil, ErrTooLarge
+	}
+	b.remaining -= int64(len(data))
+	return data, nil
+}
+
+// Decompress wraps r according to an HTTP Content-Encoding value. Decoders
+// use at most limit bytes of memory.
+func Decompress(encoding string, r io.Reader, limit int64) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(r), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		return newZstdReader(r, limit)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// zstdReader fails with ErrTooLarge when a frame needs more memory than allowed
+type zstdReader struct {
+	dec *zstd.Decoder
+}
+
+// newZstdReader decodes zstd with the window and decoded size capped at
+// limit, so that a small frame declaring a huge window cannot make the
+// decoder allocate it
+func newZstdReader(r io.Reader, limit int64) (io.ReadCloser, error) {
+	max := uint64(zstd.MinWindowSize)
+	if limit > zstd.MinWindowSize {
+		max = uint64(limit)
+	}
+	dec, err := zstd.NewReader(r,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(max),
+		zstd.WithDecoderMaxWindow(min(max, zstd.MaxWindowSize)))
+	if err != nil {
+		return nil, err
+	}
+	return zstdReader{dec}, nil
+}
+
+func (z zstdReader) Read(p []byte) (int, error) {
+	n, err := z.dec.Read(p)
+	if errors.Is(err, zstd.ErrWindowSizeExceeded) || errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		err = ErrTooLarge
+	}
+	return n, err
+}
+
+func (z zstdReader) Close() error {
+	z.dec.Close()
+	return nil
+}
+
+// Extract reads a log file, transparently decompressing gzip and zstd and
+// unpacking tar and zip archives. Archives yield one Source per file inside.
+func Extract(name string, r io.Reader, budget *Budget) ([]Source, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		defer gz.Close()
+		return Extract(trimExt(name, ".gz", ".tgz"), gz, budget)
+
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		dec, err := newZstdReader(br, budget.remaining)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		defer dec.Close()
+		return Extract(trimExt(name, ".zst", ".zstd"), dec, budget)
+
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return extractZip(name, br, budget)
+
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return extractTar(br, budget)
+	}
+
+	data, err := budget.readAll(br)
+	if err != nil {
+		return nil, err
+	}
+	return []Source{{Name: name, Text: string(data)}}, nil
+}
+
+func extractTar(r io.Reader, budget *Budget) ([]Source, error) {
+	var sources []Source
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return sources, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || skipEntry(hdr.Name) {
+			continue
+		}
+		inner, err := Extract(hdr.Name, tr, budget)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, inner...)
+	}
+}
+
+func extractZip(name string, r io.Reader, budget *Budget) ([]Source, error) {
+	// zip needs random access; the compressed archive counts against the budget too
+	data, err := budget.readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	var sources []Source
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || skipEntry(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		inner, err := Extract(f.Name, rc, budget)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, inner...)
+	}
+	return sources, nil
+}
+
+// skipEntry ignores archive metadata such as macOS resource forks
+func skipEntry(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, "._")
+}
+
+func trimExt(name string, exts ...string) string {
+	for _, ext := range exts {
+		if strings.HasSuffix(name, ext) {
+			trimmed := strings.TrimSuffix(name, ext)
+			if ext == ".tgz" {
+				trimmed += ".tar"
+			}
+			return trimmed
+		}
+	}
+	return name
+}
@@ -0,0 +1,96 @@
+package logs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// hugeWindowFrame is a zstd frame that declares a 256 MiB window for a
+// single raw block of 11 bytes
+var hugeWindowFrame = append([]byte{
+	0x28, 0xb5, 0x2f, 0xfd, // magic number
+	0x00,             // no content size, checksum or dictionary
+	18 << 3,          // window log 10+18
+	0x59, 0x00, 0x00, // last raw block of 11 bytes
+}, "ERROR boom\n"...)
+
+func TestExtract(t *testing.T) {
+	t.Run("plain text", func(t *testing.T) {
+		sources, err := Extract("app.log", strings.NewReader("ERROR boom\n"), NewBudget(1024))
+		require.NoError(t, err)
+		assert.Equal(t, []Source{{Name: "app.log", Text: "ERROR boom\n"}}, sources)
+	})
+
+	t.Run("gzip rotated log", func(t *testing.T) {
+		data := gzipped(t, []byte("ERROR rotated\n"))
+		sources, err := Extract("app.log.1.gz", bytes.NewReader(data), NewBudget(1024))
+		require.NoError(t, err)
+		assert.Equal(t, []Source{{Name: "app.log.1", Text: "ERROR rotated\n"}}, sources)
+	})
+
+	t.Run("tar.gz archive", func(t *testing.T) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for name, body := range map[string]string{"api.log": "api line\n", "db.log": "db line\n"} {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+			_, err := tw.Write([]byte(body))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+
+		sources, err := Extract("logs.tgz", bytes.NewReader(gzipped(t, buf.Bytes())), NewBudget(4096))
+		require.NoError(t, err)
+		require.Len(t, sources, 2)
+		assert.ElementsMatch(t, []string{"api.log", "db.log"}, []string{sources[0].Name, sources[1].Name})
+	})
+
+	t.Run("zip archive", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("worker.log")
+		require.NoError(t, err)
+		w.Write([]byte("worker line\n"))
+		w, err = zw.Create("__MACOSX/._worker.log")
+		require.NoError(t, err)
+		w.Write([]byte("junk"))
+		require.NoError(t, zw.Close())
+
+		sources, err := Extract("bundle.zip", &buf, NewBudget(4096))
+		require.NoError(t, err)
+		assert.Equal(t, []Source{{Name: "worker.log", Text: "worker line\n"}}, sources)
+	})
+
+	t.Run("zstd window larger than the budget", func(t *testing.T) {
+		_, err := Extract("big.zst", bytes.NewReader(hugeWindowFrame), NewBudget(64<<10))
+		assert.ErrorIs(t, err, ErrTooLarge)
+
+		r, err := Decompress("zstd", bytes.NewReader(hugeWindowFrame), 64<<10)
+		require.NoError(t, err)
+		defer r.Close()
+		_, err = io.ReadAll(r)
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("decompression bomb", func(t *testing.T) {
+		data := gzipped(t, bytes.Repeat([]byte("A"), 1<<20))
+		_, err := Extract("bomb.gz", bytes.NewReader(data), NewBudget(1024))
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+}
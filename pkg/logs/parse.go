@@ -0,0 +1,103 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Log levels returned by Level, most severe first
+const (
+	LevelFatal = "FATAL"
+	LevelError = "ERROR"
+	LevelWarn  = "WARN"
+	LevelInfo  = "INFO"
+	LevelDebug = "DEBUG"
+)
+
+var levelPattern = regexp.MustCompile(`(?i)\b(?:level[=:]\s*"?)?(trace|debug|info|notice|warn|warning|error|err|fatal|crit|critical|panic)\b`)
+
+// Level returns the normalised level of a log line, or "" when it has none
+func Level(line string) string {
+	m := levelPattern.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	switch strings.ToLower(m[1]) {
+	case "fatal", "crit", "critical", "panic":
+		return LevelFatal
+	case "error", "err":
+		return LevelError
+	case "warn", "warning":
+		return LevelWarn
+	case "info", "notice":
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
+// IsError reports whether a level is ERROR or worse
+func IsError(level string) bool {
+	return level == LevelError || level == LevelFatal
+}
+
+// timestampFormat pairs a pattern that finds a timestamp in a line with the
+// layouts used to parse it
+type timestampFormat struct {
+	pattern *regexp.Regexp
+	layouts []string
+	noYear  bool
+}
+
+var timestampFormats = []timestampFormat{
+	// ISO 8601 / RFC 3339: 2026-01-15T10:23:45.123Z, 2026-01-15 10:23:45,123 +0100
+	{
+		pattern: regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:\s?(?:Z|[+-]\d{2}:?\d{2}))?`),
+		layouts: []string{
+			time.RFC3339Nano,
+			"2006-01-02T15:04:05.999999999Z0700",
+			"2006-01-02 15:04:05.999999999Z07:00",
+			"2006-01-02 15:04:05.999999999Z0700",
+			"2006-01-02 15:04:05.999999999 Z07:00",
+			"2006-01-02 15:04:05.999999999 -0700",
+			"2006-01-02 15:04:05.999999999",
+			"2006-01-02T15:04:05.999999999",
+		},
+	},
+	// Common log format: 15/Jan/2026:10:23:45 +0000
+	{
+		pattern: regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2}(?: [+-]\d{4})?`),
+		layouts: []string{"02/Jan/2006:15:04:05 -0700", "02/Jan/2006:15:04:05"},
+	},
+	// Syslog: Jan 15 10:23:45 (no year)
+	{
+		pattern: regexp.MustCompile(`\b[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}\b`),
+		layouts: []string{time.Stamp},
+		noYear:  true,
+	},
+}
+
+// ParseTimestamp finds and parses the first timestamp in a log line. Times
+// without a zone are taken as UTC, and syslog times without a year get the
+// current year.
+func ParseTimestamp(line string) (time.Time, bool) {
+	for _, f := range timestampFormats {
+		ts := f.pattern.FindString(line)
+		if ts == "" {
+			continue
+		}
+		ts = strings.Replace(ts, ",", ".", 1)
+		for _, layout := range f.layouts {
+			t, err := time.Parse(layout, ts)
+			if err != nil {
+				continue
+			}
+			if f.noYear {
+				t = t.AddDate(time.Now().Year(), 0, 0)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
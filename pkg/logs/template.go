@@ -0,0 +1,33 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	templateTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?|\b\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\b`)
+
+	// Variable parts replaced when grouping lines into templates
+	templateReplacements = []struct {
+		pattern *regexp.Regexp
+		with    string
+	}{
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+		{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+		{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]*\d[0-9a-f]*[a-f][0-9a-f]*\b`), "<hex>"},
+		{regexp.MustCompile(`"[^"]*"|'[^']*'`), `"<str>"`},
+		{regexp.MustCompile(`\d+`), "<n>"},
+		{regexp.MustCompile(`\s+`), " "},
+	}
+)
+
+// Template strips the timestamp and variable parts (IDs, addresses, numbers,
+// quoted strings) of a line, so lines that differ only in those group together
+func Template(line string) string {
+	line = templateTimestamp.ReplaceAllString(line, "")
+	for _, r := range templateReplacements {
+		line = r.pattern.ReplaceAllString(line, r.with)
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,84 @@
+package logs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults for incident window detection
+const (
+	DefaultBurstWidth   = 5 * time.Minute
+	DefaultBurstContext = time.Minute
+)
+
+// Window is a time range of log records. A zero Since or Until leaves that
+// side open.
+type Window struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Around returns the window of the given width centred on t
+func Around(t time.Time, width time.Duration) Window {
+	return Window{Since: t.Add(-width / 2), Until: t.Add(width / 2)}
+}
+
+// IsZero reports whether the window is open on both sides
+func (w Window) IsZero() bool {
+	return w.Since.IsZero() && w.Until.IsZero()
+}
+
+// Contains reports whether t falls within the window, bounds included
+func (w Window) Contains(t time.Time) bool {
+	return (w.Since.IsZero() || !t.Before(w.Since)) && (w.Until.IsZero() || !t.After(w.Until))
+}
+
+// ParseTime parses a --since, --until or --around value: a timestamp in any
+// format ParseTimestamp understands, or a duration such as "30m" meaning that
+// long before now
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, ok := ParseTimestamp(value); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a timestamp such as 2026-01-15T10:23:00Z or a duration such as 30m", value)
+}
+
+// DetectBurst finds the width-long span with the most ERROR or worse lines and
+// returns it widened by context on both sides. It reports false when no error
+// line has a timestamp.
+func DetectBurst(logText string, width, context time.Duration) (Window, bool) {
+	var times []time.Time
+	for _, line := range splitLines(logText) {
+		if !IsError(Level(line)) {
+			continue
+		}
+		if t, ok := ParseTimestamp(line); ok {
+			times = append(times, t)
+		}
+	}
+	if len(times) == 0 {
+		return Window{}, false
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	// Slide the window's start over each error; the earliest densest span wins
+	best, bestCount := 0, 0
+	end := 0
+	for start := range times {
+		for end < len(times) && times[end].Sub(times[start]) <= width {
+			end++
+		}
+		if end-start > bestCount {
+			best, bestCount = start, end-start
+		}
+	}
+
+	last := times[best+bestCount-1]
+	return Window{Since: times[best].Add(-context), Until: last.Add(context)}, true
+}
@@ -0,0 +1,78 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dayOfLogs = `2026-01-15T08:00:00Z INFO service started
+2026-01-15T09:00:00Z ERROR cache miss storm
+2026-01-15T10:00:00Z INFO request ok
+2026-01-15T10:01:00Z ERROR upstream returned 502
+    at handler.go:42
+2026-01-15T10:01:30Z ERROR upstream returned 502
+2026-01-15T10:02:00Z FATAL database connection lost
+2026-01-15T10:09:00Z INFO request ok
+2026-01-15T12:00:00Z INFO shutting down
+`
+
+func TestCorrelateWindow(t *testing.T) {
+	filter := func(text string, w Window) (string, []Span) {
+		out, _, spans := Correlate([]Source{{Name: "app.log", Text: text}}, 0, w)
+		return out, spans
+	}
+	since := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 1, 15, 10, 1, 0, 0, time.UTC)
+	out, spans := filter(dayOfLogs, Window{Since: since, Until: until})
+	assert.Equal(t, "2026-01-15T10:00:00Z INFO request ok\n"+
+		"2026-01-15T10:01:00Z ERROR upstream returned 502\n"+
+		"    at handler.go:42\n", out)
+	assert.Equal(t, []Span{{Start: 1, End: 3, Source: "app.log", Line: 3}}, spans)
+
+	out, spans = filter(dayOfLogs, Window{Since: since.Add(time.Hour)})
+	assert.Equal(t, "2026-01-15T12:00:00Z INFO shutting down\n", out)
+	assert.Equal(t, []Span{{Start: 1, End: 1, Source: "app.log", Line: 9}}, spans)
+
+	out, spans = filter("no timestamps\n", Window{Since: since})
+	assert.Equal(t, "no timestamps\n", out)
+	assert.Nil(t, spans)
+
+	out, spans = filter(dayOfLogs, Window{})
+	assert.Equal(t, dayOfLogs, out)
+	assert.Nil(t, spans)
+}
+
+func TestAround(t *testing.T) {
+	at := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	w := Around(at, 10*time.Minute)
+	assert.Equal(t, at.Add(-5*time.Minute), w.Since)
+	assert.Equal(t, at.Add(5*time.Minute), w.Until)
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	got, err := ParseTime("30m", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-30*time.Minute), got)
+
+	got, err = ParseTime("2026-01-15T10:01:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 15, 10, 1, 0, 0, time.UTC), got)
+
+	_, err = ParseTime("yesterday", now)
+	assert.ErrorContains(t, err, "invalid time")
+}
+
+func TestDetectBurst(t *testing.T) {
+	w, ok := DetectBurst(dayOfLogs, 5*time.Minute, time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC), w.Since)
+	assert.Equal(t, time.Date(2026, 1, 15, 10, 3, 0, 0, time.UTC), w.Until)
+
+	_, ok = DetectBurst("2026-01-15T10:00:00Z INFO all good\n", 5*time.Minute, time.Minute)
+	assert.False(t, ok)
+}
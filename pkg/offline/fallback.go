@@ -0,0 +1,37 @@
+package offline
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+)
+
+// FallbackEnabled reports whether servers should fall back to offline analysis
+// when the AI provider is unavailable. Set OFFLINE_FALLBACK=off to disable it.
+func FallbackEnabled() bool {
+	switch strings.ToLower(os.Getenv("OFFLINE_FALLBACK")) {
+	case "off", "false", "0":
+		return false
+	}
+	return true
+}
+
+// Fallback returns an offline analysis of logText when err shows that the AI
+// provider is unavailable and the fallback is enabled. Known issues, the
+// source breakdown, the time window and the line map from opts are carried
+// over to the result.
+func Fallback(logText string, opts ai.Options, err error) (*ai.AnalysisResult, bool) {
+	if err == nil || !errors.Is(err, ai.ErrProviderUnavailable) || !FallbackEnabled() {
+		return nil, false
+	}
+	result := Analyze(logText)
+	result.KnownIssues = opts.KnownIssues
+	if len(opts.Sources) > 1 {
+		result.Sources = opts.Sources
+	}
+	result.Window = opts.Window
+	result.LineMap = opts.LineMap
+	return result, true
+}
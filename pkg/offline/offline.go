@@ -0,0 +1,296 @@
+// Package offline analyzes logs with heuristics and a rule pack, without
+// calling an AI provider
+package offline
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/logs"
+)
+
+// Profile is reported as the analysis profile of offline results
+const Profile = "offline"
+
+// Version identifies the heuristics, like the prompt version of AI results
+const Version = "1"
+
+// Log levels in the order they are reported
+var levels = []string{logs.LevelFatal, logs.LevelError, logs.LevelWarn, logs.LevelInfo, logs.LevelDebug}
+
+var (
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?|\b\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\b`)
+
+	// First lines of stack traces: Go panics and goroutine dumps, Java, Python and Node.js
+	stackTracePattern = regexp.MustCompile(`^panic: |^goroutine \d+ \[|^Exception in thread |^Traceback \(most recent call last\)|^\s+at [\w$.<>]+\(|^\s+at .+:\d+:\d+\)?$`)
+	stackFramePattern = regexp.MustCompile(`^\s+at |^\s+File "|^\t|^\s*\S+\.go:\d+`)
+)
+
+// maxItems caps templates, stack traces and causes listed in a result
+const maxItems = 5
+
+// Analyzer runs the heuristics with a rule pack
+type Analyzer struct {
+	Rules []Rule
+}
+
+// New creates an analyzer with the built-in rules followed by any extra rules
+func New(extra ...Rule) *Analyzer {
+	rules := make([]Rule, 0, len(DefaultRules)+len(extra))
+	rules = append(rules, DefaultRules...)
+	rules = append(rules, extra...)
+	return &Analyzer{Rules: rules}
+}
+
+// Analyze analyzes logText with the built-in rules
+func Analyze(logText string) *ai.AnalysisResult {
+	return New().Analyze(logText)
+}
+
+// match is a rule that fired, with the lines it fired on
+type match struct {
+	rule  Rule
+	lines []int
+}
+
+// template is a group of error lines that differ only in variable parts
+type template struct {
+	text  string
+	count int
+	first int
+}
+
+// Analyze builds a v2 analysis from level counts, error templates, stack
+// traces and rule matches
+func (a *Analyzer) Analyze(logText string) *ai.AnalysisResult {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(logText, "\r\n", "\n"), "\n"), "\n")
+
+	counts := make(map[string]int)
+	matches := make([]*match, len(a.Rules))
+	templates := make(map[string]*template)
+	var traces []int
+	firstError := 0
+
+	for i, line := range lines {
+		n := i + 1
+		level := logs.Level(line)
+		if level != "" {
+			counts[level]++
+		}
+		if logs.IsError(level) && firstError == 0 {
+			firstError = n
+		}
+		if logs.IsError(level) {
+			key := logs.Template(line)
+			if t, ok := templates[key]; ok {
+				t.count++
+			} else {
+				templates[key] = &template{text: key, count: 1, first: n}
+			}
+		}
+		if stackTracePattern.MatchString(line) && (i == 0 || !stackFramePattern.MatchString(lines[i-1])) {
+			traces = append(traces, n)
+		}
+		for r, rule := range a.Rules {
+			if rule.Pattern.MatchString(line) {
+				if matches[r] == nil {
+					matches[r] = &match{rule: rule}
+				}
+				matches[r].lines = append(matches[r].lines, n)
+			}
+		}
+	}
+
+	var fired []*match
+	for _, m := range matches {
+		if m != nil {
+			fired = append(fired, m)
+		}
+	}
+	sort.SliceStable(fired, func(i, j int) bool {
+		if ai.SeverityRank(fired[i].rule.Severity) != ai.SeverityRank(fired[j].rule.Severity) {
+			return ai.SeverityRank(fired[i].rule.Severity) < ai.SeverityRank(fired[j].rule.Severity)
+		}
+		return len(fired[i].lines) > len(fired[j].lines)
+	})
+
+	top := make([]*template, 0, len(templates))
+	for _, t := range templates {
+		top = append(top, t)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].count != top[j].count {
+			return top[i].count > top[j].count
+		}
+		return top[i].first < top[j].first
+	})
+	if len(top) > maxItems {
+		top = top[:maxItems]
+	}
+
+	result := &ai.AnalysisResult{
+		SchemaVersion: ai.SchemaV2,
+		Severity:      severity(fired, counts),
+		Profile:       Profile,
+		PromptVersion: Version,
+	}
+	result.Summary = summary(len(lines), counts, fired, top, traces)
+
+	// Causes follow the fired rules, so the primary issue is the first cause
+	if len(fired) > 0 {
+		result.PrimaryIssue = fired[0].rule.Title
+	} else if len(top) > 0 {
+		result.PrimaryIssue = top[0].text
+	}
+	if firstError > 0 {
+		result.FirstSeen = timestampPattern.FindString(lines[firstError-1])
+		result.Timeline = append(result.Timeline, ai.TimelineEvent{
+			Time:     result.FirstSeen,
+			Event:    "First error: " + stripTimestamp(lines[firstError-1]),
+			Evidence: []ai.LineRange{{Start: firstError, End: firstError}},
+		})
+	}
+
+	total := 0
+	for _, m := range fired {
+		total += len(m.lines)
+	}
+	for i, m := range fired {
+		if i == maxItems {
+			break
+		}
+		result.Causes = append(result.Causes, ai.Cause{
+			Cause:      m.rule.Cause,
+			Confidence: float64(len(m.lines)) / float64(total),
+			Evidence:   ai.LineRanges(m.lines, maxItems),
+		})
+		result.Actions = append(result.Actions, m.rule.Actions...)
+		if m.lines[0] == firstError {
+			continue
+		}
+		result.Timeline = append(result.Timeline, ai.TimelineEvent{
+			Time:     timestampPattern.FindString(lines[m.lines[0]-1]),
+			Event:    m.rule.Title + " first seen",
+			Evidence: []ai.LineRange{{Start: m.lines[0], End: m.lines[0]}},
+		})
+	}
+	sort.SliceStable(result.Timeline, func(i, j int) bool {
+		return result.Timeline[i].Evidence[0].Start < result.Timeline[j].Evidence[0].Start
+	})
+
+	result.Sections = sections(counts, len(lines), fired, top, traces)
+	return result
+}
+
+// stripTimestamp removes the first timestamp from a line
+func stripTimestamp(line string) string {
+	if loc := timestampPattern.FindStringIndex(line); loc != nil {
+		line = line[:loc[0]] + line[loc[1]:]
+	}
+	return strings.TrimSpace(line)
+}
+
+// severity is the most severe fired rule, or is derived from the level counts
+func severity(fired []*match, counts map[string]int) string {
+	if len(fired) > 0 {
+		return fired[0].rule.Severity
+	}
+	switch {
+	case counts[logs.LevelFatal] > 0:
+		return ai.SeverityHigh
+	case counts[logs.LevelError] > 0:
+		return ai.SeverityMedium
+	case counts[logs.LevelWarn] > 0:
+		return ai.SeverityLow
+	default:
+		return ai.SeverityInfo
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func summary(lineCount int, counts map[string]int, fired []*match, top []*template, traces []int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Offline analysis of %s: %s and %s.", plural(lineCount, "line"), plural(counts[logs.LevelError]+counts[logs.LevelFatal], "error"), plural(counts[logs.LevelWarn], "warning"))
+	if len(fired) > 0 {
+		titles := make([]string, len(fired))
+		for i, m := range fired {
+			titles[i] = fmt.Sprintf("%s (%d)", strings.ToLower(m.rule.Title), len(m.lines))
+		}
+		fmt.Fprintf(&sb, " Known failure patterns: %s.", strings.Join(titles, ", "))
+	}
+	if len(top) > 0 {
+		fmt.Fprintf(&sb, " Most frequent error (%s): %s.", plural(top[0].count, "occurrence"), top[0].text)
+	}
+	if len(traces) > 0 {
+		fmt.Fprintf(&sb, " Found %s.", plural(len(traces), "stack trace"))
+	}
+	return sb.String()
+}
+
+func sections(counts map[string]int, lineCount int, fired []*match, top []*template, traces []int) []ai.Section {
+	var out []ai.Section
+
+	if len(fired) > 0 {
+		s := ai.Section{Title: "DETECTED FAILURES"}
+		for _, m := range fired {
+			s.Content = append(s.Content, fmt.Sprintf("%s: %s, first at line %d. %s", m.rule.Title, plural(len(m.lines), "line"), m.lines[0], m.rule.Cause))
+			s.Evidence = append(s.Evidence, ai.LineRanges(m.lines, maxItems))
+		}
+		out = append(out, s)
+	}
+
+	if len(top) > 0 {
+		s := ai.Section{Title: "TOP ERRORS"}
+		for _, t := range top {
+			s.Content = append(s.Content, fmt.Sprintf("%d× %s", t.count, t.text))
+			s.Evidence = append(s.Evidence, []ai.LineRange{{Start: t.first, End: t.first}})
+		}
+		out = append(out, s)
+	}
+
+	if len(traces) > 0 {
+		s := ai.Section{Title: "STACK TRACES"}
+		for i, n := range traces {
+			if i == maxItems {
+				s.Content = append(s.Content, fmt.Sprintf("%d more", len(traces)-maxItems))
+				s.Evidence = append(s.Evidence, nil)
+				break
+			}
+			s.Content = append(s.Content, fmt.Sprintf("Stack trace starting at line %d", n))
+			s.Evidence = append(s.Evidence, []ai.LineRange{{Start: n, End: n}})
+		}
+		out = append(out, s)
+	}
+
+	levelsSection := ai.Section{Title: "LOG LEVELS"}
+	for _, level := range levels {
+		if counts[level] > 0 {
+			levelsSection.Content = append(levelsSection.Content,
+				fmt.Sprintf("%s: %d (%.1f%%)", level, counts[level], 100*float64(counts[level])/float64(lineCount)))
+		}
+	}
+	if len(levelsSection.Content) > 0 {
+		out = append(out, levelsSection)
+	}
+
+	if len(fired) > 0 {
+		s := ai.Section{Title: "IMMEDIATE RESOLUTION"}
+		for _, m := range fired {
+			for _, action := range m.rule.Actions {
+				s.Content = append(s.Content, "→ "+action.Description)
+			}
+		}
+		out = append(out, s)
+	}
+
+	return out
+}
@@ -0,0 +1,82 @@
+package offline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `2026-01-15 10:23:40 INFO server started on :8080
+2026-01-15 10:23:45 ERROR dial tcp 10.0.3.7:5432: connect: connection refused
+2026-01-15 10:23:46 ERROR dial tcp 10.0.3.8:5432: connect: connection refused
+2026-01-15 10:23:47 WARN retrying payment 42
+2026-01-15 10:23:48 FATAL write /var/lib/app/queue: no space left on device
+panic: runtime error: invalid memory address or nil pointer dereference
+	/srv/app/queue.go:88 +0x1d
+goroutine 1 [running]:
+main.main()
+	/srv/app/main.go:12 +0x25
+`
+
+func TestAnalyze(t *testing.T) {
+	result := Analyze(sample)
+
+	assert.Equal(t, ai.SchemaV2, result.SchemaVersion)
+	assert.Equal(t, Profile, result.Profile)
+	assert.Equal(t, ai.SeverityCritical, result.Severity)
+	assert.Equal(t, "Disk full", result.PrimaryIssue)
+	assert.Equal(t, "2026-01-15 10:23:45", result.FirstSeen)
+
+	// Causes are ordered by severity, then by how much of the evidence they
+	// explain, so the first one is the primary issue
+	require.Len(t, result.Causes, 2)
+	assert.Equal(t, rule(t, "disk-full").Cause, result.Causes[0].Cause)
+	assert.Equal(t, []ai.LineRange{{Start: 5, End: 5}}, result.Causes[0].Evidence)
+	assert.InDelta(t, 1.0/3, result.Causes[0].Confidence, 1e-9)
+	assert.Equal(t, rule(t, "connection-refused").Cause, result.Causes[1].Cause)
+	assert.Equal(t, []ai.LineRange{{Start: 2, End: 3}}, result.Causes[1].Evidence)
+
+	require.Len(t, result.Timeline, 2)
+	assert.Equal(t, "First error: ERROR dial tcp 10.0.3.7:5432: connect: connection refused", result.Timeline[0].Event)
+	assert.Equal(t, "Disk full first seen", result.Timeline[1].Event)
+
+	titles := make([]string, len(result.Sections))
+	for i, s := range result.Sections {
+		titles[i] = s.Title
+		for j := range s.Evidence {
+			assert.Less(t, j, len(s.Content), s.Title)
+		}
+	}
+	assert.Equal(t, []string{"DETECTED FAILURES", "TOP ERRORS", "STACK TRACES", "LOG LEVELS", "IMMEDIATE RESOLUTION"}, titles)
+
+	// The two connection refused lines share a template
+	assert.Equal(t, "2× ERROR dial tcp <ip>: connect: connection refused", result.Sections[1].Content[0])
+	// The goroutine dump continues the panic's trace
+	assert.Equal(t, [][]ai.LineRange{{{Start: 6, End: 6}}}, result.Sections[2].Evidence)
+}
+
+func TestFallback(t *testing.T) {
+	_, ok := Fallback(sample, ai.Options{}, fmt.Errorf("failed to parse AI response"))
+	assert.False(t, ok)
+
+	result, ok := Fallback(sample, ai.Options{}, fmt.Errorf("failed to call Google AI: %w", ai.ErrProviderUnavailable))
+	require.True(t, ok)
+	assert.Equal(t, Profile, result.Profile)
+
+	t.Setenv("OFFLINE_FALLBACK", "off")
+	_, ok = Fallback(sample, ai.Options{}, ai.ErrProviderUnavailable)
+	assert.False(t, ok)
+}
+
+func rule(t *testing.T, name string) Rule {
+	for _, r := range DefaultRules {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("no rule %q", name)
+	return Rule{}
+}
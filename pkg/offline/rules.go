@@ -0,0 +1,99 @@
+package offline
+
+import (
+	"regexp"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+)
+
+// Rule recognises a common failure by a pattern on individual log lines
+type Rule struct {
+	Name     string
+	Title    string
+	Severity string
+	// Cause explains what the failure usually means
+	Cause   string
+	Pattern *regexp.Regexp
+	Actions []ai.Action
+}
+
+// DefaultRules is the built-in rule pack
+var DefaultRules = []Rule{
+	{
+		Name:     "connection-refused",
+		Title:    "Connection refused",
+		Severity: ai.SeverityHigh,
+		Cause:    "A dependency is not accepting connections: the service is down, listening on another host or port, or blocked by a firewall",
+		Pattern:  regexp.MustCompile(`(?i)connection refused|ECONNREFUSED`),
+		Actions: []ai.Action{
+			{Description: "Check that the target service is running and listening on the expected port", Command: "nc -vz <host> <port>"},
+			{Description: "Verify the host and port in the client configuration match the service"},
+		},
+	},
+	{
+		Name:     "oom-killed",
+		Title:    "Out of memory",
+		Severity: ai.SeverityCritical,
+		Cause:    "The process ran out of memory and was killed or failed to allocate",
+		Pattern:  regexp.MustCompile(`(?i)OOMKilled|out of memory|cannot allocate memory|java\.lang\.OutOfMemoryError|exit code 137`),
+		Actions: []ai.Action{
+			{Description: "Check memory usage against the container limit", Command: "kubectl top pod <pod>"},
+			{Description: "Raise the memory limit or look for a leak in the growing allocation"},
+		},
+	},
+	{
+		Name:     "disk-full",
+		Title:    "Disk full",
+		Severity: ai.SeverityCritical,
+		Cause:    "A volume ran out of space, so writes are failing",
+		Pattern:  regexp.MustCompile(`(?i)no space left on device|ENOSPC|disk (?:is )?full|disk quota exceeded`),
+		Actions: []ai.Action{
+			{Description: "Find the full filesystem", Command: "df -h"},
+			{Description: "Remove or rotate old logs and temporary files, or grow the volume"},
+		},
+	},
+	{
+		Name:     "tls-handshake",
+		Title:    "TLS handshake failure",
+		Severity: ai.SeverityHigh,
+		Cause:    "TLS negotiation failed: an expired or untrusted certificate, a hostname mismatch or incompatible protocol versions",
+		Pattern:  regexp.MustCompile(`(?i)tls handshake|x509:|certificate (?:has )?expired|certificate verify failed|SSL_ERROR|handshake failure`),
+		Actions: []ai.Action{
+			{Description: "Inspect the certificate chain presented by the server", Command: "openssl s_client -connect <host>:443 -servername <host>"},
+			{Description: "Renew the certificate or add the issuing CA to the client trust store"},
+		},
+	},
+	{
+		Name:     "dns-failure",
+		Title:    "DNS resolution failure",
+		Severity: ai.SeverityHigh,
+		Cause:    "A hostname could not be resolved: a typo, a missing record or an unreachable resolver",
+		Pattern:  regexp.MustCompile(`(?i)no such host|NXDOMAIN|name or service not known|temporary failure in name resolution|ENOTFOUND|server misbehaving`),
+		Actions: []ai.Action{
+			{Description: "Resolve the hostname from the failing host", Command: "nslookup <host>"},
+			{Description: "Check the DNS record and the resolver configuration (/etc/resolv.conf, cluster DNS)"},
+		},
+	},
+	{
+		Name:     "deadlock",
+		Title:    "Deadlock",
+		Severity: ai.SeverityHigh,
+		Cause:    "Transactions or goroutines are waiting on each other's locks",
+		Pattern:  regexp.MustCompile(`(?i)deadlock detected|deadlock found|\b40P01\b|all goroutines are asleep`),
+		Actions: []ai.Action{
+			{Description: "Acquire locks in a consistent order and keep transactions short"},
+			{Description: "Retry transactions that fail with a deadlock error"},
+		},
+	},
+	{
+		Name:     "gateway-timeout",
+		Title:    "Gateway or upstream timeout",
+		Severity: ai.SeverityMedium,
+		Cause:    "An upstream call took longer than the proxy or client deadline",
+		Pattern:  regexp.MustCompile(`(?i)504 gateway time-?out|upstream timed out|gateway timeout|context deadline exceeded`),
+		Actions: []ai.Action{
+			{Description: "Find the slow upstream dependency and check its latency and saturation"},
+			{Description: "Align proxy and client timeouts with the upstream's expected latency"},
+		},
+	},
+}
@@ -0,0 +1,144 @@
+package rules
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/AyomiCoder/loggar/pkg/logs"
+)
+
+// maxEvidence caps the line ranges reported per known issue
+const maxEvidence = 5
+
+var (
+	logfmtPattern = regexp.MustCompile(`([\w.\-]+)=("(?:[^"\\]|\\.)*"|\S*)`)
+)
+
+// Match runs the rules over logText and returns the issues that fired, most
+// severe first
+func Match(rules []*Rule, logText string) []ai.KnownIssue {
+	if len(rules) == 0 {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(logText, "\r\n", "\n"), "\n"), "\n")
+
+	needFields := false
+	for _, r := range rules {
+		if len(r.fields) > 0 {
+			needFields = true
+			break
+		}
+	}
+
+	matched := make([][]int, len(rules))
+	for i, line := range lines {
+		var fields map[string]string
+		if needFields {
+			fields = parseFields(line)
+		}
+		for r, rule := range rules {
+			if rule.matches(line, fields) {
+				matched[r] = append(matched[r], i+1)
+			}
+		}
+	}
+
+	var issues []ai.KnownIssue
+	for r, rule := range rules {
+		if len(matched[r]) == 0 || !rule.fires(matched[r], lines) {
+			continue
+		}
+		issues = append(issues, ai.KnownIssue{
+			Rule:        rule.ID,
+			Title:       rule.Title,
+			Severity:    rule.Severity,
+			Remediation: rule.Remediation,
+			RunbookURL:  rule.Runbook,
+			Count:       len(matched[r]),
+			Evidence:    ai.LineRanges(matched[r], maxEvidence),
+		})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if ai.SeverityRank(issues[i].Severity) != ai.SeverityRank(issues[j].Severity) {
+			return ai.SeverityRank(issues[i].Severity) < ai.SeverityRank(issues[j].Severity)
+		}
+		return issues[i].Count > issues[j].Count
+	})
+	return issues
+}
+
+// fires applies the rule's window to its matching line numbers
+func (r *Rule) fires(matched []int, lines []string) bool {
+	if r.Window == nil {
+		return true
+	}
+	if len(matched) < r.Window.Count {
+		return false
+	}
+	if r.within == 0 {
+		return true
+	}
+
+	var times []time.Time
+	for _, n := range matched {
+		if t, ok := logs.ParseTimestamp(lines[n-1]); ok {
+			times = append(times, t)
+		}
+	}
+	if len(times) == 0 {
+		// No timestamps to window on: the count alone decides
+		return true
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	for i := 0; i+r.Window.Count-1 < len(times); i++ {
+		if times[i+r.Window.Count-1].Sub(times[i]) <= r.within {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFields reads the fields of a JSON object line or a logfmt line
+func parseFields(line string) map[string]string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &obj); err == nil {
+			fields := make(map[string]string, len(obj))
+			for k, v := range obj {
+				switch v := v.(type) {
+				case string:
+					fields[k] = v
+				default:
+					b, _ := json.Marshal(v)
+					fields[k] = string(b)
+				}
+			}
+			return fields
+		}
+	}
+
+	fields := make(map[string]string)
+	for _, m := range logfmtPattern.FindAllStringSubmatch(line, -1) {
+		value := m[2]
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		fields[m[1]] = value
+	}
+	return fields
+}
+
+func unquote(s string) (string, error) {
+	var out string
+	err := json.Unmarshal([]byte(s), &out)
+	return out, err
+}
@@ -0,0 +1,190 @@
+// Package rules matches user-defined failure signatures, loaded from YAML,
+// against logs before they are sent for analysis
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/goccy/go-yaml"
+)
+
+// Rule is a known failure signature. A line matches when the pattern (if any)
+// matches anywhere in the line, unless anchored with ^ or $, and every field
+// matcher matches the value of that field in a JSON or logfmt line. With a window, the rule only fires once
+// Count matching lines fall within Within of each other.
+//
+//	id: pg-pool-exhausted
+//	title: Postgres connection pool exhausted
+//	severity: high
+//	match:
+//	  pattern: 'remaining connection slots are reserved'
+//	  fields:
+//	    service: '^payments'
+//	window:
+//	  count: 5
+//	  within: 1m
+//	remediation: Raise PGBOUNCER_POOL_SIZE or find the connection leak
+//	runbook: https://wiki.example.com/runbooks/pg-pool
+type Rule struct {
+	ID          string  `yaml:"id"`
+	Title       string  `yaml:"title"`
+	Severity    string  `yaml:"severity"`
+	Match       Matcher `yaml:"match"`
+	Window      *Window `yaml:"window"`
+	Remediation string  `yaml:"remediation"`
+	Runbook     string  `yaml:"runbook"`
+
+	pattern *regexp.Regexp
+	fields  map[string]*regexp.Regexp
+	within  time.Duration
+}
+
+// Matcher selects lines by a regular expression and/or structured fields
+type Matcher struct {
+	Pattern string            `yaml:"pattern"`
+	Fields  map[string]string `yaml:"fields"`
+}
+
+// Window requires Count matches within a duration such as "30s" or "5m"
+type Window struct {
+	Count  int    `yaml:"count"`
+	Within string `yaml:"within"`
+}
+
+// file is the layout of a rules file: a list under "rules", or a single rule
+type file struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// DefaultDir returns the per-user rules directory, ~/.loggar/rules
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".loggar", "rules")
+}
+
+// LoadDir loads every *.yaml and *.yml file in dir. A missing directory yields no rules.
+func LoadDir(dir string) ([]*Rule, error) {
+	var files []string
+	for _, ext := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, ext))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var rules []*Rule
+	for _, path := range files {
+		loaded, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, loaded...)
+	}
+	return rules, nil
+}
+
+// LoadFile loads the rules in one YAML file
+func LoadFile(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Parse parses and validates YAML rules
+func Parse(data []byte) ([]*Rule, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Rules) == 0 {
+		var single Rule
+		if err := yaml.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		if single.ID == "" && single.Title == "" {
+			return nil, nil
+		}
+		f.Rules = []*Rule{&single}
+	}
+
+	for _, r := range f.Rules {
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return f.Rules, nil
+}
+
+// compile validates the rule and prepares its matchers
+func (r *Rule) compile() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule %q has no id", r.Title)
+	}
+	if r.Title == "" {
+		r.Title = r.ID
+	}
+	if r.Match.Pattern == "" && len(r.Match.Fields) == 0 {
+		return fmt.Errorf("rule %s: match needs a pattern or fields", r.ID)
+	}
+
+	r.Severity = strings.ToLower(r.Severity)
+	switch r.Severity {
+	case "", ai.SeverityCritical, ai.SeverityHigh, ai.SeverityMedium, ai.SeverityLow, ai.SeverityInfo:
+	default:
+		return fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+	}
+
+	var err error
+	if r.Match.Pattern != "" {
+		if r.pattern, err = regexp.Compile(r.Match.Pattern); err != nil {
+			return fmt.Errorf("rule %s: %w", r.ID, err)
+		}
+	}
+	r.fields = make(map[string]*regexp.Regexp, len(r.Match.Fields))
+	for name, expr := range r.Match.Fields {
+		if r.fields[name], err = regexp.Compile(expr); err != nil {
+			return fmt.Errorf("rule %s: field %s: %w", r.ID, name, err)
+		}
+	}
+
+	if r.Window != nil {
+		if r.Window.Count < 1 {
+			return fmt.Errorf("rule %s: window count must be at least 1", r.ID)
+		}
+		if r.Window.Within != "" {
+			if r.within, err = time.ParseDuration(r.Window.Within); err != nil {
+				return fmt.Errorf("rule %s: window: %w", r.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether a single line matches the rule
+func (r *Rule) matches(line string, fields map[string]string) bool {
+	if r.pattern != nil && !r.pattern.MatchString(line) {
+		return false
+	}
+	for name, re := range r.fields {
+		value, ok := fields[name]
+		if !ok || !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
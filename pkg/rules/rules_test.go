@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ruleFile = `
+rules:
+  - id: pg-pool
+    title: Postgres pool exhausted
+    severity: high
+    match:
+      pattern: 'remaining connection slots'
+    remediation: Raise PGBOUNCER_POOL_SIZE
+    runbook: https://wiki.example.com/pg-pool
+  - id: payments-5xx-burst
+    title: Burst of payment errors
+    severity: critical
+    match:
+      fields:
+        service: '^payments$'
+        level: '^error$'
+    window:
+      count: 3
+      within: 1m
+`
+
+func TestParseAndMatch(t *testing.T) {
+	rules, err := Parse([]byte(ruleFile))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+
+	logs := `2026-01-15T10:00:00Z level=error service=payments msg="charge failed"
+2026-01-15T10:00:10Z level=error service=auth msg="FATAL: remaining connection slots are reserved"
+{"time":"2026-01-15T10:00:20Z","level":"error","service":"payments","msg":"charge failed"}
+2026-01-15T10:00:30Z level=error service=payments msg="charge failed"
+`
+	issues := Match(rules, logs)
+	require.Len(t, issues, 2)
+
+	assert.Equal(t, "payments-5xx-burst", issues[0].Rule)
+	assert.Equal(t, 3, issues[0].Count)
+	assert.Equal(t, []ai.LineRange{{Start: 1, End: 1}, {Start: 3, End: 4}}, issues[0].Evidence)
+
+	assert.Equal(t, ai.KnownIssue{
+		Rule:        "pg-pool",
+		Title:       "Postgres pool exhausted",
+		Severity:    ai.SeverityHigh,
+		Remediation: "Raise PGBOUNCER_POOL_SIZE",
+		RunbookURL:  "https://wiki.example.com/pg-pool",
+		Count:       1,
+		Evidence:    []ai.LineRange{{Start: 2, End: 2}},
+	}, issues[1])
+}
+
+func TestPatternMatchesAnywhere(t *testing.T) {
+	rules, err := Parse([]byte("rules:\n  - id: oom\n    match:\n      pattern: OOMKilled\n  - id: panic\n    match:\n      pattern: '^panic:'\n"))
+	require.NoError(t, err)
+
+	logs := `container app was OOMKilled after 3 restarts
+goroutine 1 panic: nil map
+panic: nil map
+`
+	issues := Match(rules, logs)
+	require.Len(t, issues, 2)
+	assert.Equal(t, "oom", issues[0].Rule)
+	assert.Equal(t, []ai.LineRange{{Start: 1, End: 1}}, issues[0].Evidence)
+	assert.Equal(t, "panic", issues[1].Rule)
+	assert.Equal(t, []ai.LineRange{{Start: 3, End: 3}}, issues[1].Evidence)
+}
+
+func TestWindow(t *testing.T) {
+	rules, err := Parse([]byte(ruleFile))
+	require.NoError(t, err)
+
+	// Three payment errors, but spread over more than a minute
+	logs := `2026-01-15T10:00:00Z level=error service=payments
+2026-01-15T10:01:00Z level=error service=payments
+2026-01-15T10:02:00Z level=error service=payments
+`
+	assert.Empty(t, Match(rules, logs))
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"missing id":        "title: x\nmatch:\n  pattern: y\n",
+		"missing matcher":   "id: x\n",
+		"bad regexp":        "id: x\nmatch:\n  pattern: '('\n",
+		"bad severity":      "id: x\nseverity: urgent\nmatch:\n  pattern: y\n",
+		"bad window":        "id: x\nmatch:\n  pattern: y\nwindow:\n  count: 2\n  within: soon\n",
+		"zero window count": "id: x\nmatch:\n  pattern: y\nwindow:\n  count: 0\n",
+	} {
+		_, err := Parse([]byte(doc))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team.yaml"), []byte(ruleFile), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "single.yml"), []byte("id: oom\nmatch:\n  pattern: OOMKilled\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a rule"), 0o644))
+
+	rules, err := LoadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, rules, 3)
+
+	rules, err = LoadDir(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
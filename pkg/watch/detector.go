@@ -0,0 +1,233 @@
+package watch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/logs"
+)
+
+// Config tunes burst detection. Zero values take the defaults.
+type Config struct {
+	// Threshold errors within Window start a burst (default 5)
+	Threshold int
+	Window    time.Duration // default 30s
+	// Quiet is how long without errors ends a burst (default 15s)
+	Quiet time.Duration
+	// Context is the number of lines kept from before a burst (default 50)
+	Context int
+	// MaxLines caps the lines of one incident; a longer burst is analyzed in parts (default 2000)
+	MaxLines int
+	// DedupFor suppresses incidents with the same fingerprint for this long (default 30m)
+	DedupFor time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Threshold <= 0 {
+		c.Threshold = 5
+	}
+	if c.Window <= 0 {
+		c.Window = 30 * time.Second
+	}
+	if c.Quiet <= 0 {
+		c.Quiet = 15 * time.Second
+	}
+	if c.Context <= 0 {
+		c.Context = 50
+	}
+	if c.MaxLines <= 0 {
+		c.MaxLines = 2000
+	}
+	if c.DedupFor <= 0 {
+		c.DedupFor = 30 * time.Minute
+	}
+	return c
+}
+
+// Incident is a burst of errors together with the lines around it
+type Incident struct {
+	Start  time.Time
+	End    time.Time
+	Lines  []string
+	Errors int
+	// Fingerprint identifies incidents made of the same kinds of errors
+	Fingerprint string
+}
+
+// Logs returns the incident's lines as log text
+func (i *Incident) Logs() string {
+	return strings.Join(i.Lines, "\n") + "\n"
+}
+
+// Detector finds error bursts in lines as they arrive. Times are arrival
+// times, so logs without timestamps work too.
+type Detector struct {
+	cfg Config
+
+	// before holds the lines since the first error still within cfg.Window,
+	// plus cfg.Context lines ahead of it, while no burst is open
+	before []buffered
+	// errors are the recent errors, within cfg.Window
+	errors []buffered
+	seq    int
+
+	burst     *Incident
+	lastError time.Time
+	templates map[string]int
+}
+
+// buffered is a line with its arrival time and position in the input
+type buffered struct {
+	line string
+	at   time.Time
+	seq  int
+}
+
+// NewDetector creates a detector with cfg
+func NewDetector(cfg Config) *Detector {
+	return &Detector{cfg: cfg.withDefaults()}
+}
+
+// Add records a line that arrived at now and returns an incident if it
+// completed one
+func (d *Detector) Add(line string, now time.Time) *Incident {
+	done := d.Tick(now)
+	isError := logs.IsError(logs.Level(line))
+
+	if d.burst != nil {
+		d.burst.Lines = append(d.burst.Lines, line)
+		if isError {
+			d.burst.Errors++
+			d.burst.End = now
+			d.lastError = now
+			d.templates[logs.Template(line)]++
+		}
+		if len(d.burst.Lines) >= d.cfg.MaxLines {
+			return d.close()
+		}
+		return done
+	}
+
+	d.seq++
+	entry := buffered{line: line, at: now, seq: d.seq}
+	d.before = append(d.before, entry)
+	if isError {
+		d.errors = append(d.errors, entry)
+	}
+	cutoff := now.Add(-d.cfg.Window)
+	for len(d.errors) > 0 && d.errors[0].at.Before(cutoff) {
+		d.errors = d.errors[1:]
+	}
+
+	if len(d.errors) >= d.cfg.Threshold {
+		d.open(now)
+		return done
+	}
+
+	keepFrom := d.seq + 1
+	if len(d.errors) > 0 {
+		keepFrom = d.errors[0].seq
+	}
+	keepFrom -= d.cfg.Context
+	for len(d.before) > 0 && (d.before[0].seq < keepFrom || len(d.before) > d.cfg.MaxLines) {
+		d.before = d.before[1:]
+	}
+	return done
+}
+
+// Tick ends the open burst once no error has arrived for the quiet period
+func (d *Detector) Tick(now time.Time) *Incident {
+	if d.burst == nil || now.Sub(d.lastError) < d.cfg.Quiet {
+		return nil
+	}
+	return d.close()
+}
+
+// Flush ends the open burst, e.g. when the input ends
+func (d *Detector) Flush() *Incident {
+	if d.burst == nil {
+		return nil
+	}
+	return d.close()
+}
+
+// open starts a burst with the buffered lines. Errors among the context
+// lines ahead of the window are not counted.
+func (d *Detector) open(now time.Time) {
+	d.burst = &Incident{Start: d.errors[0].at, End: now}
+	d.templates = make(map[string]int)
+	for _, b := range d.before {
+		d.burst.Lines = append(d.burst.Lines, b.line)
+		if b.seq >= d.errors[0].seq && logs.IsError(logs.Level(b.line)) {
+			d.burst.Errors++
+			d.templates[logs.Template(b.line)]++
+		}
+	}
+	d.lastError = now
+	d.before = nil
+	d.errors = nil
+}
+
+func (d *Detector) close() *Incident {
+	inc := d.burst
+	inc.Fingerprint = fingerprint(d.templates)
+	d.burst = nil
+	d.templates = nil
+	return inc
+}
+
+// fingerprint hashes the three most frequent error templates, so the same
+// failure recurring with different IDs and timestamps is recognised
+func fingerprint(templates map[string]int) string {
+	keys := make([]string, 0, len(templates))
+	for k := range templates {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if templates[keys[i]] != templates[keys[j]] {
+			return templates[keys[i]] > templates[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > 3 {
+		keys = keys[:3]
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Deduper remembers incident fingerprints for a while
+type Deduper struct {
+	// OnExpire, if set, is called with each fingerprint that is forgotten
+	OnExpire func(fingerprint string)
+
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+// NewDeduper creates a deduper that forgets fingerprints after ttl
+func NewDeduper(ttl time.Duration) *Deduper {
+	return &Deduper{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// Seen records the fingerprint and reports whether it was already seen
+// within the ttl
+func (d *Deduper) Seen(fingerprint string, now time.Time) bool {
+	for fp, at := range d.seen {
+		if now.Sub(at) > d.ttl {
+			delete(d.seen, fp)
+			if d.OnExpire != nil {
+				d.OnExpire(fp)
+			}
+		}
+	}
+	_, seen := d.seen[fingerprint]
+	if !seen {
+		d.seen[fingerprint] = now
+	}
+	return seen
+}
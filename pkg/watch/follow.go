@@ -0,0 +1,182 @@
+// Package watch triages logs continuously: it follows a file or stream,
+// detects bursts of errors and analyzes each burst once it has ended
+package watch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultPoll is how often a followed file is checked for new data and rotation
+const DefaultPoll = 250 * time.Millisecond
+
+// maxLineBytes caps a single line read from a stream
+const maxLineBytes = 1 << 20
+
+// Follow sends the lines appended to path until ctx is cancelled, like
+// tail -F. It starts at the end of the file unless fromStart is set. When the
+// file is rotated (renamed or replaced), the rest of the old file is read and
+// the new one is followed from its start; a truncated file is re-read from
+// the beginning. A file that does not exist yet is waited for.
+func Follow(ctx context.Context, path string, fromStart bool, poll time.Duration, lines chan<- string) error {
+	if poll <= 0 {
+		poll = DefaultPoll
+	}
+
+	var (
+		f      *os.File
+		info   os.FileInfo
+		reader *bufio.Reader
+		offset int64
+		// partial holds a line whose newline has not been written yet
+		partial []byte
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	open := func(seekEnd bool) error {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			f = nil
+			return err
+		}
+		if info, err = f.Stat(); err != nil {
+			return err
+		}
+		offset = 0
+		if seekEnd {
+			if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+				return err
+			}
+		}
+		reader = bufio.NewReader(f)
+		partial = partial[:0]
+		return nil
+	}
+
+	if err := open(!fromStart); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for {
+		// Read everything available
+		for f != nil {
+			chunk, err := reader.ReadSlice('\n')
+			offset += int64(len(chunk))
+			partial = append(partial, chunk...)
+			if err == bufio.ErrBufferFull {
+				if len(partial) > maxLineBytes {
+					partial = partial[:0]
+				}
+				continue
+			}
+			if err != nil {
+				break
+			}
+			line := string(trimNewline(partial))
+			partial = partial[:0]
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+
+		current, err := os.Stat(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// Rotated away and not recreated yet: keep reading the old file
+			continue
+		case err != nil:
+			return err
+		case f == nil:
+			if err := open(false); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		case !os.SameFile(info, current):
+			// Rotated: the loop above has drained the old file, unless more
+			// was written after the last read, so drain once more first
+			if rest, _ := io.ReadAll(reader); len(rest) > 0 {
+				partial = append(partial, rest...)
+				for _, line := range splitComplete(&partial) {
+					select {
+					case lines <- line:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}
+			if len(partial) > 0 {
+				select {
+				case lines <- string(partial):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			f.Close()
+			if err := open(false); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		case current.Size() < offset:
+			// Truncated in place (copytruncate)
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			reader.Reset(f)
+			offset = 0
+			partial = partial[:0]
+		}
+	}
+}
+
+// Lines sends each line read from r, such as stdin fed by `kubectl logs -f`,
+// until r is exhausted or ctx is cancelled
+func Lines(ctx context.Context, r io.Reader, lines chan<- string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		select {
+		case lines <- scanner.Text():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return scanner.Err()
+}
+
+// splitComplete removes and returns the newline-terminated lines at the start of buf
+func splitComplete(buf *[]byte) []string {
+	var out []string
+	start := 0
+	for i, b := range *buf {
+		if b == '\n' {
+			out = append(out, string(trimNewline((*buf)[start:i+1])))
+			start = i + 1
+		}
+	}
+	*buf = append((*buf)[:0], (*buf)[start:]...)
+	return out
+}
+
+func trimNewline(b []byte) []byte {
+	if n := len(b); n > 0 && b[n-1] == '\n' {
+		b = b[:n-1]
+	}
+	if n := len(b); n > 0 && b[n-1] == '\r' {
+		b = b[:n-1]
+	}
+	return b
+}
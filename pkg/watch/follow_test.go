@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("old line\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := make(chan string, 10)
+	go Follow(ctx, path, false, 10*time.Millisecond, lines)
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a line")
+			return ""
+		}
+	}
+	appendTo := func(text string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+		require.NoError(t, err)
+		_, err = f.WriteString(text)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	appendTo("first\nsec")
+	assert.Equal(t, "first", next())
+	appendTo("ond\n")
+	assert.Equal(t, "second", next())
+
+	// Rotate: rename the file and start a new one
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendTo("after rotation\n")
+	assert.Equal(t, "after rotation", next())
+
+	// Truncate in place
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, os.Truncate(path, 0))
+	time.Sleep(30 * time.Millisecond)
+	appendTo("after truncate\n")
+	assert.Equal(t, "after truncate", next())
+}
+
+func TestLines(t *testing.T) {
+	lines := make(chan string, 10)
+	require.NoError(t, Lines(context.Background(), strings.NewReader("a\r\nb\n"), lines))
+	close(lines)
+	var got []string
+	for line := range lines {
+		got = append(got, line)
+	}
+	assert.Equal(t, []string{"a", "b"}, got)
+}
@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+)
+
+// tickInterval is how often an open burst is checked for having ended
+const tickInterval = time.Second
+
+// Report is the outcome of one incident: its analysis, or the error from the
+// analyzer. Duplicate incidents are not analyzed again: Result is the earlier
+// analysis of the same fingerprint, if it succeeded. Occurrences counts
+// the incidents with the same fingerprint since it was last analyzed,
+// including this one.
+type Report struct {
+	Incident    *Incident
+	Result      *ai.AnalysisResult
+	Err         error
+	Duplicate   bool
+	Occurrences int
+}
+
+// Watcher analyzes each error burst in a stream of lines
+type Watcher struct {
+	// Analyze runs the analysis of an incident's logs, e.g. ai.AnalyzeLogs or
+	// a call to the loggar server
+	Analyze func(logText string) (*ai.AnalysisResult, error)
+	// OnReport is called for every incident, in order
+	OnReport func(Report)
+
+	cfg     Config
+	now     func() time.Time
+	dedup   *Deduper
+	count   map[string]int
+	results map[string]*ai.AnalysisResult
+}
+
+// New creates a watcher with cfg
+func New(cfg Config, analyze func(string) (*ai.AnalysisResult, error), onReport func(Report)) *Watcher {
+	cfg = cfg.withDefaults()
+	w := &Watcher{
+		Analyze:  analyze,
+		OnReport: onReport,
+		cfg:      cfg,
+		now:      time.Now,
+		dedup:    NewDeduper(cfg.DedupFor),
+		count:    make(map[string]int),
+		results:  make(map[string]*ai.AnalysisResult),
+	}
+	// Counts and results are kept only as long as their fingerprint
+	w.dedup.OnExpire = func(fp string) {
+		delete(w.count, fp)
+		delete(w.results, fp)
+	}
+	return w
+}
+
+// Run reads lines until the channel is closed or ctx is cancelled. A burst
+// still open when the input ends is analyzed before Run returns; one open
+// when ctx is cancelled is dropped. Analyses run one at a time, and lines
+// arriving meanwhile wait in the channel.
+func (w *Watcher) Run(ctx context.Context, lines <-chan string) error {
+	detector := NewDetector(w.cfg)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				w.handle(detector.Flush())
+				return nil
+			}
+			w.handle(detector.Add(line, w.now()))
+		case <-ticker.C:
+			w.handle(detector.Tick(w.now()))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *Watcher) handle(inc *Incident) {
+	if inc == nil {
+		return
+	}
+	report := Report{Incident: inc}
+	if w.dedup.Seen(inc.Fingerprint, w.now()) {
+		w.count[inc.Fingerprint]++
+		report.Duplicate = true
+		report.Result = w.results[inc.Fingerprint]
+	} else {
+		w.count[inc.Fingerprint] = 1
+		report.Result, report.Err = w.Analyze(inc.Logs())
+		w.results[inc.Fingerprint] = report.Result
+	}
+	report.Occurrences = w.count[inc.Fingerprint]
+	if w.OnReport != nil {
+		w.OnReport(report)
+	}
+}
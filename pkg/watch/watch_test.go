@@ -0,0 +1,145 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AyomiCoder/loggar/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func TestDetector(t *testing.T) {
+	d := NewDetector(Config{Threshold: 3, Window: 10 * time.Second, Quiet: 5 * time.Second, Context: 2})
+
+	assert.Nil(t, d.Add("INFO boot", t0))
+	assert.Nil(t, d.Add("INFO ready", t0.Add(time.Second)))
+	assert.Nil(t, d.Add("ERROR dial tcp 10.0.0.1:5432: connection refused", t0.Add(2*time.Second)))
+	// Errors too far apart do not make a burst
+	assert.Nil(t, d.Add("ERROR dial tcp 10.0.0.2:5432: connection refused", t0.Add(20*time.Second)))
+	assert.Nil(t, d.Add("ERROR dial tcp 10.0.0.3:5432: connection refused", t0.Add(21*time.Second)))
+	assert.Nil(t, d.Add("ERROR dial tcp 10.0.0.4:5432: connection refused", t0.Add(22*time.Second)))
+	assert.Nil(t, d.Add("INFO retrying", t0.Add(23*time.Second)))
+	assert.Nil(t, d.Tick(t0.Add(26*time.Second)))
+
+	inc := d.Tick(t0.Add(27 * time.Second))
+	require.NotNil(t, inc)
+	assert.Equal(t, t0.Add(20*time.Second), inc.Start)
+	assert.Equal(t, t0.Add(22*time.Second), inc.End)
+	assert.Equal(t, 3, inc.Errors)
+	// Two lines of context were kept from before the burst
+	assert.Equal(t, []string{
+		"INFO ready",
+		"ERROR dial tcp 10.0.0.1:5432: connection refused",
+		"ERROR dial tcp 10.0.0.2:5432: connection refused",
+		"ERROR dial tcp 10.0.0.3:5432: connection refused",
+		"ERROR dial tcp 10.0.0.4:5432: connection refused",
+		"INFO retrying",
+	}, inc.Lines)
+
+	assert.Nil(t, d.Tick(t0.Add(time.Minute)))
+	assert.Nil(t, d.Flush())
+}
+
+func TestDetectorMaxLines(t *testing.T) {
+	d := NewDetector(Config{Threshold: 1, MaxLines: 3})
+	assert.Nil(t, d.Add("ERROR one", t0))
+	assert.Nil(t, d.Add("ERROR two", t0))
+	inc := d.Add("ERROR three", t0)
+	require.NotNil(t, inc)
+	assert.Len(t, inc.Lines, 3)
+}
+
+func TestFingerprint(t *testing.T) {
+	burst := func(host string) *Incident {
+		d := NewDetector(Config{Threshold: 2})
+		d.Add(fmt.Sprintf("2026-01-15T10:00:01Z ERROR dial tcp %s:5432: connection refused", host), t0)
+		d.Add("2026-01-15T10:00:02Z ERROR request 8812 failed", t0)
+		return d.Flush()
+	}
+	assert.Equal(t, burst("10.0.0.1").Fingerprint, burst("10.0.0.9").Fingerprint)
+
+	d := NewDetector(Config{Threshold: 1})
+	d.Add("ERROR disk full", t0)
+	assert.NotEqual(t, burst("10.0.0.1").Fingerprint, d.Flush().Fingerprint)
+}
+
+func TestDeduper(t *testing.T) {
+	d := NewDeduper(time.Minute)
+	assert.False(t, d.Seen("a", t0))
+	assert.True(t, d.Seen("a", t0.Add(30*time.Second)))
+	assert.False(t, d.Seen("b", t0.Add(30*time.Second)))
+	assert.False(t, d.Seen("a", t0.Add(2*time.Minute)))
+}
+
+func TestWatcherDeduplicates(t *testing.T) {
+	analyzed := 0
+	var reports []Report
+	w := New(Config{Threshold: 2},
+		func(logText string) (*ai.AnalysisResult, error) {
+			analyzed++
+			return &ai.AnalysisResult{PrimaryIssue: "Database down"}, nil
+		},
+		func(r Report) { reports = append(reports, r) })
+	w.now = func() time.Time { return t0 }
+
+	for i := 0; i < 3; i++ {
+		d := NewDetector(w.cfg)
+		d.Add(fmt.Sprintf("ERROR request %d: connection refused", i), t0)
+		d.Add(fmt.Sprintf("ERROR request %d: connection refused", i+100), t0)
+		w.handle(d.Flush())
+	}
+
+	assert.Equal(t, 1, analyzed)
+	require.Len(t, reports, 3)
+	assert.False(t, reports[0].Duplicate)
+	assert.True(t, reports[2].Duplicate)
+	assert.Equal(t, 3, reports[2].Occurrences)
+	assert.Equal(t, "Database down", reports[2].Result.PrimaryIssue)
+}
+
+func TestWatcherForgetsExpired(t *testing.T) {
+	w := New(Config{Threshold: 1, DedupFor: time.Minute},
+		func(string) (*ai.AnalysisResult, error) { return &ai.AnalysisResult{}, nil }, nil)
+	now := t0
+	w.now = func() time.Time { return now }
+	burst := func(line string) {
+		d := NewDetector(w.cfg)
+		d.Add(line, now)
+		w.handle(d.Flush())
+	}
+
+	burst("ERROR disk full")
+	burst("ERROR connection refused")
+	assert.Len(t, w.count, 2)
+	assert.Len(t, w.results, 2)
+
+	now = now.Add(2 * time.Minute)
+	burst("ERROR out of memory")
+	assert.Len(t, w.count, 1)
+	assert.Len(t, w.results, 1)
+}
+
+func TestWatcherRun(t *testing.T) {
+	var reports []Report
+	w := New(Config{Threshold: 2},
+		func(logText string) (*ai.AnalysisResult, error) {
+			return &ai.AnalysisResult{Summary: logText}, nil
+		},
+		func(r Report) { reports = append(reports, r) })
+
+	lines := make(chan string, 3)
+	lines <- "INFO start"
+	lines <- "ERROR one"
+	lines <- "ERROR two"
+	close(lines)
+
+	// A burst still open at the end of the input is analyzed
+	require.NoError(t, w.Run(context.Background(), lines))
+	require.Len(t, reports, 1)
+	assert.Equal(t, "INFO start\nERROR one\nERROR two\n", reports[0].Result.Summary)
+}